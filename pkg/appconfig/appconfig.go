@@ -0,0 +1,115 @@
+// Package appconfig загружает конфигурацию сервисов в едином порядке:
+// значения по умолчанию, затем YAML-файл, затем переменные окружения, затем проверка.
+//
+// Файл задается флагом --config или переменной <PREFIX>_CONFIG; без них он ищется
+// относительно рабочей директории, как раньше. Неизвестные ключи в файле — ошибка.
+//
+// Любое поле переопределяется переменной <PREFIX>_<СЕКЦИЯ>_<ПОЛЕ> по yaml-тегам,
+// например REGISTER_DATABASE_PASSWORD для database.password. Секреты удобнее передавать
+// файлами: REGISTER_DATABASE_PASSWORD_FILE=/run/secrets/db_password.
+package appconfig
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FlagName — имя флага с путем к файлу конфигурации
+const FlagName = "config"
+
+// Validator реализуют конфигурации сервисов; Validate вызывается после применения переменных окружения
+type Validator interface {
+	Validate() error
+}
+
+// Options задает источник конфигурации сервиса
+type Options struct {
+	// EnvPrefix — префикс переменных окружения сервиса, например REGISTER
+	EnvPrefix string
+	// Path — путь из флага --config; пустой означает <EnvPrefix>_CONFIG или поиск по стандартным путям
+	Path string
+}
+
+// Flag регистрирует флаг --config в fs и возвращает указатель на его значение
+func Flag(fs *flag.FlagSet) *string {
+	return fs.String(FlagName, "", "путь к YAML-файлу конфигурации")
+}
+
+// searchPaths — где искать config.yaml, если путь не задан явно
+func searchPaths() ([]string, error) {
+	workDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения рабочей директории: %w", err)
+	}
+	return []string{
+		filepath.Join(workDir, "config.yaml"),
+		filepath.Join(workDir, "config", "config.yaml"),
+		filepath.Join(workDir, "..", "config", "config.yaml"),
+		filepath.Join(workDir, "..", "..", "config", "config.yaml"),
+	}, nil
+}
+
+// Load заполняет dst, в котором уже лежат значения по умолчанию, и возвращает путь к прочитанному файлу.
+// Пустой путь означает, что файл не найден и использованы только значения по умолчанию и окружение.
+func Load(dst interface{}, opts Options) (string, error) {
+	path := opts.Path
+	explicit := path != ""
+	if !explicit && opts.EnvPrefix != "" {
+		path = os.Getenv(opts.EnvPrefix + "_CONFIG")
+		explicit = path != ""
+	}
+
+	var data []byte
+	if explicit {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return "", fmt.Errorf("ошибка чтения файла конфигурации: %w", err)
+		}
+	} else {
+		paths, err := searchPaths()
+		if err != nil {
+			return "", err
+		}
+		path = ""
+		for _, candidate := range paths {
+			if content, err := os.ReadFile(candidate); err == nil {
+				path, data = candidate, content
+				break
+			}
+		}
+	}
+
+	if data != nil {
+		if err := decodeStrict(data, dst); err != nil {
+			return "", fmt.Errorf("ошибка разбора %s: %w", path, err)
+		}
+	}
+
+	if err := applyEnv(dst, opts.EnvPrefix); err != nil {
+		return "", err
+	}
+
+	if v, ok := dst.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return "", fmt.Errorf("некорректная конфигурация: %w", err)
+		}
+	}
+	return path, nil
+}
+
+// decodeStrict разбирает YAML и отклоняет ключи, которым нет соответствующего поля
+func decodeStrict(data []byte, dst interface{}) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(dst); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package appconfig
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Database Database   `yaml:"database"`
+	Worker   testWorker `yaml:"worker"`
+	Kafka    testKafka  `yaml:"kafka"`
+	Auth     testAuth   `yaml:"auth"`
+}
+
+type testWorker struct {
+	Concurrency int           `yaml:"concurrency"`
+	Lease       time.Duration `yaml:"lease"`
+	Enabled     bool          `yaml:"enabled"`
+	Ratio       float64       `yaml:"ratio"`
+}
+
+type testKafka struct {
+	Brokers []string `yaml:"brokers"`
+}
+
+type testAuth struct {
+	Keys []testKey `yaml:"keys"`
+}
+
+type testKey struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key" secret:"true"`
+}
+
+func (c *testConfig) Validate() error {
+	return c.Database.Validate()
+}
+
+func defaultTestConfig() *testConfig {
+	return &testConfig{
+		Database: DefaultDatabase(),
+		Worker:   testWorker{Concurrency: 10, Lease: time.Minute},
+		Kafka:    testKafka{Brokers: []string{"localhost:9092"}},
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// chdir переходит в dir на время теста, чтобы поиск config.yaml не нашел файлы репозитория
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestLoadDefaultsWithoutFile(t *testing.T) {
+	chdir(t, t.TempDir())
+
+	cfg := defaultTestConfig()
+	path, err := Load(cfg, Options{EnvPrefix: "TEST"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if path != "" {
+		t.Errorf("path = %q, want empty without a config file", path)
+	}
+	if !reflect.DeepEqual(cfg, defaultTestConfig()) {
+		t.Errorf("config = %+v, want defaults", cfg)
+	}
+}
+
+func TestLoadSearchesWorkingDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "config"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(dir, "config", "config.yaml")
+	if err := os.WriteFile(want, []byte("worker:\n  concurrency: 3\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, dir)
+
+	cfg := defaultTestConfig()
+	path, err := Load(cfg, Options{EnvPrefix: "TEST"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if path != want || cfg.Worker.Concurrency != 3 {
+		t.Errorf("loaded %q with concurrency %d, want %q with 3", path, cfg.Worker.Concurrency, want)
+	}
+}
+
+func TestLoadFileAndEnvironment(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+database:
+  host: db.internal
+  password: from-file
+worker:
+  concurrency: 4
+  lease: 90s
+kafka:
+  brokers: [a:9092]
+`)
+	secret := writeFile(t, "password", "from-secret-file\n")
+
+	t.Setenv("TEST_WORKER_CONCURRENCY", "8")
+	t.Setenv("TEST_WORKER_ENABLED", "true")
+	t.Setenv("TEST_WORKER_RATIO", "0.5")
+	t.Setenv("TEST_KAFKA_BROKERS", "b:9092, c:9092,")
+	t.Setenv("TEST_DATABASE_PASSWORD_FILE", secret)
+
+	cfg := defaultTestConfig()
+	source, err := Load(cfg, Options{EnvPrefix: "TEST", Path: path})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if source != path {
+		t.Errorf("source = %q, want %q", source, path)
+	}
+
+	want := defaultTestConfig()
+	want.Database.Host = "db.internal"
+	want.Database.Password = "from-secret-file"
+	want.Worker = testWorker{Concurrency: 8, Lease: 90 * time.Second, Enabled: true, Ratio: 0.5}
+	want.Kafka.Brokers = []string{"b:9092", "c:9092"}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("config = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestLoadPathFromEnvironment(t *testing.T) {
+	path := writeFile(t, "service.yaml", "worker:\n  concurrency: 2\n")
+	t.Setenv("TEST_CONFIG", path)
+
+	cfg := defaultTestConfig()
+	source, err := Load(cfg, Options{EnvPrefix: "TEST"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if source != path || cfg.Worker.Concurrency != 2 {
+		t.Errorf("loaded %q with concurrency %d, want %q with 2", source, cfg.Worker.Concurrency, path)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		wantErr string
+	}{
+		{
+			name:    "unknown key",
+			file:    "worker:\n  concurency: 4\n",
+			wantErr: "field concurency not found",
+		},
+		{
+			name:    "invalid duration",
+			env:     map[string]string{"TEST_WORKER_LEASE": "90"},
+			wantErr: "TEST_WORKER_LEASE: ожидается длительность",
+		},
+		{
+			name:    "invalid integer",
+			env:     map[string]string{"TEST_WORKER_CONCURRENCY": "many"},
+			wantErr: "TEST_WORKER_CONCURRENCY: ожидается целое число",
+		},
+		{
+			name:    "invalid bool",
+			env:     map[string]string{"TEST_WORKER_ENABLED": "yes please"},
+			wantErr: "TEST_WORKER_ENABLED: ожидается true или false",
+		},
+		{
+			name:    "value and file together",
+			env:     map[string]string{"TEST_DATABASE_PASSWORD": "a", "TEST_DATABASE_PASSWORD_FILE": "/dev/null"},
+			wantErr: "заданы одновременно TEST_DATABASE_PASSWORD и TEST_DATABASE_PASSWORD_FILE",
+		},
+		{
+			name:    "missing secret file",
+			env:     map[string]string{"TEST_DATABASE_PASSWORD_FILE": "/nonexistent/password"},
+			wantErr: "TEST_DATABASE_PASSWORD_FILE: ошибка чтения секрета",
+		},
+		{
+			name:    "list of structs",
+			env:     map[string]string{"TEST_AUTH_KEYS": "a,b"},
+			wantErr: "нельзя задать переменной окружения",
+		},
+		{
+			name:    "validation",
+			env:     map[string]string{"TEST_DATABASE_PORT": "70000", "TEST_DATABASE_SSLMODE": "sometimes"},
+			wantErr: "некорректная конфигурация: database.port",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, "config.yaml", tt.file)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			_, err := Load(defaultTestConfig(), Options{EnvPrefix: "TEST", Path: path})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadMissingExplicitFile(t *testing.T) {
+	_, err := Load(defaultTestConfig(), Options{EnvPrefix: "TEST", Path: filepath.Join(t.TempDir(), "missing.yaml")})
+	if err == nil || !strings.Contains(err.Error(), "ошибка чтения файла конфигурации") {
+		t.Errorf("Load error = %v, want a read error", err)
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	cfg := defaultTestConfig()
+	cfg.Auth.Keys = []testKey{{Name: "ci", Key: "s3cr3t"}}
+
+	var out strings.Builder
+	if err := Print(&out, cfg); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	text := out.String()
+	for _, secret := range []string{"s3cr3t", "password: postgres"} {
+		if strings.Contains(text, secret) {
+			t.Errorf("output contains %q:\n%s", secret, text)
+		}
+	}
+	for _, visible := range []string{"host: localhost", "name: ci", "password: '[REDACTED]'", "key: '[REDACTED]'"} {
+		if !strings.Contains(text, visible) {
+			t.Errorf("output does not contain %q:\n%s", visible, text)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package appconfig
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyEnv переопределяет поля dst переменными <prefix>_<ПУТЬ> и <prefix>_<ПУТЬ>_FILE.
+// Поддерживаются строки, числа, bool, time.Duration и []string через запятую;
+// списки структур (например auth.api_keys) задаются только в файле.
+func applyEnv(dst interface{}, prefix string) error {
+	if prefix == "" {
+		return nil
+	}
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("appconfig: ожидается указатель на структуру, получен %T", dst)
+	}
+	return applyEnvStruct(v.Elem(), prefix)
+}
+
+func applyEnvStruct(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := yamlName(field)
+		if !ok {
+			continue
+		}
+		envName := prefix + "_" + strings.ToUpper(name)
+		value := v.Field(i)
+
+		if value.Kind() == reflect.Struct {
+			if err := applyEnvStruct(value, envName); err != nil {
+				return err
+			}
+			continue
+		}
+
+		raw, found, err := lookupEnv(envName)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+		if err := setValue(value, raw); err != nil {
+			return fmt.Errorf("%s: %w", envName, err)
+		}
+	}
+	return nil
+}
+
+// lookupEnv читает переменную name или файл из name_FILE; одновременно обе задавать нельзя
+func lookupEnv(name string) (string, bool, error) {
+	value, hasValue := os.LookupEnv(name)
+	path, hasFile := os.LookupEnv(name + "_FILE")
+	switch {
+	case hasValue && hasFile:
+		return "", false, fmt.Errorf("заданы одновременно %s и %s_FILE", name, name)
+	case hasFile:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("%s_FILE: ошибка чтения секрета: %w", name, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), true, nil
+	default:
+		return value, hasValue, nil
+	}
+}
+
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("ожидается длительность, например 30s: %q", raw)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("ожидается true или false: %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("ожидается целое число: %q", raw)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("ожидается неотрицательное целое число: %q", raw)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("ожидается число: %q", raw)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("поле типа %s нельзя задать переменной окружения", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("поле типа %s нельзя задать переменной окружения", v.Type())
+	}
+	return nil
+}
+
+// yamlName возвращает ключ поля в YAML; false — поле не сериализуется
+func yamlName(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+	tag := field.Tag.Get("yaml")
+	name := strings.Split(tag, ",")[0]
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name, true
+}
@@ -0,0 +1,84 @@
+package appconfig
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CommandName — подкоманда, печатающая итоговую конфигурацию: `<сервис> config print`
+const CommandName = "config"
+
+// redacted заменяет значения полей с тегом secret:"true"
+const redacted = "[REDACTED]"
+
+// RunCommand выполняет подкоманду config; args — аргументы после ее имени
+func RunCommand(args []string, cfg interface{}, path string, out io.Writer) error {
+	if len(args) != 1 || args[0] != "print" {
+		return fmt.Errorf("usage: %s print", CommandName)
+	}
+	source := path
+	if source == "" {
+		source = "значения по умолчанию"
+	}
+	fmt.Fprintf(out, "# источник: %s и переменные окружения\n", source)
+	return Print(out, cfg)
+}
+
+// Print пишет cfg в YAML, заменяя значения полей с тегом secret:"true" на [REDACTED]
+func Print(out io.Writer, cfg interface{}) error {
+	var node yaml.Node
+	if err := node.Encode(cfg); err != nil {
+		return err
+	}
+	redact(&node, reflect.TypeOf(cfg))
+
+	encoder := yaml.NewEncoder(out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func redact(node *yaml.Node, t reflect.Type) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if node.Kind == yaml.DocumentNode {
+		for _, child := range node.Content {
+			redact(child, t)
+		}
+		return
+	}
+
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields := make(map[string]reflect.StructField, t.NumField())
+		for i := 0; i < t.NumField(); i++ {
+			if name, ok := yamlName(t.Field(i)); ok {
+				fields[name] = t.Field(i)
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			field, ok := fields[node.Content[i].Value]
+			if !ok {
+				continue
+			}
+			value := node.Content[i+1]
+			if field.Tag.Get("secret") == "true" {
+				if value.Kind == yaml.ScalarNode && value.Value != "" {
+					value.Value, value.Tag, value.Style = redacted, "!!str", 0
+				}
+				continue
+			}
+			redact(value, field.Type)
+		}
+	case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && node.Kind == yaml.SequenceNode:
+		for _, item := range node.Content {
+			redact(item, t.Elem())
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package appconfig
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Database — подключение к Postgres, общее для всех сервисов
+type Database struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password" secret:"true"`
+	DBName   string `yaml:"dbname"`
+	SSLMode  string `yaml:"sslmode"`
+}
+
+func DefaultDatabase() Database {
+	return Database{
+		Host:     "localhost",
+		Port:     5432,
+		User:     "postgres",
+		Password: "postgres",
+		DBName:   "reports_db",
+		SSLMode:  "disable",
+	}
+}
+
+// DSN возвращает строку подключения для lib/pq
+func (d Database) DSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		d.Host, d.Port, d.User, d.Password, d.DBName, d.SSLMode)
+}
+
+func (d Database) Validate() error {
+	return errors.Join(
+		Required("database.host", d.Host),
+		Port("database.port", d.Port),
+		Required("database.user", d.User),
+		Required("database.dbname", d.DBName),
+		OneOf("database.sslmode", d.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full"),
+	)
+}
+
+// MinIO — хранилище файлов отчетов
+type MinIO struct {
+	Endpoint   string `yaml:"endpoint"`
+	AccessKey  string `yaml:"access_key"`
+	SecretKey  string `yaml:"secret_key" secret:"true"`
+	PDFBucket  string `yaml:"pdf_bucket"`
+	DOCXBucket string `yaml:"docx_bucket"`
+	UseSSL     bool   `yaml:"use_ssl"`
+}
+
+func DefaultMinIO() MinIO {
+	return MinIO{
+		Endpoint:   "localhost:9000",
+		AccessKey:  "minioadmin",
+		SecretKey:  "minioadmin",
+		PDFBucket:  "reports-pdf",
+		DOCXBucket: "reports-docx",
+	}
+}
+
+func (m MinIO) Validate() error {
+	return errors.Join(
+		Required("minio.endpoint", m.Endpoint),
+		Required("minio.access_key", m.AccessKey),
+		Required("minio.secret_key", m.SecretKey),
+		Required("minio.pdf_bucket", m.PDFBucket),
+		Required("minio.docx_bucket", m.DOCXBucket),
+	)
+}
+
+// Required проверяет, что строковое поле задано
+func Required(field, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s: обязательное поле", field)
+	}
+	return nil
+}
+
+// Port проверяет номер TCP-порта
+func Port(field string, value int) error {
+	if value <= 0 || value > 65535 {
+		return fmt.Errorf("%s: ожидается порт от 1 до 65535, задано %d", field, value)
+	}
+	return nil
+}
+
+// PositiveInt проверяет, что число больше нуля
+func PositiveInt(field string, value int) error {
+	if value <= 0 {
+		return fmt.Errorf("%s: должно быть больше 0, задано %d", field, value)
+	}
+	return nil
+}
+
+// NonNegativeInt проверяет, что число не отрицательное; 0 обычно отключает ограничение
+func NonNegativeInt(field string, value int) error {
+	if value < 0 {
+		return fmt.Errorf("%s: не может быть отрицательным, задано %d", field, value)
+	}
+	return nil
+}
+
+// PositiveDuration проверяет, что длительность больше нуля
+func PositiveDuration(field string, value time.Duration) error {
+	if value <= 0 {
+		return fmt.Errorf("%s: должно быть больше 0, задано %s", field, value)
+	}
+	return nil
+}
+
+// OneOf проверяет, что значение входит в список допустимых
+func OneOf(field, value string, allowed ...string) error {
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: недопустимое значение %q, ожидается одно из %v", field, value, allowed)
+}
@@ -0,0 +1,72 @@
+package appconfig
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultSectionsAreValid(t *testing.T) {
+	if err := DefaultDatabase().Validate(); err != nil {
+		t.Errorf("DefaultDatabase().Validate() = %v", err)
+	}
+	if err := DefaultMinIO().Validate(); err != nil {
+		t.Errorf("DefaultMinIO().Validate() = %v", err)
+	}
+}
+
+func TestDatabaseValidate(t *testing.T) {
+	db := Database{Port: 0, SSLMode: "sometimes"}
+	err := db.Validate()
+	if err == nil {
+		t.Fatal("Validate succeeded for an empty database section")
+	}
+	// Все ошибки сообщаются сразу, а не по одной
+	for _, field := range []string{"database.host", "database.port", "database.user", "database.dbname", "database.sslmode"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("error %q does not mention %s", err, field)
+		}
+	}
+}
+
+func TestDatabaseDSN(t *testing.T) {
+	want := "host=localhost port=5432 user=postgres password=postgres dbname=reports_db sslmode=disable"
+	if got := DefaultDatabase().DSN(); got != want {
+		t.Errorf("DSN() = %q, want %q", got, want)
+	}
+}
+
+func TestValidators(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr string
+	}{
+		{name: "required set", err: Required("f", "x")},
+		{name: "required empty", err: Required("f", ""), wantErr: "f: обязательное поле"},
+		{name: "port", err: Port("f", 65535)},
+		{name: "port zero", err: Port("f", 0), wantErr: "задано 0"},
+		{name: "port too large", err: Port("f", 65536), wantErr: "задано 65536"},
+		{name: "positive int", err: PositiveInt("f", 1)},
+		{name: "positive int zero", err: PositiveInt("f", 0), wantErr: "должно быть больше 0"},
+		{name: "non-negative zero", err: NonNegativeInt("f", 0)},
+		{name: "non-negative negative", err: NonNegativeInt("f", -1), wantErr: "не может быть отрицательным"},
+		{name: "positive duration", err: PositiveDuration("f", time.Second)},
+		{name: "positive duration zero", err: PositiveDuration("f", 0), wantErr: "задано 0s"},
+		{name: "one of", err: OneOf("f", "b", "a", "b")},
+		{name: "one of unknown", err: OneOf("f", "c", "a", "b"), wantErr: `недопустимое значение "c"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr == "" {
+				if tt.err != nil {
+					t.Errorf("error = %v, want nil", tt.err)
+				}
+				return
+			}
+			if tt.err == nil || !strings.Contains(tt.err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", tt.err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// Package audit записывает историю запроса на отчет в reporting.report_request_events.
+// Событие пишется в той же транзакции, что и изменение запроса, поэтому история не расходится с состоянием.
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/google/uuid"
+)
+
+// EventType — вид события в истории запроса
+type EventType string
+
+const (
+	EventCreated          EventType = "CREATED"
+	EventStatusChanged    EventType = "STATUS_CHANGED"
+	EventRetryAttempt     EventType = "RETRY_ATTEMPT"
+	EventRequeued         EventType = "REQUEUED"
+	EventNotificationSent EventType = "NOTIFICATION_SENT"
+	EventWebhookAttempt   EventType = "WEBHOOK_ATTEMPT"
+)
+
+// Сервисы, записывающие события
+const (
+	SourceRegister  = "reports_register"
+	SourceGenerator = "reports_generator"
+	SourcePublisher = "reports_publisher"
+)
+
+// Event описывает одно событие; пустые строки и nil записываются как NULL
+type Event struct {
+	RequestID  uuid.UUID
+	Type       EventType
+	FromStatus string
+	ToStatus   string
+	RetryCount *int
+	Error      *string
+	// Actor — кто выполнил действие: user:<id>, apikey:<name> или идентификатор воркера
+	Actor   string
+	Source  string
+	Details map[string]interface{}
+}
+
+// Execer выполняет запрос в базе или транзакции
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Record сохраняет событие
+func Record(ctx context.Context, db Execer, event Event) error {
+	var details []byte
+	if len(event.Details) > 0 {
+		var err error
+		if details, err = json.Marshal(event.Details); err != nil {
+			return fmt.Errorf("audit: marshal details: %w", err)
+		}
+	}
+
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO reporting.report_request_events
+			(request_id, event_type, from_status, to_status, retry_count, error, actor, source, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, now())
+	`,
+		event.RequestID,
+		event.Type,
+		nullString(event.FromStatus),
+		nullString(event.ToStatus),
+		event.RetryCount,
+		event.Error,
+		event.Actor,
+		event.Source,
+		details,
+	)
+	if err != nil {
+		return fmt.Errorf("audit: record %s for %s: %w", event.Type, event.RequestID, err)
+	}
+	return nil
+}
+
+// WorkerActor возвращает идентификатор воркера вида <host>:<pid>/<name>
+func WorkerActor(name string) string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d/%s", host, os.Getpid(), name)
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// APIKeyHeader — заголовок с API-ключом
+	APIKeyHeader = "X-API-Key"
+)
+
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Authenticator определяет пользователя по заголовку Authorization: Bearer <JWT> или X-API-Key
+type Authenticator struct {
+	jwt  *jwtVerifier
+	keys map[[sha256.Size]byte]*Identity
+}
+
+func New(cfg Config) (*Authenticator, error) {
+	a := &Authenticator{keys: make(map[[sha256.Size]byte]*Identity, len(cfg.APIKeys))}
+
+	if cfg.JWT.enabled() {
+		verifier, err := newJWTVerifier(cfg.JWT)
+		if err != nil {
+			return nil, err
+		}
+		a.jwt = verifier
+	}
+
+	for i, key := range cfg.APIKeys {
+		if key.Key == "" || key.UserID <= 0 {
+			return nil, fmt.Errorf("api_keys[%d]: key and user_id are required", i)
+		}
+		name := key.Name
+		if name == "" {
+			name = fmt.Sprintf("user-%d", key.UserID)
+		}
+		// Храним только хеш ключа; поиск по хешу не раскрывает ключ через время сравнения
+		a.keys[sha256.Sum256([]byte(key.Key))] = &Identity{
+			UserID:  key.UserID,
+			Roles:   key.Roles,
+			Subject: "apikey:" + name,
+		}
+	}
+
+	if a.jwt == nil && len(a.keys) == 0 {
+		return nil, errors.New("auth: neither jwt nor api_keys is configured")
+	}
+	return a, nil
+}
+
+// Authenticate определяет пользователя по заголовкам запроса
+func (a *Authenticator) Authenticate(r *http.Request) (*Identity, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		identity, ok := a.keys[sha256.Sum256([]byte(key))]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown api key", ErrInvalidCredentials)
+		}
+		return identity, nil
+	}
+
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, ErrMissingCredentials
+	}
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, fmt.Errorf("%w: expected Bearer token", ErrInvalidCredentials)
+	}
+	if a.jwt == nil {
+		return nil, fmt.Errorf("%w: jwt authentication is disabled", ErrInvalidCredentials)
+	}
+	return a.jwt.Verify(strings.TrimSpace(token))
+}
+
+// Middleware пропускает только аутентифицированные запросы и сохраняет пользователя в контексте
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, err := a.Authenticate(r)
+		if err != nil {
+			Unauthorized(w)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+	})
+}
+
+// Unauthorized отвечает 401 с заголовком WWW-Authenticate
+func Unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="reports"`)
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
+// AccessTokenParam — параметр запроса с JWT для клиентов, которые не могут задать заголовки (EventSource)
+const AccessTokenParam = "access_token"
+
+// TokenFromQuery переносит JWT из параметра access_token в заголовок Authorization,
+// если заголовок не задан. Используется только для потоковых маршрутов.
+func TokenFromQuery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := r.URL.Query().Get(AccessTokenParam); token != "" && r.Header.Get("Authorization") == "" {
+			r = r.Clone(r.Context())
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
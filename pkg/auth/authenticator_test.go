@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "empty", cfg: Config{}, wantErr: true},
+		{name: "api key without key", cfg: Config{APIKeys: []APIKeyConfig{{UserID: 1}}}, wantErr: true},
+		{name: "api key without user", cfg: Config{APIKeys: []APIKeyConfig{{Key: "k"}}}, wantErr: true},
+		{name: "invalid jwt", cfg: Config{JWT: JWTConfig{Algorithm: AlgorithmRS256}}, wantErr: true},
+		{name: "api keys only", cfg: Config{APIKeys: []APIKeyConfig{{Key: "k", UserID: 1}}}},
+		{name: "jwt only", cfg: Config{JWT: JWTConfig{Secret: testSecret}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New: error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	a, err := New(Config{
+		JWT: JWTConfig{Secret: testSecret},
+		APIKeys: []APIKeyConfig{
+			{Name: "scheduler", Key: "scheduler-key", UserID: 10, Roles: []string{RoleAdmin}},
+			{Key: "plain-key", UserID: 11},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	a.jwt.now = func() time.Time { return testNow }
+	token := signHS256(t, "HS256", []byte(testSecret), map[string]interface{}{"sub": 42, "exp": testNow.Add(time.Hour).Unix()})
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		subject string
+		userID  int
+		admin   bool
+		wantErr error
+	}{
+		{name: "named api key", headers: map[string]string{APIKeyHeader: "scheduler-key"}, subject: "apikey:scheduler", userID: 10, admin: true},
+		{name: "api key with default name", headers: map[string]string{APIKeyHeader: "plain-key"}, subject: "apikey:user-11", userID: 11},
+		{name: "api key takes precedence over bearer", headers: map[string]string{APIKeyHeader: "plain-key", "Authorization": "Bearer " + token}, subject: "apikey:user-11", userID: 11},
+		{name: "unknown api key", headers: map[string]string{APIKeyHeader: "scheduler-key "}, wantErr: ErrInvalidCredentials},
+		{name: "unknown api key does not fall back to bearer", headers: map[string]string{APIKeyHeader: "nope", "Authorization": "Bearer " + token}, wantErr: ErrInvalidCredentials},
+		{name: "bearer token", headers: map[string]string{"Authorization": "Bearer " + token}, subject: "user:42", userID: 42},
+		{name: "bearer scheme is case insensitive", headers: map[string]string{"Authorization": "bearer " + token}, subject: "user:42", userID: 42},
+		{name: "no credentials", wantErr: ErrMissingCredentials},
+		{name: "basic scheme", headers: map[string]string{"Authorization": "Basic dXNlcjpwYXNz"}, wantErr: ErrInvalidCredentials},
+		{name: "empty bearer", headers: map[string]string{"Authorization": "Bearer "}, wantErr: ErrInvalidCredentials},
+		{name: "invalid bearer", headers: map[string]string{"Authorization": "Bearer " + token + "x"}, wantErr: ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for name, value := range tt.headers {
+				r.Header.Set(name, value)
+			}
+			identity, err := a.Authenticate(r)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Authenticate: error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if identity.Subject != tt.subject || identity.UserID != tt.userID || identity.IsAdmin() != tt.admin {
+				t.Errorf("identity = %+v, want subject %q user %d admin %v", identity, tt.subject, tt.userID, tt.admin)
+			}
+		})
+	}
+}
+
+func TestAuthenticateJWTDisabled(t *testing.T) {
+	a, err := New(Config{APIKeys: []APIKeyConfig{{Key: "k", UserID: 1}}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+signHS256(t, "HS256", []byte(testSecret), claims(nil)))
+	if _, err := a.Authenticate(r); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Authenticate: error = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	a, err := New(Config{APIKeys: []APIKeyConfig{{Key: "k", UserID: 5}}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	var got *Identity
+	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("without credentials: status %d, WWW-Authenticate %q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+	if got != nil {
+		t.Fatal("handler called without credentials")
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(APIKeyHeader, "k")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK || got == nil || got.UserID != 5 {
+		t.Fatalf("with api key: status %d, identity %+v", rec.Code, got)
+	}
+}
+
+func TestTokenFromQuery(t *testing.T) {
+	var header string
+	handler := TokenFromQuery(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("Authorization")
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/stream?access_token=abc", nil))
+	if header != "Bearer abc" {
+		t.Errorf("Authorization = %q, want %q", header, "Bearer abc")
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/stream?access_token=abc", nil)
+	r.Header.Set("Authorization", "Bearer header")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if header != "Bearer header" {
+		t.Errorf("Authorization = %q, existing header must win", header)
+	}
+}
@@ -0,0 +1,34 @@
+package auth
+
+import "time"
+
+// Config задает способы аутентификации. Должен быть настроен хотя бы один из них.
+type Config struct {
+	JWT     JWTConfig      `yaml:"jwt"`
+	APIKeys []APIKeyConfig `yaml:"api_keys"`
+}
+
+// JWTConfig задает проверку JWT. Для HS256 нужен Secret, для RS256 — PublicKeyFile или PublicKey в PEM.
+type JWTConfig struct {
+	Algorithm     string        `yaml:"algorithm"`
+	Secret        string        `yaml:"secret" secret:"true"`
+	PublicKey     string        `yaml:"public_key"`
+	PublicKeyFile string        `yaml:"public_key_file"`
+	Issuer        string        `yaml:"issuer"`
+	Audience      string        `yaml:"audience"`
+	UserClaim     string        `yaml:"user_claim"`
+	RolesClaim    string        `yaml:"roles_claim"`
+	Leeway        time.Duration `yaml:"leeway"`
+}
+
+// APIKeyConfig описывает API-ключ сервисной учетной записи
+type APIKeyConfig struct {
+	Name   string   `yaml:"name"`
+	Key    string   `yaml:"key" secret:"true"`
+	UserID int      `yaml:"user_id"`
+	Roles  []string `yaml:"roles"`
+}
+
+func (c JWTConfig) enabled() bool {
+	return c.Algorithm != "" || c.Secret != "" || c.PublicKey != "" || c.PublicKeyFile != ""
+}
@@ -0,0 +1,58 @@
+// Package auth проверяет JWT и API-ключи и определяет пользователя, от имени которого выполняется запрос.
+package auth
+
+import (
+	"context"
+	"fmt"
+)
+
+// RoleAdmin дает доступ к запросам и отчетам всех пользователей
+const RoleAdmin = "admin"
+
+// Identity описывает аутентифицированного пользователя
+type Identity struct {
+	UserID int
+	Roles  []string
+	// Subject — человекочитаемое имя для журналов: user:<id> для токенов, apikey:<name> для ключей
+	Subject string
+}
+
+// HasRole сообщает, есть ли у пользователя роль
+func (i *Identity) HasRole(role string) bool {
+	for _, r := range i.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdmin сообщает, есть ли у пользователя роль администратора
+func (i *Identity) IsAdmin() bool {
+	return i.HasRole(RoleAdmin)
+}
+
+// CanAccess сообщает, может ли пользователь работать с ресурсом владельца ownerID
+func (i *Identity) CanAccess(ownerID int) bool {
+	return i.UserID == ownerID || i.IsAdmin()
+}
+
+func (i *Identity) String() string {
+	if i.Subject != "" {
+		return i.Subject
+	}
+	return fmt.Sprintf("user:%d", i.UserID)
+}
+
+type contextKey struct{}
+
+// WithIdentity возвращает контекст с пользователем
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, identity)
+}
+
+// FromContext возвращает пользователя, сохраненный Middleware, или nil
+func FromContext(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(contextKey{}).(*Identity)
+	return identity
+}
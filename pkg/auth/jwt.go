@@ -0,0 +1,262 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+)
+
+// jwtVerifier проверяет подпись и стандартные claims токена
+type jwtVerifier struct {
+	cfg       JWTConfig
+	secret    []byte
+	publicKey *rsa.PublicKey
+	now       func() time.Time
+}
+
+func newJWTVerifier(cfg JWTConfig) (*jwtVerifier, error) {
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = AlgorithmHS256
+	}
+	if cfg.UserClaim == "" {
+		cfg.UserClaim = "sub"
+	}
+	if cfg.RolesClaim == "" {
+		cfg.RolesClaim = "roles"
+	}
+
+	v := &jwtVerifier{cfg: cfg, now: time.Now}
+	switch cfg.Algorithm {
+	case AlgorithmHS256:
+		if cfg.Secret == "" {
+			return nil, errors.New("jwt: secret is required for HS256")
+		}
+		v.secret = []byte(cfg.Secret)
+	case AlgorithmRS256:
+		key, err := loadPublicKey(cfg)
+		if err != nil {
+			return nil, err
+		}
+		v.publicKey = key
+	default:
+		return nil, fmt.Errorf("jwt: unsupported algorithm %q", cfg.Algorithm)
+	}
+	return v, nil
+}
+
+// loadPublicKey читает открытый ключ RSA в формате PKIX или PKCS#1
+func loadPublicKey(cfg JWTConfig) (*rsa.PublicKey, error) {
+	data := []byte(cfg.PublicKey)
+	if cfg.PublicKeyFile != "" {
+		var err error
+		if data, err = os.ReadFile(cfg.PublicKeyFile); err != nil {
+			return nil, fmt.Errorf("jwt: read public key: %w", err)
+		}
+	}
+	if len(data) == 0 {
+		return nil, errors.New("jwt: public_key or public_key_file is required for RS256")
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("jwt: public key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: parse public key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("jwt: public key is not an RSA key")
+	}
+	return key, nil
+}
+
+// Verify проверяет токен и возвращает пользователя из его claims
+func (v *jwtVerifier) Verify(token string) (*Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidCredentials)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: invalid header", ErrInvalidCredentials)
+	}
+	// Алгоритм задается конфигурацией, а не токеном, иначе возможна подмена на none или HS256 с открытым ключом
+	if header.Alg != v.cfg.Algorithm {
+		return nil, fmt.Errorf("%w: unexpected algorithm %q", ErrInvalidCredentials, header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid signature encoding", ErrInvalidCredentials)
+	}
+	if err := v.verifySignature(parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: invalid claims", ErrInvalidCredentials)
+	}
+	if err := v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+
+	userID, err := intClaim(claims, v.cfg.UserClaim)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{
+		UserID:  userID,
+		Roles:   rolesClaim(claims[v.cfg.RolesClaim]),
+		Subject: fmt.Sprintf("user:%d", userID),
+	}, nil
+}
+
+func (v *jwtVerifier) verifySignature(signingInput string, signature []byte) error {
+	switch v.cfg.Algorithm {
+	case AlgorithmHS256:
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return fmt.Errorf("%w: signature mismatch", ErrInvalidCredentials)
+		}
+	case AlgorithmRS256:
+		hash := sha256.Sum256([]byte(signingInput))
+		if err := rsa.VerifyPKCS1v15(v.publicKey, crypto.SHA256, hash[:], signature); err != nil {
+			return fmt.Errorf("%w: signature mismatch", ErrInvalidCredentials)
+		}
+	}
+	return nil
+}
+
+func (v *jwtVerifier) validateClaims(claims map[string]interface{}) error {
+	now := v.now()
+
+	exp, ok, err := timeClaim(claims, "exp")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: exp claim is required", ErrInvalidCredentials)
+	}
+	if now.After(exp.Add(v.cfg.Leeway)) {
+		return fmt.Errorf("%w: token expired", ErrInvalidCredentials)
+	}
+
+	nbf, ok, err := timeClaim(claims, "nbf")
+	if err != nil {
+		return err
+	}
+	if ok && now.Add(v.cfg.Leeway).Before(nbf) {
+		return fmt.Errorf("%w: token not valid yet", ErrInvalidCredentials)
+	}
+
+	if v.cfg.Issuer != "" && claims["iss"] != v.cfg.Issuer {
+		return fmt.Errorf("%w: unexpected issuer", ErrInvalidCredentials)
+	}
+	if v.cfg.Audience != "" && !hasAudience(claims["aud"], v.cfg.Audience) {
+		return fmt.Errorf("%w: unexpected audience", ErrInvalidCredentials)
+	}
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+func timeClaim(claims map[string]interface{}, name string) (time.Time, bool, error) {
+	value, ok := claims[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	number, ok := value.(json.Number)
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("%w: %s must be a number", ErrInvalidCredentials, name)
+	}
+	seconds, err := number.Float64()
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%w: %s must be a number", ErrInvalidCredentials, name)
+	}
+	return time.Unix(int64(seconds), 0), true, nil
+}
+
+// intClaim читает идентификатор пользователя, заданный числом или строкой из цифр
+func intClaim(claims map[string]interface{}, name string) (int, error) {
+	var raw string
+	switch value := claims[name].(type) {
+	case json.Number:
+		raw = value.String()
+	case string:
+		raw = value
+	default:
+		return 0, fmt.Errorf("%w: %s claim is required", ErrInvalidCredentials, name)
+	}
+
+	id, err := strconv.Atoi(raw)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%w: %s claim must be a positive integer", ErrInvalidCredentials, name)
+	}
+	return id, nil
+}
+
+// rolesClaim принимает роли массивом строк или строкой через пробел
+func rolesClaim(value interface{}) []string {
+	switch value := value.(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		roles := make([]string, 0, len(value))
+		for _, item := range value {
+			if role, ok := item.(string); ok {
+				roles = append(roles, role)
+			}
+		}
+		return roles
+	}
+	return nil
+}
+
+func hasAudience(value interface{}, audience string) bool {
+	switch value := value.(type) {
+	case string:
+		return value == audience
+	case []interface{}:
+		for _, item := range value {
+			if item == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
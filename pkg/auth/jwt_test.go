@@ -0,0 +1,316 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testSecret = "test-secret"
+
+var testNow = time.Unix(1_700_000_000, 0)
+
+var (
+	rsaKeyOnce sync.Once
+	rsaKey     *rsa.PrivateKey
+)
+
+func testRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	rsaKeyOnce.Do(func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("generate rsa key: %v", err)
+		}
+		rsaKey = key
+	})
+	return rsaKey
+}
+
+func pkcs1PEM(key *rsa.PublicKey) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(key)}))
+}
+
+func pkixPEM(t *testing.T, key interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("marshal pkix key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func segment(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal segment: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// signHS256 подписывает токен HMAC-SHA256; alg в заголовке задается отдельно, чтобы проверять подмену
+func signHS256(t *testing.T, alg string, secret []byte, claims map[string]interface{}) string {
+	t.Helper()
+	input := segment(t, map[string]string{"alg": alg, "typ": "JWT"}) + "." + segment(t, claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, claims map[string]interface{}) string {
+	t.Helper()
+	input := segment(t, map[string]string{"alg": AlgorithmRS256, "typ": "JWT"}) + "." + segment(t, claims)
+	hash := sha256.Sum256([]byte(input))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		t.Fatalf("sign rs256: %v", err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func unsigned(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	return segment(t, map[string]string{"alg": "none", "typ": "JWT"}) + "." + segment(t, claims) + "."
+}
+
+// claims возвращает действительные claims, измененные overrides; nil в overrides удаляет claim
+func claims(overrides map[string]interface{}) map[string]interface{} {
+	c := map[string]interface{}{
+		"sub":   "42",
+		"exp":   testNow.Add(time.Hour).Unix(),
+		"iss":   "reports-auth",
+		"aud":   "reports-api",
+		"roles": []string{"admin"},
+	}
+	for name, value := range overrides {
+		if value == nil {
+			delete(c, name)
+			continue
+		}
+		c[name] = value
+	}
+	return c
+}
+
+func newTestVerifier(t *testing.T, cfg JWTConfig) *jwtVerifier {
+	t.Helper()
+	v, err := newJWTVerifier(cfg)
+	if err != nil {
+		t.Fatalf("newJWTVerifier: %v", err)
+	}
+	v.now = func() time.Time { return testNow }
+	return v
+}
+
+func hsConfig() JWTConfig {
+	return JWTConfig{
+		Algorithm: AlgorithmHS256,
+		Secret:    testSecret,
+		Issuer:    "reports-auth",
+		Audience:  "reports-api",
+		Leeway:    30 * time.Second,
+	}
+}
+
+func TestVerifyHS256(t *testing.T) {
+	v := newTestVerifier(t, hsConfig())
+	rsaPublic := pkcs1PEM(&testRSAKey(t).PublicKey)
+
+	tests := []struct {
+		name   string
+		token  string
+		userID int
+		roles  []string
+	}{
+		{name: "valid string sub", token: signHS256(t, "HS256", []byte(testSecret), claims(nil)), userID: 42, roles: []string{"admin"}},
+		{name: "valid numeric sub", token: signHS256(t, "HS256", []byte(testSecret), claims(map[string]interface{}{"sub": 7})), userID: 7, roles: []string{"admin"}},
+		{name: "roles as space separated string", token: signHS256(t, "HS256", []byte(testSecret), claims(map[string]interface{}{"roles": "admin viewer"})), userID: 42, roles: []string{"admin", "viewer"}},
+		{name: "no roles", token: signHS256(t, "HS256", []byte(testSecret), claims(map[string]interface{}{"roles": nil})), userID: 42},
+		{name: "expired within leeway", token: signHS256(t, "HS256", []byte(testSecret), claims(map[string]interface{}{"exp": testNow.Add(-20 * time.Second).Unix()})), userID: 42, roles: []string{"admin"}},
+		{name: "nbf within leeway", token: signHS256(t, "HS256", []byte(testSecret), claims(map[string]interface{}{"nbf": testNow.Add(20 * time.Second).Unix()})), userID: 42, roles: []string{"admin"}},
+		{name: "nbf in the past", token: signHS256(t, "HS256", []byte(testSecret), claims(map[string]interface{}{"nbf": testNow.Add(-time.Hour).Unix()})), userID: 42, roles: []string{"admin"}},
+		{name: "audience array containing expected", token: signHS256(t, "HS256", []byte(testSecret), claims(map[string]interface{}{"aud": []string{"other", "reports-api"}})), userID: 42, roles: []string{"admin"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := v.Verify(tt.token)
+			if err != nil {
+				t.Fatalf("Verify: unexpected error %v", err)
+			}
+			if identity.UserID != tt.userID {
+				t.Errorf("UserID = %d, want %d", identity.UserID, tt.userID)
+			}
+			if len(identity.Roles) != 0 || len(tt.roles) != 0 {
+				if !reflect.DeepEqual(identity.Roles, tt.roles) {
+					t.Errorf("Roles = %v, want %v", identity.Roles, tt.roles)
+				}
+			}
+			if want := fmt.Sprintf("user:%d", tt.userID); identity.Subject != want {
+				t.Errorf("Subject = %q, want %q", identity.Subject, want)
+			}
+		})
+	}
+
+	rejected := []struct {
+		name  string
+		token string
+	}{
+		{name: "alg none", token: unsigned(t, claims(nil))},
+		{name: "alg none with valid hmac", token: signHS256(t, "none", []byte(testSecret), claims(nil))},
+		{name: "alg RS256 header with hmac signature", token: signHS256(t, "RS256", []byte(testSecret), claims(nil))},
+		{name: "RS256 token", token: signRS256(t, testRSAKey(t), claims(nil))},
+		{name: "hmac keyed with rsa public key", token: signHS256(t, "HS256", []byte(rsaPublic), claims(nil))},
+		{name: "wrong secret", token: signHS256(t, "HS256", []byte("other-secret"), claims(nil))},
+		{name: "tampered claims", token: tamper(t, signHS256(t, "HS256", []byte(testSecret), claims(nil)), claims(map[string]interface{}{"sub": "1"}))},
+		{name: "missing exp", token: signHS256(t, "HS256", []byte(testSecret), claims(map[string]interface{}{"exp": nil}))},
+		{name: "exp as string", token: signHS256(t, "HS256", []byte(testSecret), claims(map[string]interface{}{"exp": "tomorrow"}))},
+		{name: "expired beyond leeway", token: signHS256(t, "HS256", []byte(testSecret), claims(map[string]interface{}{"exp": testNow.Add(-time.Minute).Unix()}))},
+		{name: "nbf beyond leeway", token: signHS256(t, "HS256", []byte(testSecret), claims(map[string]interface{}{"nbf": testNow.Add(time.Minute).Unix()}))},
+		{name: "issuer mismatch", token: signHS256(t, "HS256", []byte(testSecret), claims(map[string]interface{}{"iss": "someone-else"}))},
+		{name: "issuer missing", token: signHS256(t, "HS256", []byte(testSecret), claims(map[string]interface{}{"iss": nil}))},
+		{name: "audience string mismatch", token: signHS256(t, "HS256", []byte(testSecret), claims(map[string]interface{}{"aud": "other"}))},
+		{name: "audience array mismatch", token: signHS256(t, "HS256", []byte(testSecret), claims(map[string]interface{}{"aud": []string{"a", "b"}}))},
+		{name: "audience missing", token: signHS256(t, "HS256", []byte(testSecret), claims(map[string]interface{}{"aud": nil}))},
+		{name: "missing sub", token: signHS256(t, "HS256", []byte(testSecret), claims(map[string]interface{}{"sub": nil}))},
+		{name: "non numeric sub", token: signHS256(t, "HS256", []byte(testSecret), claims(map[string]interface{}{"sub": "alice"}))},
+		{name: "zero sub", token: signHS256(t, "HS256", []byte(testSecret), claims(map[string]interface{}{"sub": 0}))},
+		{name: "negative sub", token: signHS256(t, "HS256", []byte(testSecret), claims(map[string]interface{}{"sub": "-5"}))},
+		{name: "fractional sub", token: signHS256(t, "HS256", []byte(testSecret), claims(map[string]interface{}{"sub": 4.5}))},
+		{name: "two segments", token: "abc.def"},
+		{name: "garbage header", token: "!!!.e30.sig"},
+		{name: "bad signature encoding", token: signHS256(t, "HS256", []byte(testSecret), claims(nil)) + "!"},
+	}
+	for _, tt := range rejected {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := v.Verify(tt.token)
+			if err == nil {
+				t.Fatalf("Verify: accepted token, identity %+v", identity)
+			}
+			if !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("Verify: error %v does not wrap ErrInvalidCredentials", err)
+			}
+		})
+	}
+}
+
+func TestVerifyCustomUserClaim(t *testing.T) {
+	cfg := hsConfig()
+	cfg.UserClaim = "uid"
+	cfg.RolesClaim = "scope"
+	v := newTestVerifier(t, cfg)
+
+	identity, err := v.Verify(signHS256(t, "HS256", []byte(testSecret), claims(map[string]interface{}{"sub": nil, "uid": 9, "scope": "admin"})))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if identity.UserID != 9 || !identity.IsAdmin() {
+		t.Errorf("identity = %+v, want user 9 with admin role", identity)
+	}
+}
+
+func TestVerifyRS256(t *testing.T) {
+	key := testRSAKey(t)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+
+	keyFile := filepath.Join(t.TempDir(), "public.pem")
+	if err := os.WriteFile(keyFile, []byte(pkixPEM(t, &key.PublicKey)), 0o600); err != nil {
+		t.Fatalf("write key file: %v", err)
+	}
+
+	configs := map[string]JWTConfig{
+		"PKCS1": {Algorithm: AlgorithmRS256, PublicKey: pkcs1PEM(&key.PublicKey)},
+		"PKIX":  {Algorithm: AlgorithmRS256, PublicKey: pkixPEM(t, &key.PublicKey)},
+		"file":  {Algorithm: AlgorithmRS256, PublicKeyFile: keyFile},
+	}
+	for name, cfg := range configs {
+		t.Run(name, func(t *testing.T) {
+			v := newTestVerifier(t, cfg)
+
+			identity, err := v.Verify(signRS256(t, key, claims(nil)))
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if identity.UserID != 42 {
+				t.Errorf("UserID = %d, want 42", identity.UserID)
+			}
+
+			for reason, token := range map[string]string{
+				"other key":                   signRS256(t, other, claims(nil)),
+				"alg none":                    unsigned(t, claims(nil)),
+				"HS256 keyed with public key": signHS256(t, "HS256", []byte(cfg.PublicKey), claims(nil)),
+				"RS256 header with hmac":      signHS256(t, "RS256", []byte(cfg.PublicKey), claims(nil)),
+				"expired":                     signRS256(t, key, claims(map[string]interface{}{"exp": testNow.Add(-time.Hour).Unix()})),
+				"tampered claims":             tamper(t, signRS256(t, key, claims(nil)), claims(map[string]interface{}{"sub": "1"})),
+			} {
+				if _, err := v.Verify(token); !errors.Is(err, ErrInvalidCredentials) {
+					t.Errorf("%s: Verify error = %v, want ErrInvalidCredentials", reason, err)
+				}
+			}
+		})
+	}
+}
+
+func TestNewJWTVerifierConfig(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ecdsa key: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		cfg  JWTConfig
+	}{
+		{name: "HS256 without secret", cfg: JWTConfig{Algorithm: AlgorithmHS256}},
+		{name: "RS256 without key", cfg: JWTConfig{Algorithm: AlgorithmRS256}},
+		{name: "RS256 key is not PEM", cfg: JWTConfig{Algorithm: AlgorithmRS256, PublicKey: "not a key"}},
+		{name: "RS256 with ECDSA key", cfg: JWTConfig{Algorithm: AlgorithmRS256, PublicKey: pkixPEM(t, &ecKey.PublicKey)}},
+		{name: "RS256 missing key file", cfg: JWTConfig{Algorithm: AlgorithmRS256, PublicKeyFile: filepath.Join(t.TempDir(), "missing.pem")}},
+		{name: "unsupported algorithm", cfg: JWTConfig{Algorithm: "none", Secret: testSecret}},
+		{name: "ES256 is not supported", cfg: JWTConfig{Algorithm: "ES256", PublicKey: pkixPEM(t, &ecKey.PublicKey)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newJWTVerifier(tt.cfg); err == nil {
+				t.Fatal("newJWTVerifier: expected error")
+			}
+		})
+	}
+
+	// Без algorithm используется HS256
+	v, err := newJWTVerifier(JWTConfig{Secret: testSecret})
+	if err != nil {
+		t.Fatalf("newJWTVerifier: %v", err)
+	}
+	if v.cfg.Algorithm != AlgorithmHS256 {
+		t.Errorf("default algorithm = %q, want HS256", v.cfg.Algorithm)
+	}
+}
+
+// tamper заменяет claims токена, сохраняя заголовок и подпись
+func tamper(t *testing.T, token string, replaced map[string]interface{}) string {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	parts[1] = segment(t, replaced)
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,135 @@
+// Package health отдает /healthz и /readyz. /healthz отвечает, пока процесс жив;
+// /readyz проверяет зависимости сервиса (Postgres, MinIO, Kafka) и перестает отвечать 200,
+// как только сервис начинает останавливаться, чтобы балансировщик снял с него нагрузку.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check проверяет одну зависимость и возвращает ошибку, если она недоступна
+type Check func(ctx context.Context) error
+
+// Pinger — зависимость с методом PingContext, например *sql.DB
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// Ping превращает Pinger в Check
+func Ping(p Pinger) Check {
+	return p.PingContext
+}
+
+const (
+	StatusOK       = "ok"
+	StatusError    = "error"
+	StatusDraining = "draining"
+)
+
+// CheckResult — результат одной проверки в ответе /readyz
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report — тело ответа /healthz и /readyz
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Checker хранит проверки зависимостей сервиса
+type Checker struct {
+	// timeout ограничивает время всех проверок одного запроса /readyz
+	timeout  time.Duration
+	mu       sync.Mutex
+	checks   map[string]Check
+	draining atomic.Bool
+}
+
+func New(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+		checks:  make(map[string]Check),
+	}
+}
+
+// Add регистрирует проверку зависимости name
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check
+}
+
+// Drain переводит /readyz в 503: сервис останавливается и не должен получать новую работу
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Run выполняет все проверки параллельно
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.Lock()
+	checks := make(map[string]Check, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(checks))}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			result := CheckResult{Status: StatusOK}
+			if err := check(ctx); err != nil {
+				result = CheckResult{Status: StatusError, Error: err.Error()}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status != StatusOK {
+				report.Status = StatusError
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	if c.draining.Load() {
+		report.Status = StatusDraining
+	}
+	return report
+}
+
+// Liveness обрабатывает /healthz: процесс жив и обслуживает HTTP
+func (c *Checker) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, http.StatusOK, Report{Status: StatusOK})
+}
+
+// Readiness обрабатывает /readyz: 200, если все зависимости доступны и сервис не останавливается, иначе 503
+func (c *Checker) Readiness(w http.ResponseWriter, r *http.Request) {
+	report := c.Run(r.Context())
+	code := http.StatusOK
+	if report.Status != StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	writeReport(w, code, report)
+}
+
+func writeReport(w http.ResponseWriter, code int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(report)
+}
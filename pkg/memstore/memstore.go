@@ -0,0 +1,157 @@
+// Package memstore хранит запросы на отчет и их историю в памяти процесса.
+// Это общее хранилище для in-memory репозиториев reports_register, reports_generator
+// и reports_publisher: подключив их к одному Store, можно прогнать весь путь запроса
+// от создания до уведомления без Postgres, например в модульных тестах.
+package memstore
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KostySCH/Reports_go/pkg/audit"
+	"github.com/google/uuid"
+)
+
+// Request — строка reporting.report_requests. Сервисы переводят ее в свои модели.
+type Request struct {
+	ID             uuid.UUID
+	UserID         int
+	Type           string
+	Params         []byte
+	ParamsHash     string
+	Status         string
+	Error          *string
+	RetryCount     int
+	ReportPath     *string
+	Priority       int
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	IdempotencyKey *string
+	RequeuedBy     *string
+	RequeueReason  *string
+	RequeuedAt     *time.Time
+	ScheduleID     *uuid.UUID
+	ScheduledFor   *time.Time
+	BatchID        *uuid.UUID
+	CallbackURL    *string
+	CallbackSecret *string
+	ReusedFrom     *uuid.UUID
+	CompletedAt    *time.Time
+
+	NotificationSent bool
+	LeaseOwner       *string
+	LeaseExpiresAt   *time.Time
+}
+
+// Clone возвращает копию строки, которую можно менять без блокировки хранилища
+func (r *Request) Clone() *Request {
+	c := *r
+	c.Params = append([]byte(nil), r.Params...)
+	return &c
+}
+
+// Event — строка reporting.report_request_events
+type Event struct {
+	ID int64
+	audit.Event
+	CreatedAt time.Time
+}
+
+// DetailsJSON возвращает детали события в том виде, в каком их хранит колонка details
+func (e Event) DetailsJSON() []byte {
+	if len(e.Details) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(e.Details)
+	return data
+}
+
+// Store — потокобезопасное хранилище запросов. Все изменения выполняются внутри Tx
+// под одной блокировкой, поэтому проверки и обновления в fn атомарны.
+type Store struct {
+	mu       sync.Mutex
+	requests map[uuid.UUID]*Request
+	events   []Event
+	// now позволяет подменить часы; по умолчанию time.Now
+	now func() time.Time
+}
+
+// New создает пустое хранилище
+func New() *Store {
+	return &Store{
+		requests: make(map[uuid.UUID]*Request),
+		now:      time.Now,
+	}
+}
+
+// SetClock подменяет часы хранилища, например чтобы проверить истечение аренды
+func (s *Store) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// Tx выполняет fn под блокировкой хранилища. Отката нет: fn должна проверить все условия
+// до первого изменения, как это делают репозитории.
+func (s *Store) Tx(fn func(tx *Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn(&Tx{store: s})
+}
+
+// Tx дает доступ к данным хранилища внутри Store.Tx
+type Tx struct {
+	store *Store
+}
+
+// Now возвращает текущее время по часам хранилища
+func (tx *Tx) Now() time.Time {
+	return tx.store.now()
+}
+
+// Get возвращает строку запроса для изменения или nil
+func (tx *Tx) Get(id uuid.UUID) *Request {
+	return tx.store.requests[id]
+}
+
+// Insert добавляет запрос; существующий запрос с тем же id заменяется
+func (tx *Tx) Insert(request *Request) {
+	tx.store.requests[request.ID] = request
+}
+
+// Select возвращает строки, для которых match вернул true, упорядоченные less.
+// Строки возвращаются без копирования и могут изменяться внутри Tx.
+func (tx *Tx) Select(match func(*Request) bool, less func(a, b *Request) bool) []*Request {
+	var rows []*Request
+	for _, r := range tx.store.requests {
+		if match == nil || match(r) {
+			rows = append(rows, r)
+		}
+	}
+	if less != nil {
+		sort.Slice(rows, func(i, j int) bool { return less(rows[i], rows[j]) })
+	}
+	return rows
+}
+
+// Record добавляет событие в историю запроса, как audit.Record
+func (tx *Tx) Record(event audit.Event) {
+	tx.store.events = append(tx.store.events, Event{
+		ID:        int64(len(tx.store.events) + 1),
+		Event:     event,
+		CreatedAt: tx.Now(),
+	})
+}
+
+// Events возвращает историю запроса в порядке записи
+func (tx *Tx) Events(id uuid.UUID) []Event {
+	var events []Event
+	for _, e := range tx.store.events {
+		if e.RequestID == id {
+			events = append(events, e)
+		}
+	}
+	return events
+}
@@ -0,0 +1,72 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// CommandName — имя подкоманды, которую сервисы передают в Run
+const CommandName = "migrate"
+
+const usage = "usage: migrate up | migrate down [steps] | migrate status"
+
+// Run выполняет подкоманду migrate: up применяет все миграции, down откатывает steps последних
+// (по умолчанию одну), status печатает состояние каждой миграции
+func Run(ctx context.Context, db *sql.DB, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	m, err := New(db)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, migration := range applied {
+			fmt.Fprintf(out, "applied %s\n", migration)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "schema is up to date")
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				return fmt.Errorf("invalid steps %q: %s", args[1], usage)
+			}
+		}
+		reverted, err := m.Down(ctx, steps)
+		for _, migration := range reverted {
+			fmt.Fprintf(out, "reverted %s\n", migration)
+		}
+		return err
+
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return w.Flush()
+	}
+
+	return fmt.Errorf("unknown command %q: %s", args[0], usage)
+}
@@ -0,0 +1,293 @@
+// Package migrations содержит версионированные миграции схемы reporting, общие для всех сервисов.
+// Миграции встроены в бинарные файлы; каждая применяется в отдельной транзакции, а параллельный
+// запуск из нескольких сервисов исключается advisory-блокировкой.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockKey — ключ advisory-блокировки миграций
+const lockKey = 7_317_045_112
+
+// ErrSchemaOutdated возвращается, если в базе применены не все миграции, известные сервису
+var ErrSchemaOutdated = errors.New("database schema is out of date")
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration описывает одну версию схемы
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Status описывает состояние миграции в базе; AppliedAt равен nil, если миграция не применена
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// All возвращает встроенные миграции по возрастанию версии
+func All() ([]Migration, error) {
+	sub, err := fs.Sub(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+	return load(sub)
+}
+
+// load читает миграции из корня fsys и сопоставляет up- и down-файлы одной версии
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migrations: unexpected file name %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migrations: version %d has different names %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migrations: %s must have both up and down files", m)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func New(db *sql.DB) (*Migrator, error) {
+	migrations, err := All()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// EnsureUpToDate проверяет, что применены все миграции, известные сервису.
+// Если в базе есть миграции новее сервиса, это не ошибка: более новая схема остается совместимой
+// со старыми сервисами на время выкладки.
+func EnsureUpToDate(ctx context.Context, db *sql.DB) error {
+	m, err := New(db)
+	if err != nil {
+		return err
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	var pending []string
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, status.String())
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: pending migrations %s; run \"migrate up\"", ErrSchemaOutdated, strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// Status возвращает все известные миграции с отметкой о применении. Не изменяет базу.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Up применяет все непримененные миграции по возрастанию версии и возвращает примененные
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			err := m.apply(ctx, conn, migration.Up, `
+				INSERT INTO reporting.schema_migrations (version, name, applied_at) VALUES ($1, $2, now())
+			`, migration.Version, migration.Name)
+			if err != nil {
+				return fmt.Errorf("migration %s: %w", migration, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down откатывает steps последних примененных миграций и возвращает откаченные
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			err := m.apply(ctx, conn, migration.Down, `
+				DELETE FROM reporting.schema_migrations WHERE version = $1
+			`, migration.Version)
+			if err != nil {
+				return fmt.Errorf("migration %s: %w", migration, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// applied возвращает версии примененных миграций; если таблицы учета еще нет, миграций нет
+func (m *Migrator) applied(ctx context.Context, q querier) (map[int]time.Time, error) {
+	var exists bool
+	err := q.QueryRowContext(ctx, `SELECT to_regclass('reporting.schema_migrations') IS NOT NULL`).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]time.Time)
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := q.QueryContext(ctx, `SELECT version, applied_at FROM reporting.schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// apply выполняет миграцию и запись о ней в одной транзакции
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, script, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// withLock выполняет fn на выделенном соединении под advisory-блокировкой,
+// предварительно создав таблицу учета миграций
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	// Блокировка сессионная: снимаем ее на том же соединении даже после отмены ctx
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE SCHEMA IF NOT EXISTS reporting;
+		CREATE TABLE IF NOT EXISTS reporting.schema_migrations (
+			version    BIGINT PRIMARY KEY,
+			name       TEXT        NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
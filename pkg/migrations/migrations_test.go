@@ -0,0 +1,111 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAll(t *testing.T) {
+	migrations, err := All()
+	if err != nil {
+		t.Fatalf("All: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no embedded migrations")
+	}
+
+	for i, m := range migrations {
+		// Версии идут подряд с 1: пропуск обычно означает потерянный файл
+		if m.Version != i+1 {
+			t.Errorf("migration %d has version %d, want %d", i, m.Version, i+1)
+		}
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			t.Errorf("migration %s has an empty up or down script", m)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file := func(data string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(data)} }
+
+	migrations, err := load(fstest.MapFS{
+		"0010_reuse.down.sql":  file("down 10"),
+		"0002_second.up.sql":   file("up 2"),
+		"0010_reuse.up.sql":    file("up 10"),
+		"0001_init.down.sql":   file("down 1"),
+		"0002_second.down.sql": file("down 2"),
+		"0001_init.up.sql":     file("up 1"),
+	})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	want := []Migration{
+		{Version: 1, Name: "init", Up: "up 1", Down: "down 1"},
+		{Version: 2, Name: "second", Up: "up 2", Down: "down 2"},
+		{Version: 10, Name: "reuse", Up: "up 10", Down: "down 10"},
+	}
+	if len(migrations) != len(want) {
+		t.Fatalf("got %d migrations, want %d", len(migrations), len(want))
+	}
+	for i := range want {
+		if migrations[i] != want[i] {
+			t.Errorf("migration %d = %+v, want %+v", i, migrations[i], want[i])
+		}
+	}
+	if got := migrations[0].String(); got != "0001_init" {
+		t.Errorf("String() = %q, want %q", got, "0001_init")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	file := &fstest.MapFile{Data: []byte("SELECT 1;")}
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		wantErr string
+	}{
+		{
+			name:    "unexpected file name",
+			files:   fstest.MapFS{"0001_init.sql": file},
+			wantErr: "unexpected file name 0001_init.sql",
+		},
+		{
+			name:    "upper case name",
+			files:   fstest.MapFS{"0001_Init.up.sql": file},
+			wantErr: "unexpected file name",
+		},
+		{
+			name:    "missing down",
+			files:   fstest.MapFS{"0001_init.up.sql": file},
+			wantErr: "0001_init must have both up and down files",
+		},
+		{
+			name:    "missing up",
+			files:   fstest.MapFS{"0003_audit.down.sql": file},
+			wantErr: "0003_audit must have both up and down files",
+		},
+		{
+			name:    "empty script",
+			files:   fstest.MapFS{"0001_init.up.sql": file, "0001_init.down.sql": &fstest.MapFile{}},
+			wantErr: "must have both up and down files",
+		},
+		{
+			name: "different names for one version",
+			files: fstest.MapFS{
+				"0002_audit.up.sql":     file,
+				"0002_history.down.sql": file,
+			},
+			wantErr: "version 2 has different names",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(tt.files)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("load error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
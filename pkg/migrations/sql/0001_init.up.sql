@@ -0,0 +1,27 @@
+-- Базовая схема. IF NOT EXISTS позволяет принять под управление миграций базы,
+-- в которых таблица уже была создана вручную.
+CREATE SCHEMA IF NOT EXISTS reporting;
+
+CREATE TABLE IF NOT EXISTS reporting.report_requests (
+    id                UUID PRIMARY KEY,
+    user_id           BIGINT      NOT NULL,
+    type              TEXT        NOT NULL,
+    params            JSONB       NOT NULL DEFAULT '{}'::jsonb,
+    status            VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    error             TEXT,
+    retry_count       INTEGER     NOT NULL DEFAULT 0,
+    report_path       TEXT,
+    notification_sent BOOLEAN     NOT NULL DEFAULT FALSE,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS report_requests_status_created_at_idx
+    ON reporting.report_requests (status, created_at);
+
+CREATE INDEX IF NOT EXISTS report_requests_user_id_created_at_idx
+    ON reporting.report_requests (user_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS report_requests_notification_idx
+    ON reporting.report_requests (updated_at)
+    WHERE notification_sent = FALSE;
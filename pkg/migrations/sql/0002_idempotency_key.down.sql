@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS reporting.report_requests_user_idempotency_key_idx;
+
+ALTER TABLE reporting.report_requests
+    DROP COLUMN IF EXISTS idempotency_key;
@@ -0,0 +1,6 @@
+ALTER TABLE reporting.report_requests
+    ADD COLUMN IF NOT EXISTS idempotency_key VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS report_requests_user_idempotency_key_idx
+    ON reporting.report_requests (user_id, idempotency_key)
+    WHERE idempotency_key IS NOT NULL;
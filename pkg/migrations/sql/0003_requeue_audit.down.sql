@@ -0,0 +1,4 @@
+ALTER TABLE reporting.report_requests
+    DROP COLUMN IF EXISTS requeued_by,
+    DROP COLUMN IF EXISTS requeue_reason,
+    DROP COLUMN IF EXISTS requeued_at;
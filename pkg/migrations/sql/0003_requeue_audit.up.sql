@@ -0,0 +1,4 @@
+ALTER TABLE reporting.report_requests
+    ADD COLUMN IF NOT EXISTS requeued_by    TEXT,
+    ADD COLUMN IF NOT EXISTS requeue_reason TEXT,
+    ADD COLUMN IF NOT EXISTS requeued_at    TIMESTAMPTZ;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS reporting.report_requests_schedule_run_idx;
+
+ALTER TABLE reporting.report_requests
+    DROP COLUMN IF EXISTS schedule_id,
+    DROP COLUMN IF EXISTS scheduled_for;
+
+DROP TABLE IF EXISTS reporting.report_schedules;
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS reporting.report_schedules (
+    id              UUID PRIMARY KEY,
+    name            TEXT        NOT NULL,
+    owner_id        BIGINT      NOT NULL,
+    cron_expr       TEXT        NOT NULL,
+    timezone        TEXT        NOT NULL DEFAULT 'UTC',
+    type            TEXT        NOT NULL,
+    params_template JSONB       NOT NULL DEFAULT '{}'::jsonb,
+    enabled         BOOLEAN     NOT NULL DEFAULT TRUE,
+    next_run_at     TIMESTAMPTZ NOT NULL,
+    last_run_at     TIMESTAMPTZ,
+    last_error      TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS report_schedules_due_idx
+    ON reporting.report_schedules (next_run_at)
+    WHERE enabled;
+
+CREATE INDEX IF NOT EXISTS report_schedules_owner_id_idx
+    ON reporting.report_schedules (owner_id);
+
+ALTER TABLE reporting.report_requests
+    ADD COLUMN IF NOT EXISTS schedule_id   UUID REFERENCES reporting.report_schedules (id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS scheduled_for TIMESTAMPTZ;
+
+-- Один запуск расписания создает не больше одного запроса, даже если планировщиков несколько
+CREATE UNIQUE INDEX IF NOT EXISTS report_requests_schedule_run_idx
+    ON reporting.report_requests (schedule_id, scheduled_for);
@@ -0,0 +1,4 @@
+ALTER TABLE reporting.report_requests
+    DROP COLUMN IF EXISTS batch_id;
+
+DROP TABLE IF EXISTS reporting.report_batches;
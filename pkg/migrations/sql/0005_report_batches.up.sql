@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS reporting.report_batches (
+    id         UUID PRIMARY KEY,
+    user_id    BIGINT      NOT NULL,
+    total      INTEGER     NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE reporting.report_requests
+    ADD COLUMN IF NOT EXISTS batch_id UUID REFERENCES reporting.report_batches (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS report_requests_batch_id_idx
+    ON reporting.report_requests (batch_id)
+    WHERE batch_id IS NOT NULL;
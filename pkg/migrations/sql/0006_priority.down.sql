@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS reporting.report_requests_pending_queue_idx;
+
+ALTER TABLE reporting.report_schedules
+    DROP COLUMN IF EXISTS priority;
+
+ALTER TABLE reporting.report_requests
+    DROP COLUMN IF EXISTS priority;
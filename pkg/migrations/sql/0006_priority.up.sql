@@ -0,0 +1,10 @@
+ALTER TABLE reporting.report_requests
+    ADD COLUMN IF NOT EXISTS priority SMALLINT NOT NULL DEFAULT 5;
+
+ALTER TABLE reporting.report_schedules
+    ADD COLUMN IF NOT EXISTS priority SMALLINT NOT NULL DEFAULT 5;
+
+-- Генератор выбирает ожидающие запросы по убыванию приоритета, затем по времени создания
+CREATE INDEX IF NOT EXISTS report_requests_pending_queue_idx
+    ON reporting.report_requests (priority DESC, created_at)
+    WHERE status = 'PENDING';
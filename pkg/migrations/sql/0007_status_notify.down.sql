@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS report_requests_notify ON reporting.report_requests;
+
+DROP FUNCTION IF EXISTS reporting.notify_report_request_change();
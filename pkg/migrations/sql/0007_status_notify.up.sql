@@ -0,0 +1,19 @@
+-- Уведомления для потока событий GET /api/reports/{id}/events
+CREATE OR REPLACE FUNCTION reporting.notify_report_request_change() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('report_request_changes', json_build_object(
+        'id', NEW.id,
+        'status', NEW.status,
+        'retry_count', NEW.retry_count
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS report_requests_notify ON reporting.report_requests;
+
+CREATE TRIGGER report_requests_notify
+    AFTER UPDATE OF status, retry_count ON reporting.report_requests
+    FOR EACH ROW
+    WHEN (OLD.status IS DISTINCT FROM NEW.status OR OLD.retry_count IS DISTINCT FROM NEW.retry_count)
+    EXECUTE FUNCTION reporting.notify_report_request_change();
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS reporting.webhook_delivery_attempts;
+DROP TABLE IF EXISTS reporting.webhook_deliveries;
+
+DROP INDEX IF EXISTS reporting.report_requests_webhook_due_idx;
+
+ALTER TABLE reporting.report_requests
+    DROP COLUMN IF EXISTS callback_url,
+    DROP COLUMN IF EXISTS callback_secret,
+    DROP COLUMN IF EXISTS webhook_status;
@@ -0,0 +1,43 @@
+ALTER TABLE reporting.report_requests
+    ADD COLUMN IF NOT EXISTS callback_url    TEXT,
+    ADD COLUMN IF NOT EXISTS callback_secret TEXT,
+    -- Статус, для которого уже создана доставка вебхука
+    ADD COLUMN IF NOT EXISTS webhook_status  VARCHAR(20);
+
+CREATE INDEX IF NOT EXISTS report_requests_webhook_due_idx
+    ON reporting.report_requests (status)
+    WHERE callback_url IS NOT NULL AND webhook_status IS DISTINCT FROM status;
+
+CREATE TABLE IF NOT EXISTS reporting.webhook_deliveries (
+    id               UUID PRIMARY KEY,
+    request_id       UUID        NOT NULL REFERENCES reporting.report_requests (id) ON DELETE CASCADE,
+    status           VARCHAR(20) NOT NULL,
+    state            VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    attempts         INTEGER     NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_status_code INTEGER,
+    last_error       TEXT,
+    delivered_at     TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx
+    ON reporting.webhook_deliveries (next_attempt_at)
+    WHERE state = 'PENDING';
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_request_id_idx
+    ON reporting.webhook_deliveries (request_id);
+
+CREATE TABLE IF NOT EXISTS reporting.webhook_delivery_attempts (
+    id           BIGSERIAL PRIMARY KEY,
+    delivery_id  UUID        NOT NULL REFERENCES reporting.webhook_deliveries (id) ON DELETE CASCADE,
+    attempt      INTEGER     NOT NULL,
+    status_code  INTEGER,
+    error        TEXT,
+    duration_ms  BIGINT      NOT NULL,
+    attempted_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_attempts_delivery_id_idx
+    ON reporting.webhook_delivery_attempts (delivery_id);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS reporting.report_request_events;
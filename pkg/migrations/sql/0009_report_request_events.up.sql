@@ -0,0 +1,18 @@
+-- История — журнал аудита и должна переживать удаление запроса, поэтому request_id
+-- остается обычной индексированной колонкой без внешнего ключа
+CREATE TABLE IF NOT EXISTS reporting.report_request_events (
+    id          BIGSERIAL PRIMARY KEY,
+    request_id  UUID        NOT NULL,
+    event_type  VARCHAR(30) NOT NULL,
+    from_status VARCHAR(20),
+    to_status   VARCHAR(20),
+    retry_count INTEGER,
+    error       TEXT,
+    actor       TEXT        NOT NULL,
+    source      VARCHAR(30) NOT NULL,
+    details     JSONB,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS report_request_events_request_id_idx
+    ON reporting.report_request_events (request_id, id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS reporting.report_requests_reusable_idx;
+
+ALTER TABLE reporting.report_requests
+    DROP COLUMN IF EXISTS completed_at,
+    DROP COLUMN IF EXISTS reused_from,
+    DROP COLUMN IF EXISTS params_hash;
@@ -0,0 +1,15 @@
+-- params_hash — sha256 от типа и канонического JSON параметров, по нему ищется готовый результат.
+-- completed_at — когда сформирован результат: updated_at меняется и после завершения,
+-- а копия, повторно использующая результат, наследует completed_at источника.
+ALTER TABLE reporting.report_requests
+    ADD COLUMN IF NOT EXISTS params_hash VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS reused_from UUID REFERENCES reporting.report_requests (id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS completed_at TIMESTAMPTZ;
+
+UPDATE reporting.report_requests
+SET completed_at = updated_at
+WHERE status = 'COMPLETED' AND completed_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS report_requests_reusable_idx
+    ON reporting.report_requests (type, params_hash, completed_at DESC)
+    WHERE status = 'COMPLETED' AND report_path IS NOT NULL;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS reporting.report_requests_lease_expiry_idx;
+
+ALTER TABLE reporting.report_requests
+    DROP COLUMN IF EXISTS lease_expires_at,
+    DROP COLUMN IF EXISTS lease_owner;
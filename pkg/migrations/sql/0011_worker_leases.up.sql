@@ -0,0 +1,9 @@
+-- Аренда запроса воркером генератора: владелец продлевает lease_expires_at во время генерации,
+-- а запросы с истекшей арендой возвращаются в очередь
+ALTER TABLE reporting.report_requests
+    ADD COLUMN IF NOT EXISTS lease_owner TEXT,
+    ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS report_requests_lease_expiry_idx
+    ON reporting.report_requests (lease_expires_at)
+    WHERE status = 'IN_PROGRESS';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS reporting.report_definition_shares;
+
+DROP TABLE IF EXISTS reporting.report_definitions;
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS reporting.report_definitions (
+    id              UUID PRIMARY KEY,
+    name            TEXT        NOT NULL,
+    owner_id        BIGINT      NOT NULL,
+    type            TEXT        NOT NULL,
+    params          JSONB       NOT NULL DEFAULT '{}'::jsonb,
+    priority        SMALLINT    NOT NULL DEFAULT 5,
+    callback_url    TEXT,
+    callback_secret TEXT,
+    reuse           BOOLEAN     NOT NULL DEFAULT TRUE,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Имя определения уникально в пределах владельца
+CREATE UNIQUE INDEX IF NOT EXISTS report_definitions_owner_name_idx
+    ON reporting.report_definitions (owner_id, name);
+
+-- Пользователи, которым владелец открыл определение для просмотра и запуска
+CREATE TABLE IF NOT EXISTS reporting.report_definition_shares (
+    definition_id UUID        NOT NULL REFERENCES reporting.report_definitions (id) ON DELETE CASCADE,
+    user_id       BIGINT      NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (definition_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS report_definition_shares_user_id_idx
+    ON reporting.report_definition_shares (user_id);
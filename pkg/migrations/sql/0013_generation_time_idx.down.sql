@@ -0,0 +1 @@
+DROP INDEX IF EXISTS reporting.report_request_events_completed_idx;
@@ -0,0 +1,4 @@
+-- Оценка времени готовности в reports_register читает недавние завершения генерации
+CREATE INDEX IF NOT EXISTS report_request_events_completed_idx
+    ON reporting.report_request_events (created_at)
+    WHERE to_status = 'COMPLETED';
@@ -0,0 +1,11 @@
+// Package queue описывает правила очереди генерации, общие для reports_register и reports_generator.
+package queue
+
+// MaxRetries — сколько попыток генератор делает для неудачного запроса. Запрос в FAILED,
+// у которого счетчик попыток меньше MaxRetries, еще вернется в обработку.
+const MaxRetries = 5
+
+// RetriesExhausted сообщает, что генератор больше не будет повторять запрос с retryCount попытками
+func RetriesExhausted(retryCount int) bool {
+	return retryCount >= MaxRetries
+}
@@ -0,0 +1,42 @@
+package reporttype
+
+// BranchPerformanceReport — отчет по эффективности филиала за месяц
+const BranchPerformanceReport = "branch_performance_report"
+
+func float(v float64) *float64 {
+	return &v
+}
+
+// Default возвращает реестр с типами отчетов, которые умеет генерировать reports_generator.
+// Генератор каждого типа проверяет параметры по этому же описанию.
+func Default() *Registry {
+	return NewRegistry(
+		Definition{
+			Name:        BranchPerformanceReport,
+			Description: "Отчет по эффективности филиала за месяц",
+			Params: []Param{
+				{
+					Name:        "branch_id",
+					Kind:        KindInteger,
+					Required:    true,
+					Description: "ID филиала",
+					Minimum:     float(1),
+				},
+				{
+					Name:        "month",
+					Kind:        KindString,
+					Required:    true,
+					Description: "Отчетный месяц",
+					Format:      "YYYY-MM",
+				},
+				{
+					Name:        "format",
+					Kind:        KindString,
+					Required:    true,
+					Description: "Формат документа",
+					Enum:        []string{"pdf", "docx"},
+				},
+			},
+		},
+	)
+}
@@ -0,0 +1,85 @@
+package reporttype
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// formatLayouts сопоставляет поддерживаемые форматы строковых параметров с layout для time.Parse
+var formatLayouts = map[string]string{
+	"YYYY-MM":    "2006-01",
+	"YYYY-MM-DD": "2006-01-02",
+}
+
+// check проверяет значение параметра и приводит его к каноническому виду
+func (p Param) check(value interface{}) (interface{}, error) {
+	switch p.Kind {
+	case KindInteger:
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) {
+			return nil, errors.New("must be an integer")
+		}
+		if err := p.checkRange(number); err != nil {
+			return nil, err
+		}
+		return int64(number), nil
+
+	case KindNumber:
+		number, ok := value.(float64)
+		if !ok {
+			return nil, errors.New("must be a number")
+		}
+		if err := p.checkRange(number); err != nil {
+			return nil, err
+		}
+		return number, nil
+
+	case KindBoolean:
+		if _, ok := value.(bool); !ok {
+			return nil, errors.New("must be a boolean")
+		}
+		return value, nil
+
+	case KindString:
+		str, ok := value.(string)
+		if !ok {
+			return nil, errors.New("must be a string")
+		}
+		if len(p.Enum) > 0 && !contains(p.Enum, str) {
+			return nil, fmt.Errorf("must be one of %v", p.Enum)
+		}
+		if p.Format != "" {
+			layout, ok := formatLayouts[p.Format]
+			if !ok {
+				return nil, fmt.Errorf("unsupported format %s", p.Format)
+			}
+			if _, err := time.Parse(layout, str); err != nil {
+				return nil, fmt.Errorf("must match format %s", p.Format)
+			}
+		}
+		return str, nil
+	}
+
+	return nil, fmt.Errorf("unsupported parameter type %s", p.Kind)
+}
+
+func (p Param) checkRange(number float64) error {
+	if p.Minimum != nil && number < *p.Minimum {
+		return fmt.Errorf("must be greater than or equal to %v", *p.Minimum)
+	}
+	if p.Maximum != nil && number > *p.Maximum {
+		return fmt.Errorf("must be less than or equal to %v", *p.Maximum)
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
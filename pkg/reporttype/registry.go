@@ -0,0 +1,175 @@
+// Package reporttype описывает типы отчетов и схемы их параметров. Это единственное описание
+// параметров: reports_register проверяет по нему запросы клиентов, а reports_generator — параметры
+// перед генерацией, поэтому сервисы не расходятся в том, какие запросы допустимы.
+package reporttype
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ParamKind определяет JSON-тип параметра отчета
+type ParamKind string
+
+const (
+	KindInteger ParamKind = "integer"
+	KindNumber  ParamKind = "number"
+	KindString  ParamKind = "string"
+	KindBoolean ParamKind = "boolean"
+)
+
+// Param описывает параметр отчета и правила его проверки
+type Param struct {
+	Name        string    `json:"name"`
+	Kind        ParamKind `json:"type"`
+	Required    bool      `json:"required"`
+	Description string    `json:"description,omitempty"`
+	Format      string    `json:"format,omitempty" example:"YYYY-MM"`
+	Enum        []string  `json:"enum,omitempty"`
+	Minimum     *float64  `json:"minimum,omitempty"`
+	Maximum     *float64  `json:"maximum,omitempty"`
+}
+
+// Definition описывает тип отчета, доступный для заказа
+type Definition struct {
+	Name        string  `json:"name" example:"branch_performance_report"`
+	Description string  `json:"description"`
+	Params      []Param `json:"params"`
+}
+
+// FieldError описывает ошибку проверки одного поля запроса
+type FieldError struct {
+	Field   string `json:"field" example:"params.month"`
+	Message string `json:"message" example:"must match format YYYY-MM"`
+}
+
+// ValidationError содержит все ошибки проверки запроса на отчет
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + ": " + field.Message
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) add(field, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Registry хранит известные типы отчетов
+type Registry struct {
+	mu          sync.RWMutex
+	definitions map[string]Definition
+}
+
+func NewRegistry(definitions ...Definition) *Registry {
+	r := &Registry{definitions: make(map[string]Definition)}
+	for _, definition := range definitions {
+		r.Register(definition)
+	}
+	return r
+}
+
+// Register добавляет или заменяет тип отчета
+func (r *Registry) Register(definition Definition) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.definitions[definition.Name] = definition
+}
+
+// Get возвращает описание типа отчета по имени
+func (r *Registry) Get(name string) (Definition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	definition, ok := r.definitions[name]
+	return definition, ok
+}
+
+// List возвращает все зарегистрированные типы отчетов, упорядоченные по имени
+func (r *Registry) List() []Definition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	definitions := make([]Definition, 0, len(r.definitions))
+	for _, definition := range r.definitions {
+		definitions = append(definitions, definition)
+	}
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Name < definitions[j].Name
+	})
+	return definitions
+}
+
+// Validate проверяет тип и параметры отчета и возвращает нормализованные параметры.
+// Все найденные ошибки возвращаются одним *ValidationError.
+func (r *Registry) Validate(reportType string, params map[string]interface{}) (map[string]interface{}, error) {
+	verr := &ValidationError{}
+
+	definition, ok := r.Get(reportType)
+	if !ok {
+		if reportType == "" {
+			verr.add("type", "is required")
+		} else {
+			verr.add("type", "unknown report type %q", reportType)
+		}
+		return nil, verr
+	}
+
+	normalized := make(map[string]interface{}, len(definition.Params))
+	known := make(map[string]bool, len(definition.Params))
+	for _, param := range definition.Params {
+		known[param.Name] = true
+		field := "params." + param.Name
+
+		value, ok := params[param.Name]
+		if !ok || value == nil {
+			if param.Required {
+				verr.add(field, "is required")
+			}
+			continue
+		}
+
+		value, err := param.check(value)
+		if err != nil {
+			verr.add(field, "%s", err.Error())
+			continue
+		}
+		normalized[param.Name] = value
+	}
+
+	unknown := make([]string, 0)
+	for name := range params {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		verr.add("params."+name, "unknown parameter")
+	}
+
+	if len(verr.Fields) > 0 {
+		return nil, verr
+	}
+	return normalized, nil
+}
+
+// ValidateJSON проверяет параметры, пришедшие JSON-объектом, так же, как Validate
+func (r *Registry) ValidateJSON(reportType string, raw json.RawMessage) (map[string]interface{}, error) {
+	var params map[string]interface{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &params); err != nil {
+			verr := &ValidationError{}
+			verr.add("params", "must be a JSON object")
+			return nil, verr
+		}
+	}
+	return r.Validate(reportType, params)
+}
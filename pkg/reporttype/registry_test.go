@@ -0,0 +1,178 @@
+package reporttype
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func testRegistry() *Registry {
+	return NewRegistry(
+		Definition{
+			Name: "test_report",
+			Params: []Param{
+				{Name: "branch_id", Kind: KindInteger, Required: true, Minimum: float(1), Maximum: float(100)},
+				{Name: "ratio", Kind: KindNumber, Minimum: float(0)},
+				{Name: "month", Kind: KindString, Required: true, Format: "YYYY-MM"},
+				{Name: "day", Kind: KindString, Format: "YYYY-MM-DD"},
+				{Name: "format", Kind: KindString, Enum: []string{"pdf", "docx"}},
+				{Name: "draft", Kind: KindBoolean},
+			},
+		},
+	)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		reportType string
+		params     map[string]interface{}
+		want       map[string]interface{}
+		wantFields []FieldError
+	}{
+		{
+			name:       "valid params are normalised",
+			reportType: "test_report",
+			params: map[string]interface{}{
+				"branch_id": float64(3), "ratio": 0.5, "month": "2024-05",
+				"day": "2024-05-31", "format": "pdf", "draft": true,
+			},
+			want: map[string]interface{}{
+				"branch_id": int64(3), "ratio": 0.5, "month": "2024-05",
+				"day": "2024-05-31", "format": "pdf", "draft": true,
+			},
+		},
+		{
+			name:       "optional params may be omitted or null",
+			reportType: "test_report",
+			params:     map[string]interface{}{"branch_id": float64(1), "month": "2024-05", "format": nil},
+			want:       map[string]interface{}{"branch_id": int64(1), "month": "2024-05"},
+		},
+		{
+			name:       "missing type",
+			wantFields: []FieldError{{Field: "type", Message: "is required"}},
+		},
+		{
+			name:       "unknown type",
+			reportType: "nope",
+			wantFields: []FieldError{{Field: "type", Message: `unknown report type "nope"`}},
+		},
+		{
+			name:       "required params",
+			reportType: "test_report",
+			params:     map[string]interface{}{"branch_id": nil},
+			wantFields: []FieldError{
+				{Field: "params.branch_id", Message: "is required"},
+				{Field: "params.month", Message: "is required"},
+			},
+		},
+		{
+			name:       "unknown params are reported in name order",
+			reportType: "test_report",
+			params:     map[string]interface{}{"branch_id": float64(1), "month": "2024-05", "zone": "x", "extra": 1.0},
+			wantFields: []FieldError{
+				{Field: "params.extra", Message: "unknown parameter"},
+				{Field: "params.zone", Message: "unknown parameter"},
+			},
+		},
+		{
+			name:       "every invalid field is reported",
+			reportType: "test_report",
+			params: map[string]interface{}{
+				"branch_id": 2.5, "ratio": "high", "month": "05-2024",
+				"day": 20240531.0, "format": "xls", "draft": "yes",
+			},
+			wantFields: []FieldError{
+				{Field: "params.branch_id", Message: "must be an integer"},
+				{Field: "params.ratio", Message: "must be a number"},
+				{Field: "params.month", Message: "must match format YYYY-MM"},
+				{Field: "params.day", Message: "must be a string"},
+				{Field: "params.format", Message: "must be one of [pdf docx]"},
+				{Field: "params.draft", Message: "must be a boolean"},
+			},
+		},
+		{
+			name:       "range",
+			reportType: "test_report",
+			params:     map[string]interface{}{"branch_id": float64(101), "ratio": -0.1, "month": "2024-05"},
+			wantFields: []FieldError{
+				{Field: "params.branch_id", Message: "must be less than or equal to 100"},
+				{Field: "params.ratio", Message: "must be greater than or equal to 0"},
+			},
+		},
+		{
+			name:       "integer from string is rejected",
+			reportType: "test_report",
+			params:     map[string]interface{}{"branch_id": "3", "month": "2024-05"},
+			wantFields: []FieldError{{Field: "params.branch_id", Message: "must be an integer"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := testRegistry().Validate(tt.reportType, tt.params)
+
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("params = %#v, want %#v", got, tt.want)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Validate error = %v, want *ValidationError", err)
+			}
+			if !reflect.DeepEqual(verr.Fields, tt.wantFields) {
+				t.Errorf("fields = %+v, want %+v", verr.Fields, tt.wantFields)
+			}
+			if got != nil {
+				t.Errorf("params = %v, want nil on error", got)
+			}
+		})
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	got, err := testRegistry().ValidateJSON("test_report", json.RawMessage(`{"branch_id": 3, "month": "2024-05"}`))
+	if err != nil {
+		t.Fatalf("ValidateJSON: %v", err)
+	}
+	if want := map[string]interface{}{"branch_id": int64(3), "month": "2024-05"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("params = %#v, want %#v", got, want)
+	}
+
+	_, err = testRegistry().ValidateJSON("test_report", json.RawMessage(`[1, 2]`))
+	var verr *ValidationError
+	if !errors.As(err, &verr) || !reflect.DeepEqual(verr.Fields, []FieldError{{Field: "params", Message: "must be a JSON object"}}) {
+		t.Errorf("ValidateJSON error = %v, want params must be a JSON object", err)
+	}
+}
+
+func TestValidationErrorMessage(t *testing.T) {
+	err := &ValidationError{Fields: []FieldError{
+		{Field: "type", Message: "is required"},
+		{Field: "params.month", Message: "must match format YYYY-MM"},
+	}}
+	want := "validation failed: type: is required; params.month: must match format YYYY-MM"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestDefaultDescribesGeneratorTypes(t *testing.T) {
+	definition, ok := Default().Get(BranchPerformanceReport)
+	if !ok {
+		t.Fatalf("Default has no %s", BranchPerformanceReport)
+	}
+	var names []string
+	for _, param := range definition.Params {
+		names = append(names, param.Name)
+	}
+	if want := []string{"branch_id", "month", "format"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("params = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,59 @@
+// Package shutdown помогает воркерам завершаться корректно: после сигнала остановки они перестают
+// брать новую работу, а начатая работа доделывается в отдельном контексте, который отменяется,
+// только если не уложилась в отведенный срок.
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrAborted — причина отмены Drain.Context, если работа не завершилась до истечения срока остановки
+var ErrAborted = errors.New("работа прервана: истек срок остановки сервиса")
+
+// Drain отслеживает горутины воркера и их текущую работу
+type Drain struct {
+	wg    sync.WaitGroup
+	ctx   context.Context
+	abort context.CancelCauseFunc
+}
+
+func New() *Drain {
+	ctx, abort := context.WithCancelCause(context.Background())
+	return &Drain{ctx: ctx, abort: abort}
+}
+
+// Context — контекст начатой работы. Он не зависит от сигнала остановки и отменяется
+// с причиной ErrAborted, только если Wait не дождался завершения работы.
+func (d *Drain) Context() context.Context {
+	return d.ctx
+}
+
+// Go запускает fn в отдельной горутине; Wait дождется ее завершения.
+// Go нужно вызывать до Wait, обычно в Start воркера.
+func (d *Drain) Go(fn func()) {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		fn()
+	}()
+}
+
+// Wait ждет завершения горутин, запущенных через Go. Если ctx истекает раньше,
+// Context отменяется с причиной ErrAborted и возвращается ошибка ctx.
+func (d *Drain) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		d.abort(ErrAborted)
+		return ctx.Err()
+	}
+}
@@ -3,13 +3,21 @@ package main
 import (
 	"context"
 	"database/sql"
+	"flag"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/KostySCH/Reports_go/pkg/appconfig"
+	"github.com/KostySCH/Reports_go/pkg/health"
+	"github.com/KostySCH/Reports_go/pkg/migrations"
 	"github.com/KostySCH/Reports_go/reports_generator/internal/config"
+	"github.com/KostySCH/Reports_go/reports_generator/internal/handler"
+	"github.com/KostySCH/Reports_go/reports_generator/internal/report"
 	"github.com/KostySCH/Reports_go/reports_generator/internal/repository"
 	"github.com/KostySCH/Reports_go/reports_generator/internal/service"
 	"github.com/KostySCH/Reports_go/reports_generator/internal/worker"
@@ -18,7 +26,25 @@ import (
 
 func main() {
 	// Загружаем конфигурацию
-	cfg := config.Load()
+	configPath := appconfig.Flag(flag.CommandLine)
+	flag.Parse()
+	args := flag.Args()
+
+	cfg, source, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
+	}
+	if len(args) > 0 && args[0] == appconfig.CommandName {
+		if err := appconfig.RunCommand(args[1:], cfg, source, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if source == "" {
+		log.Println("Файл конфигурации не найден, используются значения по умолчанию и переменные окружения")
+	} else {
+		log.Printf("Конфигурация загружена из %s", source)
+	}
 
 	// Инициализируем подключение к базе данных
 	db, err := sql.Open("postgres", cfg.GetDSN())
@@ -27,6 +53,17 @@ func main() {
 	}
 	defer db.Close()
 
+	// Подкоманда migrate управляет схемой и завершает работу
+	if len(args) > 0 && args[0] == migrations.CommandName {
+		if err := migrations.Run(context.Background(), db, args[1:], os.Stdout); err != nil {
+			log.Fatalf("Ошибка миграции: %v", err)
+		}
+		return
+	}
+	if err := migrations.EnsureUpToDate(context.Background(), db); err != nil {
+		log.Fatalf("Схема базы данных не соответствует версии сервиса: %v", err)
+	}
+
 	// Инициализируем MinIO сервис
 	minioSvc, err := service.NewMinioService(
 		cfg.MinIO.Endpoint,
@@ -42,24 +79,71 @@ func main() {
 
 	// Инициализируем репозиторий и сервисы
 	repo := repository.NewReportRequestRepository(db)
-	reportSvc := service.NewReportService(db, "output", minioSvc)
+	// Каждый тип отчета регистрируется своим генератором; воркеры и предпросмотр выбирают его по типу запроса
+	reports := report.NewRegistry(
+		service.NewBranchPerformanceReport(db, service.NewDocumentService("output")),
+	)
+	reportSvc := service.NewReportService(reports, minioSvc)
+
+	// Проверки готовности и внутренний HTTP API
+	checker := health.New(5 * time.Second)
+	checker.Add("postgres", health.Ping(db))
+	checker.Add("minio", minioSvc.Ping)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", checker.Liveness)
+	mux.HandleFunc("GET /readyz", checker.Readiness)
+	previewHandler := handler.NewPreviewHandler(reportSvc, cfg.Preview.Token, cfg.Preview.Timeout, cfg.Preview.MaxConcurrent)
+	mux.HandleFunc("POST /internal/preview", previewHandler.Preview)
+	httpServer := &http.Server{
+		Addr:    cfg.Server.Addr,
+		Handler: mux,
+	}
+	go func() {
+		log.Printf("Запуск HTTP сервера на %s", cfg.Server.Addr)
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Ошибка запуска HTTP сервера: %v", err)
+		}
+	}()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	// Создаем и запускаем воркеры
+	// Создаем и запускаем воркеры. Отмена ctx останавливает захват новых запросов.
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	mainWorker := worker.NewWorker(repo, reportSvc, 10)
-	retryWorker := worker.NewRetryWorker(repo, reportSvc)
+	mainWorker := worker.NewWorker(repo, reportSvc, cfg.Worker.Concurrency, cfg.Worker.LeaseDuration)
+	retryWorker := worker.NewRetryWorker(repo, reportSvc, cfg.Worker.LeaseDuration)
+	reaper := worker.NewReaper(repo, cfg.Worker.ReaperPeriod, cfg.Worker.LeaseDuration)
 
 	mainWorker.Start(ctx)
 	retryWorker.Start(ctx)
+	go reaper.Start(ctx)
 
 	// Ждем сигнала для завершения
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
 
-	log.Println("Получен сигнал завершения, останавливаем воркеры...")
+	log.Println("Получен сигнал завершения, перестаем захватывать запросы...")
+	checker.Drain()
 	cancel()
-	time.Sleep(time.Second) // Даем время на graceful shutdown
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer shutdownCancel()
+
+	// Начатые генерации доделываются до истечения shutdown_timeout; прерванные вернет в очередь reaper
+	var wg sync.WaitGroup
+	for _, w := range []interface{ Shutdown(context.Context) error }{mainWorker, retryWorker} {
+		wg.Add(1)
+		go func(w interface{ Shutdown(context.Context) error }) {
+			defer wg.Done()
+			w.Shutdown(shutdownCtx)
+		}(w)
+	}
+	wg.Wait()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Ошибка при остановке HTTP сервера: %v", err)
+	}
+	log.Println("Генератор остановлен")
 }
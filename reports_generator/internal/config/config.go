@@ -1,113 +1,93 @@
 package config
 
 import (
-	"fmt"
-	"os"
-	"path/filepath"
+	"errors"
+	"time"
 
-	"gopkg.in/yaml.v3"
+	"github.com/KostySCH/Reports_go/pkg/appconfig"
 )
 
+// EnvPrefix — префикс переменных окружения reports_generator, например GENERATOR_MINIO_SECRET_KEY
+const EnvPrefix = "GENERATOR"
+
 type Config struct {
-	Database struct {
-		Host     string `yaml:"host"`
-		Port     string `yaml:"port"`
-		User     string `yaml:"user"`
-		Password string `yaml:"password"`
-		DBName   string `yaml:"dbname"`
-		SSLMode  string `yaml:"sslmode"`
-	} `yaml:"database"`
-	MinIO struct {
-		Endpoint   string `yaml:"endpoint"`
-		AccessKey  string `yaml:"access_key"`
-		SecretKey  string `yaml:"secret_key"`
-		PDFBucket  string `yaml:"pdf_bucket"`
-		DOCXBucket string `yaml:"docx_bucket"`
-		UseSSL     bool   `yaml:"use_ssl"`
-	} `yaml:"minio"`
+	Database appconfig.Database `yaml:"database"`
+	MinIO    appconfig.MinIO    `yaml:"minio"`
+	Worker   Worker             `yaml:"worker"`
+	Server   Server             `yaml:"server"`
+	Preview  Preview            `yaml:"preview"`
 }
 
-func Load() *Config {
-	config := &Config{}
-
-	workDir, err := os.Getwd()
-	if err != nil {
-		fmt.Printf("Error getting working directory: %v\n", err)
-		return getDefaultConfig()
-	}
-
-	configPaths := []string{
-		filepath.Join(workDir, "config.yaml"),
-		filepath.Join(workDir, "config", "config.yaml"),
-		filepath.Join(workDir, "..", "config", "config.yaml"),
-		filepath.Join(workDir, "..", "..", "config", "config.yaml"),
-	}
+type Worker struct {
+	Concurrency int `yaml:"concurrency"`
+	// LeaseDuration — срок аренды запроса воркером; продлевается во время генерации
+	LeaseDuration time.Duration `yaml:"lease_duration"`
+	// ReaperPeriod — как часто запросы с истекшей арендой возвращаются в очередь
+	ReaperPeriod time.Duration `yaml:"reaper_period"`
+}
 
-	var configData []byte
-	for _, path := range configPaths {
-		data, err := os.ReadFile(path)
-		if err == nil {
-			configData = data
-			fmt.Printf("Found config file at: %s\n", path)
-			break
-		}
-	}
+// Preview ограничивает синхронный сбор данных для POST /internal/preview
+type Preview struct {
+	// Token — общий с reports_register токен; без него внутренний API отвечает 401
+	Token         string        `yaml:"token" secret:"true"`
+	Timeout       time.Duration `yaml:"timeout"`
+	MaxConcurrent int           `yaml:"max_concurrent"`
+}
 
-	if configData == nil {
-		fmt.Printf("Config file not found in any of the expected locations\n")
-		return getDefaultConfig()
-	}
+type Server struct {
+	// Addr — адрес HTTP-сервера с /healthz, /readyz и внутренним API
+	Addr string `yaml:"addr"`
+	// ShutdownTimeout — сколько ждать завершения начатых генераций при остановке
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
 
-	// Парсим YAML
-	if err := yaml.Unmarshal(configData, config); err != nil {
-		fmt.Printf("Error parsing config file: %v\n", err)
-		return getDefaultConfig()
+// Load читает конфигурацию из path (флаг --config) или из найденного config.yaml
+// и применяет переменные окружения GENERATOR_*. Возвращает путь к прочитанному файлу.
+func Load(path string) (*Config, string, error) {
+	config := getDefaultConfig()
+	source, err := appconfig.Load(config, appconfig.Options{EnvPrefix: EnvPrefix, Path: path})
+	if err != nil {
+		return nil, "", err
 	}
-
-	return config
+	return config, source, nil
 }
 
 func getDefaultConfig() *Config {
 	return &Config{
-		Database: struct {
-			Host     string `yaml:"host"`
-			Port     string `yaml:"port"`
-			User     string `yaml:"user"`
-			Password string `yaml:"password"`
-			DBName   string `yaml:"dbname"`
-			SSLMode  string `yaml:"sslmode"`
-		}{
-			Host:     "localhost",
-			Port:     "5432",
-			User:     "postgres",
-			Password: "postgres",
-			DBName:   "reports_db",
-			SSLMode:  "disable",
+		Database: appconfig.DefaultDatabase(),
+		MinIO:    appconfig.DefaultMinIO(),
+		Worker: Worker{
+			Concurrency:   10,
+			LeaseDuration: 2 * time.Minute,
+			ReaperPeriod:  30 * time.Second,
+		},
+		Server: Server{
+			Addr:            ":8081",
+			ShutdownTimeout: 30 * time.Second,
 		},
-		MinIO: struct {
-			Endpoint   string `yaml:"endpoint"`
-			AccessKey  string `yaml:"access_key"`
-			SecretKey  string `yaml:"secret_key"`
-			PDFBucket  string `yaml:"pdf_bucket"`
-			DOCXBucket string `yaml:"docx_bucket"`
-			UseSSL     bool   `yaml:"use_ssl"`
-		}{
-			Endpoint:   "localhost:9000",
-			AccessKey:  "minioadmin",
-			SecretKey:  "minioadmin",
-			PDFBucket:  "reports-pdf",
-			DOCXBucket: "reports-docx",
-			UseSSL:     false,
+		Preview: Preview{
+			Timeout:       10 * time.Second,
+			MaxConcurrent: 4,
 		},
 	}
 }
 
+// Validate проверяет значения после применения переменных окружения
+func (c *Config) Validate() error {
+	return errors.Join(
+		c.Database.Validate(),
+		c.MinIO.Validate(),
+		appconfig.PositiveInt("worker.concurrency", c.Worker.Concurrency),
+		appconfig.PositiveDuration("worker.lease_duration", c.Worker.LeaseDuration),
+		appconfig.PositiveDuration("worker.reaper_period", c.Worker.ReaperPeriod),
+		appconfig.Required("server.addr", c.Server.Addr),
+		appconfig.PositiveDuration("server.shutdown_timeout", c.Server.ShutdownTimeout),
+		appconfig.Required("preview.token", c.Preview.Token),
+		appconfig.PositiveDuration("preview.timeout", c.Preview.Timeout),
+		appconfig.PositiveInt("preview.max_concurrent", c.Preview.MaxConcurrent),
+	)
+}
+
 func (c *Config) GetDSN() string {
-	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		c.Database.Host,
-		c.Database.Port,
-		c.Database.User,
-		c.Database.Password,
-		c.Database.DBName,
-		c.Database.SSLMode)
+	return c.Database.DSN()
 }
@@ -0,0 +1,132 @@
+// Package handler содержит внутренний HTTP API генератора. Он не публикуется наружу:
+// клиенты обращаются к reports_register, который проверяет доступ и параметры, а сам
+// reports_register подтверждает вызовы общим токеном сервисов.
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/KostySCH/Reports_go/reports_generator/internal/report"
+)
+
+const (
+	// maxPreviewBodySize ограничивает тело запроса предпросмотра
+	maxPreviewBodySize = 64 << 10
+	// ServiceTokenHeader — заголовок с общим токеном, которым reports_register подтверждает вызовы
+	ServiceTokenHeader = "X-Service-Token"
+)
+
+// DataCollector собирает данные отчета без формирования документа, например service.ReportService.
+// Ошибки неизвестного типа и некорректных параметров — report.ErrUnknownType и *report.ParamsError.
+type DataCollector interface {
+	Collect(ctx context.Context, reportType string, params json.RawMessage) (interface{}, error)
+}
+
+// PreviewRequest — тело POST /internal/preview
+type PreviewRequest struct {
+	Type   string          `json:"type"`
+	Params json.RawMessage `json:"params"`
+}
+
+// PreviewResponse — данные отчета, которые легли бы в документ
+type PreviewResponse struct {
+	Type        string      `json:"type"`
+	Data        interface{} `json:"data"`
+	GeneratedAt time.Time   `json:"generated_at"`
+}
+
+// PreviewHandler выполняет запросы сбора данных отчета синхронно. Каждый предпросмотр ограничен
+// timeout, а одновременно выполняется не больше maxConcurrent, чтобы предпросмотры не отнимали
+// подключения к базе у генерации. Запросы без общего токена сервисов отклоняются с 401.
+type PreviewHandler struct {
+	collector DataCollector
+	// tokenHash — хеш общего токена; сравнение хешей не зависит от длины присланного токена
+	tokenHash [sha256.Size]byte
+	timeout   time.Duration
+	slots     chan struct{}
+}
+
+// NewPreviewHandler создает обработчик; пустой token отклоняет все запросы
+func NewPreviewHandler(collector DataCollector, token string, timeout time.Duration, maxConcurrent int) *PreviewHandler {
+	h := &PreviewHandler{
+		collector: collector,
+		timeout:   timeout,
+		slots:     make(chan struct{}, maxConcurrent),
+	}
+	if token != "" {
+		h.tokenHash = sha256.Sum256([]byte(token))
+	}
+	return h
+}
+
+// authorized сообщает, передан ли в запросе общий токен сервисов
+func (h *PreviewHandler) authorized(r *http.Request) bool {
+	token := r.Header.Get(ServiceTokenHeader)
+	if token == "" || h.tokenHash == [sha256.Size]byte{} {
+		return false
+	}
+	got := sha256.Sum256([]byte(token))
+	return subtle.ConstantTimeCompare(got[:], h.tokenHash[:]) == 1
+}
+
+func (h *PreviewHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req PreviewRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPreviewBodySize)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case h.slots <- struct{}{}:
+		defer func() { <-h.slots }()
+	default:
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "Too many previews in progress", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.collector.Collect(ctx, req.Type, req.Params)
+	var perr *report.ParamsError
+	switch {
+	case errors.Is(err, report.ErrUnknownType):
+		http.Error(w, "Unsupported report type", http.StatusUnprocessableEntity)
+		return
+	case errors.As(err, &perr):
+		http.Error(w, perr.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		// lib/pq сообщает об отмене запроса своей ошибкой, поэтому причину берем из контекста
+		http.Error(w, "Preview timed out", http.StatusGatewayTimeout)
+		return
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "No data for report parameters", http.StatusNotFound)
+		return
+	case err != nil:
+		log.Printf("Ошибка предпросмотра отчета %s: %v", req.Type, err)
+		http.Error(w, "Failed to collect report data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PreviewResponse{
+		Type:        req.Type,
+		Data:        data,
+		GeneratedAt: time.Now().UTC(),
+	})
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type stubCollector struct {
+	calls int
+}
+
+func (c *stubCollector) Collect(ctx context.Context, reportType string, params json.RawMessage) (interface{}, error) {
+	c.calls++
+	return map[string]int{"clients": 3}, nil
+}
+
+func TestPreviewRequiresServiceToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		sent       string
+		wantStatus int
+	}{
+		{name: "valid token", configured: "secret-token", sent: "secret-token", wantStatus: http.StatusOK},
+		{name: "missing token", configured: "secret-token", wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", configured: "secret-token", sent: "secret-tokeN", wantStatus: http.StatusUnauthorized},
+		{name: "token prefix", configured: "secret-token", sent: "secret", wantStatus: http.StatusUnauthorized},
+		{name: "token not configured", sent: "anything", wantStatus: http.StatusUnauthorized},
+		{name: "token not configured and not sent", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &stubCollector{}
+			h := NewPreviewHandler(collector, tt.configured, time.Second, 1)
+
+			r := httptest.NewRequest(http.MethodPost, "/internal/preview",
+				strings.NewReader(`{"type":"branch_performance_report","params":{}}`))
+			if tt.sent != "" {
+				r.Header.Set(ServiceTokenHeader, tt.sent)
+			}
+			w := httptest.NewRecorder()
+			h.Preview(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			wantCalls := 0
+			if tt.wantStatus == http.StatusOK {
+				wantCalls = 1
+			}
+			if collector.calls != wantCalls {
+				t.Errorf("collector called %d times, want %d", collector.calls, wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Package memtest содержит замены внешних зависимостей reports_generator для тестов.
+// Пакет импортируется только из _test.go.
+package memtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/KostySCH/Reports_go/reports_generator/internal/report"
+)
+
+// Report — отчет, «сформированный» ReportService
+type Report struct {
+	Type string
+	// Params — параметры, разобранные генератором типа, например *models.BranchPerformanceParams
+	Params interface{}
+}
+
+// ReportService — замена service.ReportService без базы данных и MinIO. Тип и параметры
+// проверяются генераторами из реестра, но вместо файла запоминаются параметры отчета
+// и возвращается путь в формате minio://bucket/path.
+type ReportService struct {
+	reports *report.Registry
+
+	mu        sync.Mutex
+	generated map[string]Report
+	err       error
+}
+
+func NewReportService(reports *report.Registry) *ReportService {
+	return &ReportService{reports: reports, generated: make(map[string]Report)}
+}
+
+// FailWith заставляет следующие генерации завершаться ошибкой err; nil возвращает успешную генерацию
+func (s *ReportService) FailWith(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+func (s *ReportService) Generate(ctx context.Context, reportType string, params json.RawMessage) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", fmt.Errorf("генерация прервана: %w", err)
+	}
+
+	_, decoded, err := s.reports.Decode(reportType, params)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return "", s.err
+	}
+
+	// Бакет выбирается по формату так же, как MinioService выбирает его по расширению файла
+	var options struct {
+		Format string `json:"format"`
+	}
+	json.Unmarshal(params, &options)
+	bucket := "reports-pdf"
+	if options.Format == "docx" {
+		bucket = "reports-docx"
+	}
+	path := fmt.Sprintf("minio://%s/reports/%s/%s_%d.%s",
+		bucket, time.Now().Format("2006/01/02"), reportType, len(s.generated)+1, options.Format)
+	s.generated[path] = Report{Type: reportType, Params: decoded}
+	return path, nil
+}
+
+// Reports возвращает сформированные отчеты по путям
+func (s *ReportService) Reports() map[string]Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reports := make(map[string]Report, len(s.generated))
+	for path, generated := range s.generated {
+		reports[path] = generated
+	}
+	return reports
+}
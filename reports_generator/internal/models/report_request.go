@@ -15,6 +15,7 @@ const (
 	StatusInProgress = "IN_PROGRESS"
 	StatusCompleted  = "COMPLETED"
 	StatusFailed     = "FAILED"
+	StatusCancelled  = "CANCELLED"
 )
 
 type JSON json.RawMessage
@@ -71,4 +72,5 @@ type ReportRequest struct {
 	Error      sql.NullString `json:"error"`
 	RetryCount int            `json:"retry_count"`
 	ReportPath sql.NullString `json:"report_path"`
+	Priority   int            `json:"priority"`
 }
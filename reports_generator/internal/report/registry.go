@@ -0,0 +1,96 @@
+// Package report описывает типы отчетов генератора. Новый тип добавляется реализацией
+// ReportGenerator и регистрацией в Registry: воркеры и предпросмотр выбирают реализацию
+// по типу запроса.
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownType возвращается для типа отчета, для которого не зарегистрирован генератор
+var ErrUnknownType = errors.New("неподдерживаемый тип отчета")
+
+// ParamsError — параметры запроса не прошли проверку генератора
+type ParamsError struct {
+	Reason string
+}
+
+func (e *ParamsError) Error() string {
+	return e.Reason
+}
+
+// ReportGenerator формирует отчеты одного типа. Параметры и данные непрозрачны для
+// вызывающего кода: их тип знает только сама реализация.
+type ReportGenerator interface {
+	// Type — имя типа отчета, как в report_requests.type
+	Type() string
+	// DecodeParams разбирает и проверяет параметры запроса; ошибка проверки — *ParamsError
+	DecodeParams(raw json.RawMessage) (interface{}, error)
+	// Collect собирает данные отчета; результат сериализуется в JSON для предпросмотра
+	Collect(ctx context.Context, params interface{}) (interface{}, error)
+	// Render формирует документ из собранных данных и возвращает путь к локальному файлу
+	Render(data interface{}, params interface{}) (string, error)
+}
+
+// Registry хранит генераторы по типам отчетов
+type Registry struct {
+	mu         sync.RWMutex
+	generators map[string]ReportGenerator
+}
+
+func NewRegistry(generators ...ReportGenerator) *Registry {
+	r := &Registry{generators: make(map[string]ReportGenerator)}
+	for _, generator := range generators {
+		r.Register(generator)
+	}
+	return r
+}
+
+// Register добавляет или заменяет генератор типа generator.Type()
+func (r *Registry) Register(generator ReportGenerator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.generators[generator.Type()] = generator
+}
+
+// Get возвращает генератор типа reportType или ошибку, содержащую ErrUnknownType
+func (r *Registry) Get(reportType string) (ReportGenerator, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	generator, ok := r.generators[reportType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownType, reportType)
+	}
+	return generator, nil
+}
+
+// Types возвращает зарегистрированные типы отчетов, упорядоченные по имени
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	types := make([]string, 0, len(r.generators))
+	for reportType := range r.generators {
+		types = append(types, reportType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Decode находит генератор типа reportType и разбирает параметры запроса
+func (r *Registry) Decode(reportType string, raw json.RawMessage) (ReportGenerator, interface{}, error) {
+	generator, err := r.Get(reportType)
+	if err != nil {
+		return nil, nil, err
+	}
+	params, err := generator.DecodeParams(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	return generator, params, nil
+}
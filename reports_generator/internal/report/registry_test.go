@@ -0,0 +1,100 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type fakeGenerator struct {
+	name string
+}
+
+func (g *fakeGenerator) Type() string { return g.name }
+
+func (g *fakeGenerator) DecodeParams(raw json.RawMessage) (interface{}, error) {
+	var params map[string]string
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, &ParamsError{Reason: "params must be an object of strings"}
+	}
+	return params, nil
+}
+
+func (g *fakeGenerator) Collect(ctx context.Context, params interface{}) (interface{}, error) {
+	return params, nil
+}
+
+func (g *fakeGenerator) Render(data interface{}, params interface{}) (string, error) {
+	return "/tmp/" + g.name, nil
+}
+
+func TestRegistryGet(t *testing.T) {
+	sales := &fakeGenerator{name: "sales"}
+	registry := NewRegistry(&fakeGenerator{name: "stock"}, sales)
+
+	generator, err := registry.Get("sales")
+	if err != nil {
+		t.Fatalf("Get(sales): %v", err)
+	}
+	if generator != sales {
+		t.Errorf("Get(sales) = %v, want the registered generator", generator)
+	}
+
+	_, err = registry.Get("payroll")
+	if !errors.Is(err, ErrUnknownType) {
+		t.Fatalf("Get(payroll) error = %v, want ErrUnknownType", err)
+	}
+	if want := ErrUnknownType.Error() + ": payroll"; err.Error() != want {
+		t.Errorf("Get(payroll) error = %q, want %q", err, want)
+	}
+}
+
+func TestRegistryRegisterReplaces(t *testing.T) {
+	registry := NewRegistry(&fakeGenerator{name: "sales"})
+	replacement := &fakeGenerator{name: "sales"}
+	registry.Register(replacement)
+
+	if generator, _ := registry.Get("sales"); generator != replacement {
+		t.Errorf("Get(sales) = %v, want the replacement", generator)
+	}
+	if types := registry.Types(); !reflect.DeepEqual(types, []string{"sales"}) {
+		t.Errorf("Types() = %v, want [sales]", types)
+	}
+}
+
+func TestRegistryTypes(t *testing.T) {
+	if types := NewRegistry().Types(); len(types) != 0 {
+		t.Errorf("empty registry Types() = %v", types)
+	}
+
+	registry := NewRegistry(&fakeGenerator{name: "stock"}, &fakeGenerator{name: "audit"}, &fakeGenerator{name: "sales"})
+	want := []string{"audit", "sales", "stock"}
+	if types := registry.Types(); !reflect.DeepEqual(types, want) {
+		t.Errorf("Types() = %v, want %v", types, want)
+	}
+}
+
+func TestRegistryDecode(t *testing.T) {
+	registry := NewRegistry(&fakeGenerator{name: "sales"})
+
+	generator, params, err := registry.Decode("sales", json.RawMessage(`{"month":"2024-05"}`))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if generator.Type() != "sales" || !reflect.DeepEqual(params, map[string]string{"month": "2024-05"}) {
+		t.Errorf("Decode = %s, %v", generator.Type(), params)
+	}
+
+	_, _, err = registry.Decode("sales", json.RawMessage(`{"month":5}`))
+	var perr *ParamsError
+	if !errors.As(err, &perr) || perr.Error() != "params must be an object of strings" {
+		t.Errorf("Decode with bad params error = %v, want *ParamsError", err)
+	}
+
+	_, _, err = registry.Decode("payroll", json.RawMessage(`{}`))
+	if !errors.Is(err, ErrUnknownType) || errors.As(err, &perr) {
+		t.Errorf("Decode of unknown type error = %v, want only ErrUnknownType", err)
+	}
+}
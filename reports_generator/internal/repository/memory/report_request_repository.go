@@ -0,0 +1,261 @@
+// Package memory реализует репозиторий генератора поверх memstore.Store — для тестов без Postgres.
+// Захват, аренда и возврат зависших запросов повторяют repository.ReportRequestRepository.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/KostySCH/Reports_go/pkg/audit"
+	"github.com/KostySCH/Reports_go/pkg/memstore"
+	"github.com/KostySCH/Reports_go/reports_generator/internal/models"
+	"github.com/KostySCH/Reports_go/reports_generator/internal/repository"
+	"github.com/google/uuid"
+)
+
+type ReportRequestRepository struct {
+	store *memstore.Store
+}
+
+func NewReportRequestRepository(store *memstore.Store) *ReportRequestRepository {
+	return &ReportRequestRepository{store: store}
+}
+
+func toModel(r *memstore.Request) *models.ReportRequest {
+	req := &models.ReportRequest{
+		ID:         r.ID,
+		UserID:     int64(r.UserID),
+		Type:       r.Type,
+		Params:     append([]byte(nil), r.Params...),
+		Status:     r.Status,
+		CreatedAt:  r.CreatedAt,
+		UpdatedAt:  r.UpdatedAt,
+		RetryCount: r.RetryCount,
+		Priority:   r.Priority,
+	}
+	if r.Error != nil {
+		req.Error.String, req.Error.Valid = *r.Error, true
+	}
+	if r.ReportPath != nil {
+		req.ReportPath.String, req.ReportPath.Valid = *r.ReportPath, true
+	}
+	return req
+}
+
+// GetPendingRequests захватывает до limit запросов в статусе PENDING и переводит их в IN_PROGRESS
+// с арендой на lease
+func (r *ReportRequestRepository) GetPendingRequests(ctx context.Context, limit int, actor string, lease time.Duration) ([]*models.ReportRequest, error) {
+	match := func(row *memstore.Request) bool {
+		return row.Status == models.StatusPending
+	}
+	order := func(a, b *memstore.Request) bool {
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return r.claim(match, order, limit, actor, lease, false)
+}
+
+// ClaimFailedRequests захватывает до limit неудачных запросов, у которых осталось меньше maxRetries попыток,
+// увеличивает их счетчик попыток и переводит в IN_PROGRESS с арендой на lease
+func (r *ReportRequestRepository) ClaimFailedRequests(ctx context.Context, limit, maxRetries int, actor string, lease time.Duration) ([]*models.ReportRequest, error) {
+	match := func(row *memstore.Request) bool {
+		return row.Status == models.StatusFailed && row.RetryCount < maxRetries
+	}
+	order := func(a, b *memstore.Request) bool {
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return a.UpdatedAt.Before(b.UpdatedAt)
+	}
+	return r.claim(match, order, limit, actor, lease, true)
+}
+
+func (r *ReportRequestRepository) claim(match func(*memstore.Request) bool, order func(a, b *memstore.Request) bool,
+	limit int, actor string, lease time.Duration, retry bool) ([]*models.ReportRequest, error) {
+	var requests []*models.ReportRequest
+	err := r.store.Tx(func(tx *memstore.Tx) error {
+		rows := tx.Select(match, order)
+		for i := 0; i < len(rows) && i < limit; i++ {
+			row := rows[i]
+			now := tx.Now()
+			expiresAt := now.Add(lease)
+
+			event := audit.Event{
+				RequestID:  row.ID,
+				Type:       audit.EventStatusChanged,
+				FromStatus: row.Status,
+				ToStatus:   models.StatusInProgress,
+				Actor:      actor,
+				Source:     audit.SourceGenerator,
+			}
+			if retry {
+				row.RetryCount++
+				event.Type = audit.EventRetryAttempt
+				event.Error = row.Error
+			}
+			retryCount := row.RetryCount
+			event.RetryCount = &retryCount
+
+			row.Status = models.StatusInProgress
+			row.LeaseOwner = &actor
+			row.LeaseExpiresAt = &expiresAt
+			row.UpdatedAt = now
+			tx.Record(event)
+
+			requests = append(requests, toModel(row))
+		}
+		return nil
+	})
+	return requests, err
+}
+
+// RenewLease продлевает аренду запроса на lease так же, как repository.ReportRequestRepository.RenewLease
+func (r *ReportRequestRepository) RenewLease(ctx context.Context, id uuid.UUID, actor string, lease time.Duration) (repository.LeaseState, error) {
+	state := repository.LeaseLost
+	err := r.store.Tx(func(tx *memstore.Tx) error {
+		row := tx.Get(id)
+		switch {
+		case row == nil:
+		case row.Status == models.StatusCancelled:
+			state = repository.LeaseCancelled
+		case row.Status == models.StatusInProgress && row.LeaseOwner != nil && *row.LeaseOwner == actor:
+			expiresAt := tx.Now().Add(lease)
+			row.LeaseExpiresAt = &expiresAt
+			state = repository.LeaseRenewed
+		}
+		return nil
+	})
+	return state, err
+}
+
+// UpdateRequestStatus сохраняет результат обработки запроса, снимает аренду и записывает переход в историю
+func (r *ReportRequestRepository) UpdateRequestStatus(ctx context.Context, id uuid.UUID, status string, errorMsg *string, reportPath *string, actor string) error {
+	return r.store.Tx(func(tx *memstore.Tx) error {
+		row := tx.Get(id)
+		if row == nil {
+			return fmt.Errorf("запрос не найден: %s", id)
+		}
+		if row.Status != models.StatusInProgress {
+			return nil
+		}
+		if row.LeaseOwner == nil || *row.LeaseOwner != actor {
+			return repository.ErrLeaseLost
+		}
+
+		previous := row.Status
+		row.Status = status
+		row.Error = errorMsg
+		row.ReportPath = reportPath
+		row.UpdatedAt = tx.Now()
+		row.CompletedAt = nil
+		if status == models.StatusCompleted {
+			completed := row.UpdatedAt
+			row.CompletedAt = &completed
+		}
+		row.NotificationSent = false
+		row.LeaseOwner = nil
+		row.LeaseExpiresAt = nil
+
+		event := audit.Event{
+			RequestID:  id,
+			Type:       audit.EventStatusChanged,
+			FromStatus: previous,
+			ToStatus:   status,
+			Error:      errorMsg,
+			Actor:      actor,
+			Source:     audit.SourceGenerator,
+		}
+		if reportPath != nil {
+			event.Details = map[string]interface{}{"report_path": *reportPath}
+		}
+		tx.Record(event)
+		return nil
+	})
+}
+
+// ReapExpiredLeases возвращает в PENDING до limit запросов IN_PROGRESS с истекшей арендой;
+// запрос, исчерпавший maxRetries попыток, переводится в FAILED
+func (r *ReportRequestRepository) ReapExpiredLeases(ctx context.Context, limit, maxRetries int, lease time.Duration, actor string) (*repository.ReapResult, error) {
+	result := &repository.ReapResult{}
+	err := r.store.Tx(func(tx *memstore.Tx) error {
+		now := tx.Now()
+		rows := tx.Select(func(row *memstore.Request) bool {
+			if row.Status != models.StatusInProgress {
+				return false
+			}
+			if row.LeaseExpiresAt == nil {
+				return row.UpdatedAt.Before(now.Add(-lease))
+			}
+			return row.LeaseExpiresAt.Before(now)
+		}, func(a, b *memstore.Request) bool {
+			if a.LeaseExpiresAt == nil || b.LeaseExpiresAt == nil {
+				return a.LeaseExpiresAt == nil && b.LeaseExpiresAt != nil
+			}
+			return a.LeaseExpiresAt.Before(*b.LeaseExpiresAt)
+		})
+
+		for i := 0; i < len(rows) && i < limit; i++ {
+			row := rows[i]
+			details := map[string]interface{}{"reason": "lease_expired"}
+			owner := ""
+			if row.LeaseOwner != nil {
+				owner = *row.LeaseOwner
+				details["lease_owner"] = owner
+			}
+			if row.LeaseExpiresAt != nil {
+				details["lease_expires_at"] = *row.LeaseExpiresAt
+			}
+
+			row.RetryCount++
+			row.Status = models.StatusPending
+			var errorMsg *string
+			if row.RetryCount >= maxRetries {
+				row.Status = models.StatusFailed
+				message := fmt.Sprintf("аренда воркера %s истекла, попытки исчерпаны (%d из %d)", owner, row.RetryCount, maxRetries)
+				errorMsg = &message
+				row.Error = errorMsg
+			}
+			row.UpdatedAt = now
+			row.NotificationSent = false
+			row.LeaseOwner = nil
+			row.LeaseExpiresAt = nil
+
+			retryCount := row.RetryCount
+			tx.Record(audit.Event{
+				RequestID:  row.ID,
+				Type:       audit.EventStatusChanged,
+				FromStatus: models.StatusInProgress,
+				ToStatus:   row.Status,
+				RetryCount: &retryCount,
+				Error:      errorMsg,
+				Actor:      actor,
+				Source:     audit.SourceGenerator,
+				Details:    details,
+			})
+
+			if row.Status == models.StatusPending {
+				result.Requeued = append(result.Requeued, row.ID)
+			} else {
+				result.Failed = append(result.Failed, row.ID)
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (r *ReportRequestRepository) GetRequestStatus(ctx context.Context, id uuid.UUID) (string, error) {
+	var status string
+	err := r.store.Tx(func(tx *memstore.Tx) error {
+		row := tx.Get(id)
+		if row == nil {
+			return fmt.Errorf("запрос не найден: %s", id)
+		}
+		status = row.Status
+		return nil
+	})
+	return status, err
+}
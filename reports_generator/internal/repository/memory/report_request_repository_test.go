@@ -0,0 +1,328 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KostySCH/Reports_go/pkg/audit"
+	"github.com/KostySCH/Reports_go/pkg/memstore"
+	"github.com/KostySCH/Reports_go/reports_generator/internal/models"
+	"github.com/KostySCH/Reports_go/reports_generator/internal/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	workerA = "host-a:1/generator-worker-1"
+	workerB = "host-b:1/generator-worker-1"
+	lease   = time.Minute
+)
+
+var start = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// clock — подменяемые часы хранилища
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newRepo() (*ReportRequestRepository, *memstore.Store, *clock) {
+	c := &clock{now: start}
+	store := memstore.New()
+	store.SetClock(c.Now)
+	return NewReportRequestRepository(store), store, c
+}
+
+// insert добавляет запрос, созданный age назад
+func insert(t *testing.T, store *memstore.Store, status string, priority int, age time.Duration, retryCount int) uuid.UUID {
+	t.Helper()
+	id := uuid.New()
+	store.Tx(func(tx *memstore.Tx) error {
+		tx.Insert(&memstore.Request{
+			ID:         id,
+			UserID:     1,
+			Type:       "branch_performance_report",
+			Params:     []byte(`{}`),
+			Status:     status,
+			Priority:   priority,
+			RetryCount: retryCount,
+			CreatedAt:  start.Add(-age),
+			UpdatedAt:  start.Add(-age),
+		})
+		return nil
+	})
+	return id
+}
+
+func row(t *testing.T, store *memstore.Store, id uuid.UUID) *memstore.Request {
+	t.Helper()
+	var r *memstore.Request
+	store.Tx(func(tx *memstore.Tx) error {
+		r = tx.Get(id).Clone()
+		return nil
+	})
+	return r
+}
+
+func ids(requests []*models.ReportRequest) []uuid.UUID {
+	result := make([]uuid.UUID, 0, len(requests))
+	for _, r := range requests {
+		result = append(result, r.ID)
+	}
+	return result
+}
+
+func equalIDs(a, b []uuid.UUID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGetPendingRequestsClaimsByPriorityThenAge(t *testing.T) {
+	ctx := context.Background()
+	repo, store, _ := newRepo()
+
+	oldLow := insert(t, store, models.StatusPending, 0, 3*time.Minute, 0)
+	newHigh := insert(t, store, models.StatusPending, 5, time.Minute, 0)
+	newLow := insert(t, store, models.StatusPending, 0, time.Minute, 0)
+	insert(t, store, models.StatusCompleted, 9, time.Hour, 0)
+
+	claimed, err := repo.GetPendingRequests(ctx, 2, workerA, lease)
+	if err != nil {
+		t.Fatalf("GetPendingRequests: %v", err)
+	}
+	if want := []uuid.UUID{newHigh, oldLow}; !equalIDs(ids(claimed), want) {
+		t.Fatalf("claimed %v, want %v", ids(claimed), want)
+	}
+	for _, id := range ids(claimed) {
+		r := row(t, store, id)
+		if r.Status != models.StatusInProgress || r.LeaseOwner == nil || *r.LeaseOwner != workerA {
+			t.Errorf("claimed request %s: status %s, owner %v", id, r.Status, r.LeaseOwner)
+		}
+		if r.LeaseExpiresAt == nil || !r.LeaseExpiresAt.Equal(start.Add(lease)) {
+			t.Errorf("claimed request %s: lease expires at %v, want %v", id, r.LeaseExpiresAt, start.Add(lease))
+		}
+	}
+
+	// Захваченные запросы не достаются другому воркеру
+	claimed, err = repo.GetPendingRequests(ctx, 10, workerB, lease)
+	if err != nil {
+		t.Fatalf("GetPendingRequests: %v", err)
+	}
+	if want := []uuid.UUID{newLow}; !equalIDs(ids(claimed), want) {
+		t.Fatalf("second claim %v, want %v", ids(claimed), want)
+	}
+
+	claimed, err = repo.GetPendingRequests(ctx, 10, workerA, lease)
+	if err != nil || len(claimed) != 0 {
+		t.Fatalf("claim from empty queue: %v, %v", ids(claimed), err)
+	}
+}
+
+func TestClaimFailedRequestsRespectsMaxRetries(t *testing.T) {
+	ctx := context.Background()
+	repo, store, _ := newRepo()
+
+	retryable := insert(t, store, models.StatusFailed, 0, time.Minute, 1)
+	insert(t, store, models.StatusFailed, 0, time.Minute, 3)
+	insert(t, store, models.StatusPending, 0, time.Minute, 0)
+
+	claimed, err := repo.ClaimFailedRequests(ctx, 10, 3, workerA, lease)
+	if err != nil {
+		t.Fatalf("ClaimFailedRequests: %v", err)
+	}
+	if want := []uuid.UUID{retryable}; !equalIDs(ids(claimed), want) {
+		t.Fatalf("claimed %v, want %v", ids(claimed), want)
+	}
+	if claimed[0].RetryCount != 2 {
+		t.Errorf("retry count = %d, want 2", claimed[0].RetryCount)
+	}
+
+	var events []memstore.Event
+	store.Tx(func(tx *memstore.Tx) error {
+		events = tx.Events(retryable)
+		return nil
+	})
+	if len(events) != 1 || events[0].Type != audit.EventRetryAttempt || events[0].RetryCount == nil || *events[0].RetryCount != 2 {
+		t.Errorf("events = %+v, want one RETRY_ATTEMPT with retry count 2", events)
+	}
+}
+
+func TestRenewLease(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		setup func(store *memstore.Store, id uuid.UUID)
+		actor string
+		// missing продлевает аренду несуществующего запроса
+		missing bool
+		want    repository.LeaseState
+	}{
+		{name: "owner", actor: workerA, want: repository.LeaseRenewed},
+		{name: "other worker", actor: workerB, want: repository.LeaseLost},
+		{
+			name:  "cancelled",
+			actor: workerA,
+			setup: func(store *memstore.Store, id uuid.UUID) {
+				store.Tx(func(tx *memstore.Tx) error {
+					tx.Get(id).Status = models.StatusCancelled
+					return nil
+				})
+			},
+			want: repository.LeaseCancelled,
+		},
+		{
+			name:  "completed",
+			actor: workerA,
+			setup: func(store *memstore.Store, id uuid.UUID) {
+				store.Tx(func(tx *memstore.Tx) error {
+					tx.Get(id).Status = models.StatusCompleted
+					return nil
+				})
+			},
+			want: repository.LeaseLost,
+		},
+		{name: "missing request", actor: workerA, missing: true, want: repository.LeaseLost},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, store, c := newRepo()
+			id := insert(t, store, models.StatusPending, 0, time.Minute, 0)
+			if _, err := repo.GetPendingRequests(ctx, 1, workerA, lease); err != nil {
+				t.Fatalf("GetPendingRequests: %v", err)
+			}
+			if tt.setup != nil {
+				tt.setup(store, id)
+			}
+			target := id
+			if tt.missing {
+				target = uuid.New()
+			}
+
+			c.Advance(30 * time.Second)
+			state, err := repo.RenewLease(ctx, target, tt.actor, lease)
+			if err != nil {
+				t.Fatalf("RenewLease: %v", err)
+			}
+			if state != tt.want {
+				t.Fatalf("RenewLease = %v, want %v", state, tt.want)
+			}
+
+			expiresAt := row(t, store, id).LeaseExpiresAt
+			wantExpiry := start.Add(lease)
+			if tt.want == repository.LeaseRenewed {
+				wantExpiry = c.Now().Add(lease)
+			}
+			if expiresAt == nil || !expiresAt.Equal(wantExpiry) {
+				t.Errorf("lease expires at %v, want %v", expiresAt, wantExpiry)
+			}
+		})
+	}
+}
+
+func TestUpdateRequestStatusRequiresLease(t *testing.T) {
+	ctx := context.Background()
+	repo, store, _ := newRepo()
+	id := insert(t, store, models.StatusPending, 0, time.Minute, 0)
+	if _, err := repo.GetPendingRequests(ctx, 1, workerA, lease); err != nil {
+		t.Fatalf("GetPendingRequests: %v", err)
+	}
+
+	path := "minio://reports-pdf/report.pdf"
+	if err := repo.UpdateRequestStatus(ctx, id, models.StatusCompleted, nil, &path, workerB); !errors.Is(err, repository.ErrLeaseLost) {
+		t.Fatalf("update by other worker: error = %v, want ErrLeaseLost", err)
+	}
+	if r := row(t, store, id); r.Status != models.StatusInProgress {
+		t.Fatalf("status after rejected update = %s, want IN_PROGRESS", r.Status)
+	}
+
+	if err := repo.UpdateRequestStatus(ctx, id, models.StatusCompleted, nil, &path, workerA); err != nil {
+		t.Fatalf("update by owner: %v", err)
+	}
+	r := row(t, store, id)
+	if r.Status != models.StatusCompleted || r.ReportPath == nil || *r.ReportPath != path {
+		t.Errorf("after update: status %s, path %v", r.Status, r.ReportPath)
+	}
+	if r.LeaseOwner != nil || r.LeaseExpiresAt != nil {
+		t.Errorf("lease is not released: owner %v, expires %v", r.LeaseOwner, r.LeaseExpiresAt)
+	}
+
+	// Повторное обновление завершенного запроса ничего не меняет
+	failure := "late failure"
+	if err := repo.UpdateRequestStatus(ctx, id, models.StatusFailed, &failure, nil, workerA); err != nil {
+		t.Fatalf("update of completed request: %v", err)
+	}
+	if r := row(t, store, id); r.Status != models.StatusCompleted {
+		t.Errorf("completed request changed to %s", r.Status)
+	}
+}
+
+func TestReapExpiredLeases(t *testing.T) {
+	ctx := context.Background()
+	repo, store, c := newRepo()
+	id := insert(t, store, models.StatusPending, 0, time.Minute, 0)
+	if _, err := repo.GetPendingRequests(ctx, 1, workerA, lease); err != nil {
+		t.Fatalf("GetPendingRequests: %v", err)
+	}
+	const maxRetries = 2
+
+	// Пока аренда действует, запрос не возвращается в очередь
+	c.Advance(lease - time.Second)
+	result, err := repo.ReapExpiredLeases(ctx, 10, maxRetries, lease, "reaper")
+	if err != nil || len(result.Requeued)+len(result.Failed) != 0 {
+		t.Fatalf("reap before expiry: %+v, %v", result, err)
+	}
+
+	c.Advance(2 * time.Second)
+	result, err = repo.ReapExpiredLeases(ctx, 10, maxRetries, lease, "reaper")
+	if err != nil {
+		t.Fatalf("ReapExpiredLeases: %v", err)
+	}
+	if !equalIDs(result.Requeued, []uuid.UUID{id}) || len(result.Failed) != 0 {
+		t.Fatalf("reap after expiry: %+v, want %s requeued", result, id)
+	}
+	r := row(t, store, id)
+	if r.Status != models.StatusPending || r.RetryCount != 1 || r.LeaseOwner != nil || r.LeaseExpiresAt != nil {
+		t.Fatalf("requeued request: status %s, retries %d, owner %v, expires %v", r.Status, r.RetryCount, r.LeaseOwner, r.LeaseExpiresAt)
+	}
+
+	// Другой воркер перехватывает запрос, и прежний владелец уже не может записать результат
+	if _, err := repo.GetPendingRequests(ctx, 1, workerB, lease); err != nil {
+		t.Fatalf("GetPendingRequests: %v", err)
+	}
+	path := "minio://reports-pdf/stale.pdf"
+	if err := repo.UpdateRequestStatus(ctx, id, models.StatusCompleted, nil, &path, workerA); !errors.Is(err, repository.ErrLeaseLost) {
+		t.Fatalf("update by previous owner: error = %v, want ErrLeaseLost", err)
+	}
+	if state, err := repo.RenewLease(ctx, id, workerA, lease); err != nil || state != repository.LeaseLost {
+		t.Fatalf("renew by previous owner: %v, %v, want LeaseLost", state, err)
+	}
+
+	// Вторая истекшая аренда исчерпывает попытки
+	c.Advance(lease + time.Second)
+	result, err = repo.ReapExpiredLeases(ctx, 10, maxRetries, lease, "reaper")
+	if err != nil {
+		t.Fatalf("ReapExpiredLeases: %v", err)
+	}
+	if !equalIDs(result.Failed, []uuid.UUID{id}) || len(result.Requeued) != 0 {
+		t.Fatalf("reap with exhausted retries: %+v, want %s failed", result, id)
+	}
+	if r := row(t, store, id); r.Status != models.StatusFailed || r.Error == nil {
+		t.Errorf("failed request: status %s, error %v", r.Status, r.Error)
+	}
+}
@@ -3,14 +3,19 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"strings"
 	"time"
 
+	"github.com/KostySCH/Reports_go/pkg/audit"
 	"github.com/KostySCH/Reports_go/reports_generator/internal/models"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+// ErrLeaseLost возвращается, если аренда запроса истекла и запрос уже передан другому воркеру
+var ErrLeaseLost = errors.New("аренда запроса потеряна")
+
 type ReportRequestRepository struct {
 	db *sql.DB
 }
@@ -23,22 +28,47 @@ func (r *ReportRequestRepository) DB() *sql.DB {
 	return r.db
 }
 
-func (r *ReportRequestRepository) GetPendingRequests(ctx context.Context, limit int) ([]*models.ReportRequest, error) {
-	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
-	if err != nil {
-		return nil, err
-	}
-	defer tx.Rollback()
+// reportRequestColumns перечисляет колонки, читаемые claim
+const reportRequestColumns = `id, user_id, type, params, status, created_at, updated_at, error, retry_count, report_path, priority`
 
+// GetPendingRequests захватывает до limit запросов в статусе PENDING и переводит их в IN_PROGRESS
+// с арендой на lease. actor — идентификатор воркера, он же владелец аренды.
+func (r *ReportRequestRepository) GetPendingRequests(ctx context.Context, limit int, actor string, lease time.Duration) ([]*models.ReportRequest, error) {
 	query := `
-		SELECT id, user_id, type, params, status, created_at, updated_at, error, retry_count, report_path
+		SELECT ` + reportRequestColumns + `
 		FROM reporting.report_requests
 		WHERE status = $1
-		ORDER BY created_at ASC
+		ORDER BY priority DESC, created_at ASC
 		LIMIT $2
 		FOR UPDATE SKIP LOCKED
 	`
-	rows, err := tx.QueryContext(ctx, query, models.StatusPending, limit)
+	return r.claim(ctx, query, []interface{}{models.StatusPending, limit}, actor, lease, false)
+}
+
+// ClaimFailedRequests захватывает до limit неудачных запросов, у которых осталось меньше maxRetries попыток,
+// увеличивает их счетчик попыток и переводит в IN_PROGRESS с арендой на lease
+func (r *ReportRequestRepository) ClaimFailedRequests(ctx context.Context, limit, maxRetries int, actor string, lease time.Duration) ([]*models.ReportRequest, error) {
+	query := `
+		SELECT ` + reportRequestColumns + `
+		FROM reporting.report_requests
+		WHERE status = $1 AND retry_count < $2
+		ORDER BY priority DESC, updated_at ASC
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	`
+	return r.claim(ctx, query, []interface{}{models.StatusFailed, maxRetries, limit}, actor, lease, true)
+}
+
+// claim блокирует запросы, выбранные query, переводит их в IN_PROGRESS под аренду actor
+// и записывает переход в историю. retry означает повторную попытку: счетчик попыток увеличивается.
+func (r *ReportRequestRepository) claim(ctx context.Context, query string, args []interface{}, actor string, lease time.Duration, retry bool) ([]*models.ReportRequest, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -58,6 +88,7 @@ func (r *ReportRequestRepository) GetPendingRequests(ctx context.Context, limit
 			&req.Error,
 			&req.RetryCount,
 			&req.ReportPath,
+			&req.Priority,
 		)
 		if err != nil {
 			return nil, err
@@ -68,33 +99,52 @@ func (r *ReportRequestRepository) GetPendingRequests(ctx context.Context, limit
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
+	rows.Close()
 
-	if len(requests) > 0 {
-		ids := make([]uuid.UUID, len(requests))
-		for i, req := range requests {
-			ids[i] = req.ID
-		}
-
-		placeholders := make([]string, len(ids))
-		args := make([]interface{}, len(ids)+1)
-		for i := range ids {
-			placeholders[i] = fmt.Sprintf("$%d", i+1)
-			args[i] = ids[i]
-		}
-		args[len(ids)] = models.StatusInProgress
+	if len(requests) == 0 {
+		return nil, tx.Commit()
+	}
 
-		updateQuery := fmt.Sprintf(`
-			UPDATE reporting.report_requests
-			SET status = $%d, updated_at = $%d
-			WHERE id IN (%s)
-		`, len(ids)+1, len(ids)+2, strings.Join(placeholders, ","))
+	ids := make([]string, len(requests))
+	for i, req := range requests {
+		ids[i] = req.ID.String()
+	}
 
-		args = append(args, time.Now())
+	updateQuery := `
+		UPDATE reporting.report_requests
+		SET status = $1,
+			retry_count = retry_count + CASE WHEN $2 THEN 1 ELSE 0 END,
+			lease_owner = $3,
+			lease_expires_at = now() + make_interval(secs => $4),
+			updated_at = $5
+		WHERE id = ANY($6::uuid[])
+	`
+	_, err = tx.ExecContext(ctx, updateQuery, models.StatusInProgress, retry, actor, lease.Seconds(), time.Now(), pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
 
-		_, err = tx.ExecContext(ctx, updateQuery, args...)
-		if err != nil {
+	for _, req := range requests {
+		event := audit.Event{
+			RequestID:  req.ID,
+			Type:       audit.EventStatusChanged,
+			FromStatus: req.Status,
+			ToStatus:   models.StatusInProgress,
+			Actor:      actor,
+			Source:     audit.SourceGenerator,
+		}
+		if retry {
+			req.RetryCount++
+			event.Type = audit.EventRetryAttempt
+			if req.Error.Valid {
+				event.Error = &req.Error.String
+			}
+		}
+		event.RetryCount = &req.RetryCount
+		if err := audit.Record(ctx, tx, event); err != nil {
 			return nil, err
 		}
+		req.Status = models.StatusInProgress
 	}
 
 	if err = tx.Commit(); err != nil {
@@ -104,7 +154,59 @@ func (r *ReportRequestRepository) GetPendingRequests(ctx context.Context, limit
 	return requests, nil
 }
 
-func (r *ReportRequestRepository) UpdateRequestStatus(ctx context.Context, id uuid.UUID, status string, errorMsg *string, reportPath *string) error {
+// LeaseState — результат продления аренды
+type LeaseState int
+
+const (
+	// LeaseRenewed — аренда продлена, генерацию можно продолжать
+	LeaseRenewed LeaseState = iota
+	// LeaseCancelled — запрос отменен пользователем
+	LeaseCancelled
+	// LeaseLost — запрос завершен или передан другому воркеру после истечения аренды
+	LeaseLost
+)
+
+// RenewLease продлевает аренду запроса на lease и одним запросом к базе сообщает,
+// не отменен ли запрос, поэтому отдельно опрашивать статус во время генерации не нужно.
+func (r *ReportRequestRepository) RenewLease(ctx context.Context, id uuid.UUID, actor string, lease time.Duration) (LeaseState, error) {
+	query := `
+		WITH renewed AS (
+			UPDATE reporting.report_requests
+			SET lease_expires_at = now() + make_interval(secs => $1)
+			WHERE id = $2 AND status = $3 AND lease_owner = $4
+			RETURNING id
+		)
+		SELECT
+			EXISTS (SELECT 1 FROM renewed),
+			(SELECT status FROM reporting.report_requests WHERE id = $2)
+	`
+	var (
+		renewed bool
+		status  sql.NullString
+	)
+	err := r.db.QueryRowContext(ctx, query, lease.Seconds(), id, models.StatusInProgress, actor).Scan(&renewed, &status)
+	if err != nil {
+		return LeaseLost, err
+	}
+	return leaseState(renewed, status.String), nil
+}
+
+// leaseState определяет результат продления по тому, продлена ли аренда, и текущему статусу запроса
+func leaseState(renewed bool, status string) LeaseState {
+	switch {
+	case renewed:
+		return LeaseRenewed
+	case status == models.StatusCancelled:
+		return LeaseCancelled
+	default:
+		return LeaseLost
+	}
+}
+
+// UpdateRequestStatus сохраняет результат обработки запроса, снимает аренду и записывает переход в историю.
+// Отмененный или уже завершенный запрос не изменяется; если аренду перехватил другой воркер,
+// возвращается ErrLeaseLost.
+func (r *ReportRequestRepository) UpdateRequestStatus(ctx context.Context, id uuid.UUID, status string, errorMsg *string, reportPath *string, actor string) error {
 	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
 	if err != nil {
 		return err
@@ -112,13 +214,16 @@ func (r *ReportRequestRepository) UpdateRequestStatus(ctx context.Context, id uu
 	defer tx.Rollback()
 
 	query := `
-		SELECT status 
-		FROM reporting.report_requests 
-		WHERE id = $1 
-		FOR UPDATE SKIP LOCKED
+		SELECT status, lease_owner
+		FROM reporting.report_requests
+		WHERE id = $1
+		FOR UPDATE
 	`
-	var currentStatus string
-	err = tx.QueryRowContext(ctx, query, id).Scan(&currentStatus)
+	var (
+		currentStatus string
+		leaseOwner    sql.NullString
+	)
+	err = tx.QueryRowContext(ctx, query, id).Scan(&currentStatus, &leaseOwner)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return fmt.Errorf("запрос не найден: %s", id)
@@ -126,29 +231,169 @@ func (r *ReportRequestRepository) UpdateRequestStatus(ctx context.Context, id uu
 		return err
 	}
 
-	if currentStatus == models.StatusCompleted || currentStatus == models.StatusFailed {
+	if currentStatus != models.StatusInProgress {
 		return nil
 	}
+	if leaseOwner.String != actor {
+		return ErrLeaseLost
+	}
 
+	// notification_sent сбрасывается, чтобы об успешной повторной попытке тоже пришло уведомление.
+	// completed_at ставится только при успешной генерации: по нему register решает, свежий ли результат.
+	now := time.Now()
+	var completedAt *time.Time
+	if status == models.StatusCompleted {
+		completedAt = &now
+	}
 	updateQuery := `
 		UPDATE reporting.report_requests
-		SET status = $1, error = $2, report_path = $3, updated_at = $4
-		WHERE id = $5
+		SET status = $1, error = $2, report_path = $3, updated_at = $4, completed_at = $5,
+			notification_sent = false, lease_owner = NULL, lease_expires_at = NULL
+		WHERE id = $6
 	`
-	_, err = tx.ExecContext(ctx, updateQuery, status, errorMsg, reportPath, time.Now(), id)
+	_, err = tx.ExecContext(ctx, updateQuery, status, errorMsg, reportPath, now, completedAt, id)
 	if err != nil {
 		return err
 	}
 
+	event := audit.Event{
+		RequestID:  id,
+		Type:       audit.EventStatusChanged,
+		FromStatus: currentStatus,
+		ToStatus:   status,
+		Error:      errorMsg,
+		Actor:      actor,
+		Source:     audit.SourceGenerator,
+	}
+	if reportPath != nil {
+		event.Details = map[string]interface{}{"report_path": *reportPath}
+	}
+	if err := audit.Record(ctx, tx, event); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
-func (r *ReportRequestRepository) IncrementRetryCount(ctx context.Context, id uuid.UUID) error {
+// ReapResult — итог одного прохода ReapExpiredLeases
+type ReapResult struct {
+	// Requeued — запросы, возвращенные в PENDING
+	Requeued []uuid.UUID
+	// Failed — запросы, исчерпавшие попытки и переведенные в FAILED
+	Failed []uuid.UUID
+}
+
+// ReapExpiredLeases возвращает в PENDING до limit запросов IN_PROGRESS с истекшей арендой
+// и увеличивает их счетчик попыток; запрос, исчерпавший maxRetries попыток, переводится в FAILED.
+// Запросы без аренды (захваченные до появления аренды) считаются зависшими, если не обновлялись дольше lease.
+func (r *ReportRequestRepository) ReapExpiredLeases(ctx context.Context, limit, maxRetries int, lease time.Duration, actor string) (*ReapResult, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	query := `
-		UPDATE reporting.report_requests
-		SET retry_count = retry_count + 1, updated_at = $1
-		WHERE id = $2
+		SELECT id, lease_owner, lease_expires_at, retry_count
+		FROM reporting.report_requests
+		WHERE status = $1
+		AND (lease_expires_at < now()
+			OR (lease_expires_at IS NULL AND updated_at < now() - make_interval(secs => $2)))
+		ORDER BY lease_expires_at ASC NULLS FIRST
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
 	`
-	_, err := r.db.ExecContext(ctx, query, time.Now(), id)
-	return err
+	rows, err := tx.QueryContext(ctx, query, models.StatusInProgress, lease.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type expired struct {
+		id         uuid.UUID
+		owner      sql.NullString
+		expiresAt  sql.NullTime
+		retryCount int
+	}
+	var requests []expired
+	for rows.Next() {
+		var e expired
+		if err := rows.Scan(&e.id, &e.owner, &e.expiresAt, &e.retryCount); err != nil {
+			return nil, err
+		}
+		requests = append(requests, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	result := &ReapResult{}
+	for _, e := range requests {
+		retryCount := e.retryCount + 1
+		status := models.StatusPending
+		var errorMsg *string
+		if retryCount >= maxRetries {
+			status = models.StatusFailed
+			message := fmt.Sprintf("аренда воркера %s истекла, попытки исчерпаны (%d из %d)", e.owner.String, retryCount, maxRetries)
+			errorMsg = &message
+		}
+
+		updateQuery := `
+			UPDATE reporting.report_requests
+			SET status = $1, retry_count = $2, error = COALESCE($3, error), updated_at = $4,
+				notification_sent = false, lease_owner = NULL, lease_expires_at = NULL
+			WHERE id = $5
+		`
+		if _, err := tx.ExecContext(ctx, updateQuery, status, retryCount, errorMsg, time.Now(), e.id); err != nil {
+			return nil, err
+		}
+
+		details := map[string]interface{}{"reason": "lease_expired"}
+		if e.owner.Valid {
+			details["lease_owner"] = e.owner.String
+		}
+		if e.expiresAt.Valid {
+			details["lease_expires_at"] = e.expiresAt.Time
+		}
+		err := audit.Record(ctx, tx, audit.Event{
+			RequestID:  e.id,
+			Type:       audit.EventStatusChanged,
+			FromStatus: models.StatusInProgress,
+			ToStatus:   status,
+			RetryCount: &retryCount,
+			Error:      errorMsg,
+			Actor:      actor,
+			Source:     audit.SourceGenerator,
+			Details:    details,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if status == models.StatusPending {
+			result.Requeued = append(result.Requeued, e.id)
+		} else {
+			result.Failed = append(result.Failed, e.id)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *ReportRequestRepository) GetRequestStatus(ctx context.Context, id uuid.UUID) (string, error) {
+	query := `
+		SELECT status
+		FROM reporting.report_requests
+		WHERE id = $1
+	`
+	var status string
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("запрос не найден: %s", id)
+	}
+	return status, err
 }
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/KostySCH/Reports_go/pkg/reporttype"
+	"github.com/KostySCH/Reports_go/reports_generator/internal/models"
+	"github.com/KostySCH/Reports_go/reports_generator/internal/report"
+)
+
+// BranchPerformanceType — отчет по эффективности филиала за месяц
+const BranchPerformanceType = reporttype.BranchPerformanceReport
+
+// reportTypes — схемы параметров, по которым reports_register принимает запросы
+var reportTypes = reporttype.Default()
+
+// BranchPerformanceReport реализует report.ReportGenerator для отчета по эффективности филиала
+type BranchPerformanceReport struct {
+	db         *sql.DB
+	docService *DocumentService
+}
+
+func NewBranchPerformanceReport(db *sql.DB, docService *DocumentService) *BranchPerformanceReport {
+	return &BranchPerformanceReport{db: db, docService: docService}
+}
+
+func (g *BranchPerformanceReport) Type() string {
+	return BranchPerformanceType
+}
+
+// DecodeParams проверяет параметры по общей схеме reporttype и разбирает их в *models.BranchPerformanceParams
+func (g *BranchPerformanceReport) DecodeParams(raw json.RawMessage) (interface{}, error) {
+	normalized, err := reportTypes.ValidateJSON(BranchPerformanceType, raw)
+	if err != nil {
+		return nil, &report.ParamsError{Reason: err.Error()}
+	}
+
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора параметров: %v", err)
+	}
+	var params models.BranchPerformanceParams
+	if err := json.Unmarshal(data, &params); err != nil {
+		return nil, fmt.Errorf("ошибка разбора параметров: %v", err)
+	}
+	return &params, nil
+}
+
+// Collect собирает данные отчета по эффективности филиала. Если филиал не найден,
+// возвращаемая ошибка содержит sql.ErrNoRows.
+func (g *BranchPerformanceReport) Collect(ctx context.Context, p interface{}) (interface{}, error) {
+	params := p.(*models.BranchPerformanceParams)
+
+	fullDate := params.Month + "-01"
+
+	branchInfo, err := g.getBranchInfo(ctx, params.BranchID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения информации о филиале: %w", err)
+	}
+
+	// Получаем статистику клиентов
+	customerStats, err := g.getCustomerStats(ctx, params.BranchID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения статистики клиентов: %w", err)
+	}
+
+	// Получаем статистику транзакций
+	transactionStats, err := g.getTransactionStats(ctx, params.BranchID, fullDate)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения статистики транзакций: %w", err)
+	}
+
+	// Получаем ежедневную активность
+	dailyActivity, err := g.getDailyActivity(ctx, params.BranchID, fullDate)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения ежедневной активности: %w", err)
+	}
+
+	// Получаем топ клиентов
+	topCustomers, err := g.getTopCustomers(ctx, params.BranchID, fullDate)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения топ клиентов: %w", err)
+	}
+
+	// Формируем данные для отчета
+	return &models.BranchPerformanceData{
+		BranchInfo:       *branchInfo,
+		CustomerStats:    *customerStats,
+		TransactionStats: *transactionStats,
+		DailyActivity:    dailyActivity,
+		TopCustomers:     topCustomers,
+	}, nil
+}
+
+// Render формирует документ в формате из параметров
+func (g *BranchPerformanceReport) Render(data interface{}, p interface{}) (string, error) {
+	return g.docService.GenerateReport(data.(*models.BranchPerformanceData), p.(*models.BranchPerformanceParams).Format)
+}
+
+func (g *BranchPerformanceReport) getBranchInfo(ctx context.Context, branchID int64) (*models.BranchInfo, error) {
+	query := `
+		SELECT b.branch_id, b.branch_name, b.location, b.phone, b.email, 
+		       e.first_name || ' ' || e.last_name as manager_name
+		FROM bank.branches b
+		LEFT JOIN bank.employees e ON b.manager_id = e.employee_id
+		WHERE b.branch_id = $1
+	`
+	var info models.BranchInfo
+	err := g.db.QueryRowContext(ctx, query, branchID).Scan(
+		&info.ID, &info.Name, &info.Location, &info.Phone, &info.Email, &info.ManagerName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (g *BranchPerformanceReport) getCustomerStats(ctx context.Context, branchID int64) (*models.CustomerStats, error) {
+	query := `
+		SELECT 
+			COUNT(DISTINCT c.customer_id) as total_customers,
+			COUNT(DISTINCT a.account_id) as total_accounts,
+			COUNT(DISTINCT CASE WHEN a.status = 'ACTIVE' THEN a.account_id END) as active_accounts
+		FROM bank.customers c
+		LEFT JOIN bank.accounts a ON c.customer_id = a.customer_id
+		WHERE c.branch_id = $1
+	`
+	var stats models.CustomerStats
+	err := g.db.QueryRowContext(ctx, query, branchID).Scan(
+		&stats.TotalCustomers, &stats.TotalAccounts, &stats.ActiveAccounts,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func (g *BranchPerformanceReport) getTransactionStats(ctx context.Context, branchID int64, month string) (*models.TransactionStats, error) {
+	query := `
+		SELECT 
+			COALESCE(COUNT(*), 0) as total_transactions,
+			COALESCE(SUM(amount), 0) as total_amount,
+			COALESCE(AVG(amount), 0) as average_amount
+		FROM bank.transactions t
+		JOIN bank.accounts a ON t.account_id = a.account_id
+		JOIN bank.customers c ON a.customer_id = c.customer_id
+		WHERE c.branch_id = $1
+	`
+	var stats models.TransactionStats
+	err := g.db.QueryRowContext(ctx, query, branchID).Scan(
+		&stats.TotalTransactions, &stats.TotalAmount, &stats.AverageAmount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func (g *BranchPerformanceReport) getDailyActivity(ctx context.Context, branchID int64, month string) ([]models.DailyActivity, error) {
+	query := `
+		WITH daily_stats AS (
+			SELECT 
+				DATE(t.created_at) as date,
+				COALESCE(COUNT(*), 0) as transactions,
+				COALESCE(SUM(amount), 0) as amount
+			FROM bank.transactions t
+			JOIN bank.accounts a ON t.account_id = a.account_id
+			JOIN bank.customers c ON a.customer_id = c.customer_id
+			WHERE c.branch_id = $1
+			GROUP BY DATE(t.created_at)
+		),
+		prev_month_stats AS (
+			SELECT 
+				DATE(t.created_at) as date,
+				COALESCE(COUNT(*), 0) as transactions,
+				COALESCE(SUM(amount), 0) as amount
+			FROM bank.transactions t
+			JOIN bank.accounts a ON t.account_id = a.account_id
+			JOIN bank.customers c ON a.customer_id = c.customer_id
+			WHERE c.branch_id = $1
+			GROUP BY DATE(t.created_at)
+		)
+		SELECT 
+			ds.date,
+			ds.transactions,
+			ds.amount,
+			CASE 
+				WHEN pms.amount IS NULL OR pms.amount = 0 THEN 0
+				ELSE ((ds.amount - pms.amount) / pms.amount) * 100
+			END as growth_percent
+		FROM daily_stats ds
+		LEFT JOIN prev_month_stats pms ON ds.date = pms.date + INTERVAL '1 month'
+		ORDER BY ds.date DESC
+		LIMIT 30
+	`
+	rows, err := g.db.QueryContext(ctx, query, branchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var activities []models.DailyActivity
+	for rows.Next() {
+		var activity models.DailyActivity
+		err := rows.Scan(
+			&activity.Date,
+			&activity.Transactions,
+			&activity.Amount,
+			&activity.GrowthPercent,
+		)
+		if err != nil {
+			return nil, err
+		}
+		activities = append(activities, activity)
+	}
+	return activities, nil
+}
+
+func (g *BranchPerformanceReport) getTopCustomers(ctx context.Context, branchID int64, month string) ([]models.TopCustomer, error) {
+	query := `
+		SELECT 
+			c.first_name || ' ' || c.last_name as name,
+			COALESCE(COUNT(*), 0) as transactions,
+			COALESCE(SUM(t.amount), 0) as total_amount
+		FROM bank.transactions t
+		JOIN bank.accounts a ON t.account_id = a.account_id
+		JOIN bank.customers c ON a.customer_id = c.customer_id
+		WHERE c.branch_id = $1
+		GROUP BY c.customer_id, c.first_name, c.last_name
+		ORDER BY total_amount DESC
+		LIMIT 10
+	`
+	rows, err := g.db.QueryContext(ctx, query, branchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var customers []models.TopCustomer
+	for rows.Next() {
+		var customer models.TopCustomer
+		err := rows.Scan(
+			&customer.Name,
+			&customer.Transactions,
+			&customer.TotalAmount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		customers = append(customers, customer)
+	}
+	return customers, nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/KostySCH/Reports_go/reports_generator/internal/models"
+	"github.com/KostySCH/Reports_go/reports_generator/internal/report"
+)
+
+func TestBranchPerformanceDecodeParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    *models.BranchPerformanceParams
+		wantErr string
+	}{
+		{
+			name: "valid",
+			raw:  `{"branch_id": 3, "month": "2024-05", "format": "docx"}`,
+			want: &models.BranchPerformanceParams{BranchID: 3, Month: "2024-05", Format: "docx"},
+		},
+		{
+			name:    "missing branch",
+			raw:     `{"month": "2024-05", "format": "pdf"}`,
+			wantErr: "validation failed: params.branch_id: is required",
+		},
+		{
+			name:    "not an object",
+			raw:     `[1, 2]`,
+			wantErr: "validation failed: params: must be a JSON object",
+		},
+	}
+	generator := NewBranchPerformanceReport(nil, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := generator.DecodeParams(json.RawMessage(tt.raw))
+			if tt.wantErr != "" {
+				var perr *report.ParamsError
+				if !errors.As(err, &perr) || err.Error() != tt.wantErr {
+					t.Fatalf("DecodeParams error = %v, want *report.ParamsError %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeParams: %v", err)
+			}
+			if !reflect.DeepEqual(params, tt.want) {
+				t.Errorf("DecodeParams = %+v, want %+v", params, tt.want)
+			}
+		})
+	}
+}
@@ -92,3 +92,17 @@ func (s *MinioService) GetReportURL(ctx context.Context, reportPath string) (str
 
 	return url.String(), nil
 }
+
+// Ping проверяет, что MinIO доступен и бакеты отчетов существуют
+func (s *MinioService) Ping(ctx context.Context) error {
+	for _, bucket := range []string{s.pdfBucket, s.docxBucket} {
+		exists, err := s.client.BucketExists(ctx, bucket)
+		if err != nil {
+			return fmt.Errorf("ошибка проверки бакета %s: %v", bucket, err)
+		}
+		if !exists {
+			return fmt.Errorf("бакет %s не найден", bucket)
+		}
+	}
+	return nil
+}
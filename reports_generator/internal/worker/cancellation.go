@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/KostySCH/Reports_go/pkg/shutdown"
+	"github.com/KostySCH/Reports_go/reports_generator/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	// errCancelled означает, что запрос отменен пользователем во время генерации
+	errCancelled = errors.New("запрос отменен пользователем")
+	// errLeaseLost означает, что аренда истекла и запрос возвращен в очередь, поэтому результат генерации не нужен
+	errLeaseLost = errors.New("аренда запроса потеряна")
+)
+
+// watchRequest возвращает контекст генерации, который отменяется с причиной errCancelled,
+// если запрос переведен в статус CANCELLED, или errLeaseLost, если аренду не удалось продлить.
+// Пока идет генерация, аренда actor продлевается на lease каждую треть lease; отмена обнаруживается
+// тем же запросом продления, то есть не позже чем через треть lease.
+// stop нужно вызвать по завершении генерации.
+func watchRequest(ctx context.Context, repo ReportRequestRepository, id uuid.UUID, actor string, lease time.Duration) (context.Context, func()) {
+	genCtx, cancel := context.WithCancelCause(ctx)
+
+	go func() {
+		ticker := time.NewTicker(lease / 3)
+		defer ticker.Stop()
+
+		// Первое продление выполняется сразу при старте генерации
+		for {
+			state, err := repo.RenewLease(genCtx, id, actor, lease)
+			if err == nil {
+				switch state {
+				case repository.LeaseCancelled:
+					cancel(errCancelled)
+					return
+				case repository.LeaseLost:
+					cancel(errLeaseLost)
+					return
+				}
+			}
+
+			select {
+			case <-genCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return genCtx, func() { cancel(nil) }
+}
+
+// interrupted возвращает errCancelled, errLeaseLost или shutdown.ErrAborted, если генерация была прервана
+// по одной из этих причин
+func interrupted(ctx context.Context) error {
+	cause := context.Cause(ctx)
+	if errors.Is(cause, errCancelled) || errors.Is(cause, errLeaseLost) || errors.Is(cause, shutdown.ErrAborted) {
+		return cause
+	}
+	return nil
+}
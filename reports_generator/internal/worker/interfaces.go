@@ -0,0 +1,44 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/KostySCH/Reports_go/reports_generator/internal/models"
+	"github.com/KostySCH/Reports_go/reports_generator/internal/report"
+	"github.com/KostySCH/Reports_go/reports_generator/internal/repository"
+	"github.com/KostySCH/Reports_go/reports_generator/internal/service"
+	"github.com/google/uuid"
+)
+
+// ReportRequestRepository захватывает запросы на генерацию и сохраняет результат.
+// Реализации: repository.ReportRequestRepository (Postgres) и memory.ReportRequestRepository.
+type ReportRequestRepository interface {
+	GetPendingRequests(ctx context.Context, limit int, actor string, lease time.Duration) ([]*models.ReportRequest, error)
+	ClaimFailedRequests(ctx context.Context, limit, maxRetries int, actor string, lease time.Duration) ([]*models.ReportRequest, error)
+	// RenewLease продлевает аренду и сообщает, не отменен ли запрос
+	RenewLease(ctx context.Context, id uuid.UUID, actor string, lease time.Duration) (repository.LeaseState, error)
+	// UpdateRequestStatus возвращает repository.ErrLeaseLost, если аренду перехватил другой воркер
+	UpdateRequestStatus(ctx context.Context, id uuid.UUID, status string, errorMsg *string, reportPath *string, actor string) error
+	ReapExpiredLeases(ctx context.Context, limit, maxRetries int, lease time.Duration, actor string) (*repository.ReapResult, error)
+}
+
+// ReportBuilder формирует файл отчета любого зарегистрированного типа и возвращает путь к нему в хранилище.
+// Реализации: service.ReportService и memtest.ReportService в тестах.
+type ReportBuilder interface {
+	Generate(ctx context.Context, reportType string, params json.RawMessage) (string, error)
+}
+
+var (
+	_ ReportRequestRepository = (*repository.ReportRequestRepository)(nil)
+	_ ReportBuilder           = (*service.ReportService)(nil)
+)
+
+// rejected сообщает, что запрос отклонен до генерации: тип не поддерживается или параметры
+// некорректны. Такие ошибки записываются в запрос без префикса ошибки генерации.
+func rejected(err error) bool {
+	var perr *report.ParamsError
+	return errors.As(err, &perr) || errors.Is(err, report.ErrUnknownType)
+}
@@ -0,0 +1,66 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/KostySCH/Reports_go/pkg/audit"
+	"github.com/KostySCH/Reports_go/pkg/queue"
+	"github.com/KostySCH/Reports_go/reports_generator/internal/logger"
+)
+
+const (
+	reaperWorkerType = "Возврат зависших"
+
+	// reaperBatchSize — сколько запросов с истекшей арендой обрабатывается за один проход
+	reaperBatchSize = 100
+)
+
+// Reaper возвращает в очередь запросы IN_PROGRESS, чья аренда истекла, например потому что
+// процесс генератора упал посреди генерации. Несколько экземпляров могут работать одновременно:
+// строки блокируются через FOR UPDATE SKIP LOCKED.
+type Reaper struct {
+	repo       ReportRequestRepository
+	pollPeriod time.Duration
+	lease      time.Duration
+	workerID   int
+	actor      string
+}
+
+func NewReaper(repo ReportRequestRepository, pollPeriod, lease time.Duration) *Reaper {
+	return &Reaper{
+		repo:       repo,
+		pollPeriod: pollPeriod,
+		lease:      lease,
+		workerID:   5,
+		actor:      audit.WorkerActor("generator-reaper-5"),
+	}
+}
+
+func (w *Reaper) Start(ctx context.Context) {
+	logger.LogWorkerEvent(reaperWorkerType, w.workerID, fmt.Sprintf("Запуск воркера, аренда %s", w.lease))
+
+	ticker := time.NewTicker(w.pollPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.LogWorkerEvent(reaperWorkerType, w.workerID, "Остановка воркера")
+			return
+		case <-ticker.C:
+			result, err := w.repo.ReapExpiredLeases(ctx, reaperBatchSize, queue.MaxRetries, w.lease, w.actor)
+			if err != nil {
+				logger.LogWorkerError(reaperWorkerType, w.workerID, fmt.Errorf("ошибка возврата зависших запросов: %v", err))
+				continue
+			}
+			for _, id := range result.Requeued {
+				logger.LogWorkerEvent(reaperWorkerType, w.workerID, fmt.Sprintf("Аренда запроса %s истекла, запрос возвращен в очередь", id))
+			}
+			for _, id := range result.Failed {
+				logger.LogWorkerEvent(reaperWorkerType, w.workerID, fmt.Sprintf("Аренда запроса %s истекла, попытки исчерпаны", id))
+			}
+		}
+	}
+}
@@ -2,36 +2,46 @@ package worker
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/KostySCH/Reports_go/pkg/audit"
+	"github.com/KostySCH/Reports_go/pkg/queue"
+	"github.com/KostySCH/Reports_go/pkg/shutdown"
 	"github.com/KostySCH/Reports_go/reports_generator/internal/logger"
 	"github.com/KostySCH/Reports_go/reports_generator/internal/models"
 	"github.com/KostySCH/Reports_go/reports_generator/internal/repository"
-	"github.com/KostySCH/Reports_go/reports_generator/internal/service"
 )
 
 const (
-	maxRetries = 5
 	workerType = "Повторная обработка"
+
+	// retryBatchSize — сколько неудачных запросов захватывает поток за один проход
+	retryBatchSize = 1
 )
 
 type RetryWorker struct {
-	repo        *repository.ReportRequestRepository
-	reportSvc   *service.ReportService
+	repo        ReportRequestRepository
+	reportSvc   ReportBuilder
 	pollPeriod  time.Duration
 	workerID    int
 	concurrency int
+	actor       string
+	lease       time.Duration
+	drain       *shutdown.Drain
 }
 
-func NewRetryWorker(repo *repository.ReportRequestRepository, reportSvc *service.ReportService) *RetryWorker {
+func NewRetryWorker(repo ReportRequestRepository, reportSvc ReportBuilder, lease time.Duration) *RetryWorker {
 	return &RetryWorker{
 		repo:        repo,
 		reportSvc:   reportSvc,
 		pollPeriod:  30 * time.Second,
 		workerID:    2,
 		concurrency: 10,
+		actor:       audit.WorkerActor("generator-retry-2"),
+		lease:       lease,
+		drain:       shutdown.New(),
 	}
 }
 
@@ -39,11 +49,18 @@ func (w *RetryWorker) Start(ctx context.Context) {
 	logger.LogWorkerEvent(workerType, w.workerID, fmt.Sprintf("Запуск воркера с параллельностью %d", w.concurrency))
 
 	for i := 0; i < w.concurrency; i++ {
-		go w.processFailedReports(ctx)
+		w.drain.Go(func() { w.processFailedReports(ctx) })
 	}
+}
 
-	<-ctx.Done()
+// Shutdown ждет завершения повторных генераций, начатых до отмены контекста Start
+func (w *RetryWorker) Shutdown(ctx context.Context) error {
 	logger.LogWorkerEvent(workerType, w.workerID, "Остановка воркера")
+	if err := w.drain.Wait(ctx); err != nil {
+		logger.LogWorkerError(workerType, w.workerID, fmt.Errorf("не все повторные генерации завершились до остановки: %v", err))
+		return err
+	}
+	return nil
 }
 
 func (w *RetryWorker) processFailedReports(ctx context.Context) {
@@ -55,53 +72,36 @@ func (w *RetryWorker) processFailedReports(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-
-			query := `
-				SELECT id, user_id, type, params, status, created_at, updated_at, error, retry_count, report_path
-				FROM reporting.report_requests
-				WHERE status = $1 AND retry_count < $2
-				ORDER BY updated_at ASC
-				FOR UPDATE SKIP LOCKED
-			`
-			rows, err := w.repo.DB().QueryContext(ctx, query, models.StatusFailed, maxRetries)
+			requests, err := w.repo.ClaimFailedRequests(ctx, retryBatchSize, queue.MaxRetries, w.actor, w.lease)
 			if err != nil {
 				logger.LogWorkerError(workerType, w.workerID, fmt.Errorf("ошибка получения неудачных отчетов: %v", err))
 				continue
 			}
-			defer rows.Close()
-
-			for rows.Next() {
-				var req models.ReportRequest
-				err := rows.Scan(
-					&req.ID,
-					&req.UserID,
-					&req.Type,
-					&req.Params,
-					&req.Status,
-					&req.CreatedAt,
-					&req.UpdatedAt,
-					&req.Error,
-					&req.RetryCount,
-					&req.ReportPath,
-				)
-				if err != nil {
-					logger.LogWorkerError(workerType, w.workerID, fmt.Errorf("ошибка сканирования строки: %v", err))
-					continue
-				}
-
-				if err := w.repo.IncrementRetryCount(ctx, req.ID); err != nil {
-					logger.LogWorkerError(workerType, w.workerID, fmt.Errorf("ошибка обновления счетчика попыток для отчета %s: %v", req.ID, err))
-					continue
-				}
+			if len(requests) == 0 {
+				continue
+			}
 
-				if err := w.processRequest(ctx, &req); err != nil {
+			for _, req := range requests {
+				if err := w.processRequest(w.drain.Context(), req); err != nil {
+					if errors.Is(err, errCancelled) {
+						logger.LogWorkerEvent(workerType, w.workerID, fmt.Sprintf("Повторная генерация отчета %s прервана: запрос отменен", req.ID))
+						continue
+					}
+					if errors.Is(err, errLeaseLost) || errors.Is(err, repository.ErrLeaseLost) {
+						logger.LogWorkerEvent(workerType, w.workerID, fmt.Sprintf("Повторная генерация отчета %s прервана: аренда истекла", req.ID))
+						continue
+					}
+					if errors.Is(err, shutdown.ErrAborted) {
+						logger.LogWorkerEvent(workerType, w.workerID, fmt.Sprintf("Повторная генерация отчета %s прервана остановкой сервиса: запрос вернется в очередь после истечения аренды", req.ID))
+						continue
+					}
 					errorMsg := err.Error()
-					if err := w.repo.UpdateRequestStatus(ctx, req.ID, models.StatusFailed, &errorMsg, nil); err != nil {
+					if err := w.repo.UpdateRequestStatus(w.drain.Context(), req.ID, models.StatusFailed, &errorMsg, nil, w.actor); err != nil {
 						logger.LogWorkerError(workerType, w.workerID, fmt.Errorf("ошибка обновления статуса для отчета %s: %v", req.ID, err))
 					}
-					logger.LogWorkerReport(workerType, w.workerID, req.ID.String(), req.RetryCount+1, maxRetries, false, errorMsg)
+					logger.LogWorkerReport(workerType, w.workerID, req.ID.String(), req.RetryCount, queue.MaxRetries, false, errorMsg)
 				} else {
-					logger.LogWorkerReport(workerType, w.workerID, req.ID.String(), req.RetryCount+1, maxRetries, true, "")
+					logger.LogWorkerReport(workerType, w.workerID, req.ID.String(), req.RetryCount, queue.MaxRetries, true, "")
 				}
 			}
 			logger.LogWorkerSeparator()
@@ -111,43 +111,19 @@ func (w *RetryWorker) processFailedReports(ctx context.Context) {
 
 func (w *RetryWorker) processRequest(ctx context.Context, req *models.ReportRequest) error {
 
-	var params map[string]interface{}
-	if err := json.Unmarshal(req.Params, &params); err != nil {
-		return fmt.Errorf("ошибка разбора параметров: %v", err)
-	}
-
-	var reportPath string
-	var err error
+	genCtx, stop := watchRequest(ctx, w.repo, req.ID, w.actor, w.lease)
+	defer stop()
 
-	switch req.Type {
-	case "branch_performance_report":
-
-		branchID, ok := params["branch_id"]
-		if !ok {
-			return fmt.Errorf("отсутствует обязательный параметр branch_id")
-		}
-		month, ok := params["month"]
-		if !ok {
-			return fmt.Errorf("отсутствует обязательный параметр month")
-		}
-		format, ok := params["format"]
-		if !ok {
-			return fmt.Errorf("отсутствует обязательный параметр format")
-		}
-
-		branchParams := &models.BranchPerformanceParams{
-			BranchID: int64(branchID.(float64)),
-			Month:    month.(string),
-			Format:   format.(string),
-		}
-		reportPath, err = w.reportSvc.GenerateBranchPerformanceReport(ctx, branchParams)
-	default:
-		return fmt.Errorf("неподдерживаемый тип отчета: %s", req.Type)
+	reportPath, err := w.reportSvc.Generate(genCtx, req.Type, req.Params)
+	if err := interrupted(genCtx); err != nil {
+		return err
+	}
+	if rejected(err) {
+		return err
 	}
-
 	if err != nil {
 		return fmt.Errorf("ошибка генерации отчета: %v", err)
 	}
 
-	return w.repo.UpdateRequestStatus(ctx, req.ID, models.StatusCompleted, nil, &reportPath)
+	return w.repo.UpdateRequestStatus(ctx, req.ID, models.StatusCompleted, nil, &reportPath, w.actor)
 }
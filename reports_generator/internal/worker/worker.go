@@ -2,15 +2,16 @@ package worker
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
+	"github.com/KostySCH/Reports_go/pkg/audit"
+	"github.com/KostySCH/Reports_go/pkg/shutdown"
 	"github.com/KostySCH/Reports_go/reports_generator/internal/logger"
 	"github.com/KostySCH/Reports_go/reports_generator/internal/models"
 	"github.com/KostySCH/Reports_go/reports_generator/internal/repository"
-	"github.com/KostySCH/Reports_go/reports_generator/internal/service"
 )
 
 const (
@@ -18,21 +19,29 @@ const (
 )
 
 type Worker struct {
-	repo        *repository.ReportRequestRepository
-	reportSvc   *service.ReportService
+	repo        ReportRequestRepository
+	reportSvc   ReportBuilder
 	done        chan struct{}
 	stopOnce    sync.Once
 	concurrency int
 	workerID    int
+	// actor — идентификатор воркера в истории запросов и владелец аренды
+	actor string
+	lease time.Duration
+	// drain дожидается начатых генераций при остановке
+	drain *shutdown.Drain
 }
 
-func NewWorker(repo *repository.ReportRequestRepository, reportSvc *service.ReportService, concurrency int) *Worker {
+func NewWorker(repo ReportRequestRepository, reportSvc ReportBuilder, concurrency int, lease time.Duration) *Worker {
 	return &Worker{
 		repo:        repo,
 		reportSvc:   reportSvc,
 		done:        make(chan struct{}),
 		concurrency: concurrency,
 		workerID:    1,
+		actor:       audit.WorkerActor("generator-worker-1"),
+		lease:       lease,
+		drain:       shutdown.New(),
 	}
 }
 
@@ -40,7 +49,7 @@ func (w *Worker) Start(ctx context.Context) {
 	logger.LogWorkerEvent(mainWorkerType, w.workerID, fmt.Sprintf("Запуск воркера с параллельностью %d", w.concurrency))
 
 	for i := 0; i < w.concurrency; i++ {
-		go w.processRequests(ctx)
+		w.drain.Go(func() { w.processRequests(ctx) })
 	}
 
 	go func() {
@@ -56,6 +65,18 @@ func (w *Worker) Stop() {
 	})
 }
 
+// Shutdown перестает захватывать запросы и ждет завершения начатых генераций.
+// Если ctx истекает раньше, генерации прерываются, а их запросы вернутся в очередь после истечения аренды.
+func (w *Worker) Shutdown(ctx context.Context) error {
+	w.Stop()
+	if err := w.drain.Wait(ctx); err != nil {
+		logger.LogWorkerError(mainWorkerType, w.workerID, fmt.Errorf("не все генерации завершились до остановки: %v", err))
+		return err
+	}
+	logger.LogWorkerEvent(mainWorkerType, w.workerID, "Начатые генерации завершены")
+	return nil
+}
+
 func (w *Worker) processRequests(ctx context.Context) {
 	for {
 		select {
@@ -64,74 +85,76 @@ func (w *Worker) processRequests(ctx context.Context) {
 		case <-w.done:
 			return
 		default:
-
-			requests, err := w.repo.GetPendingRequests(ctx, w.concurrency)
+			processed, err := w.processPending(ctx)
 			if err != nil {
 				logger.LogWorkerError(mainWorkerType, w.workerID, fmt.Errorf("ошибка получения запросов: %v", err))
 				time.Sleep(time.Second)
 				continue
 			}
-
-			if len(requests) == 0 {
+			if processed == 0 {
 				time.Sleep(time.Second)
-				continue
-			}
-
-			for _, req := range requests {
-				if err := w.processRequest(ctx, req); err != nil {
-					errorMsg := err.Error()
-					if err := w.repo.UpdateRequestStatus(ctx, req.ID, models.StatusFailed, &errorMsg, nil); err != nil {
-						logger.LogWorkerError(mainWorkerType, w.workerID, fmt.Errorf("ошибка обновления статуса запроса %s: %v", req.ID, err))
-					}
-					logger.LogWorkerReport(mainWorkerType, w.workerID, req.ID.String(), 1, 1, false, errorMsg)
-				} else {
-					logger.LogWorkerReport(mainWorkerType, w.workerID, req.ID.String(), 1, 1, true, "")
-				}
 			}
-			logger.LogWorkerSeparator()
 		}
 	}
 }
 
-func (w *Worker) processRequest(ctx context.Context, req *models.ReportRequest) error {
-
-	var params map[string]interface{}
-	if err := json.Unmarshal(req.Params, &params); err != nil {
-		return fmt.Errorf("ошибка разбора параметров: %v", err)
+// processPending выполняет один проход: захватывает запрос из очереди, формирует отчет
+// и возвращает число обработанных запросов. Ошибка генерации записывается в запрос,
+// а не возвращается. Генерация выполняется в контексте остановки воркера, а не ctx,
+// чтобы Shutdown мог дождаться ее завершения.
+func (w *Worker) processPending(ctx context.Context) (int, error) {
+	// Каждый поток захватывает по одному запросу, чтобы аренда не истекала,
+	// пока запрос ждет обработки предыдущих
+	requests, err := w.repo.GetPendingRequests(ctx, 1, w.actor, w.lease)
+	if err != nil {
+		return 0, err
+	}
+	if len(requests) == 0 {
+		return 0, nil
 	}
 
-	var reportPath string
-	var err error
+	for _, req := range requests {
+		if err := w.processRequest(w.drain.Context(), req); err != nil {
+			if errors.Is(err, errCancelled) {
+				logger.LogWorkerEvent(mainWorkerType, w.workerID, fmt.Sprintf("Генерация отчета %s прервана: запрос отменен", req.ID))
+				continue
+			}
+			if errors.Is(err, errLeaseLost) || errors.Is(err, repository.ErrLeaseLost) {
+				logger.LogWorkerEvent(mainWorkerType, w.workerID, fmt.Sprintf("Генерация отчета %s прервана: аренда истекла", req.ID))
+				continue
+			}
+			if errors.Is(err, shutdown.ErrAborted) {
+				logger.LogWorkerEvent(mainWorkerType, w.workerID, fmt.Sprintf("Генерация отчета %s прервана остановкой сервиса: запрос вернется в очередь после истечения аренды", req.ID))
+				continue
+			}
+			errorMsg := err.Error()
+			if err := w.repo.UpdateRequestStatus(w.drain.Context(), req.ID, models.StatusFailed, &errorMsg, nil, w.actor); err != nil {
+				logger.LogWorkerError(mainWorkerType, w.workerID, fmt.Errorf("ошибка обновления статуса запроса %s: %v", req.ID, err))
+			}
+			logger.LogWorkerReport(mainWorkerType, w.workerID, req.ID.String(), 1, 1, false, errorMsg)
+		} else {
+			logger.LogWorkerReport(mainWorkerType, w.workerID, req.ID.String(), 1, 1, true, "")
+		}
+	}
+	logger.LogWorkerSeparator()
+	return len(requests), nil
+}
 
-	switch req.Type {
-	case "branch_performance_report":
+func (w *Worker) processRequest(ctx context.Context, req *models.ReportRequest) error {
 
-		branchID, ok := params["branch_id"]
-		if !ok {
-			return fmt.Errorf("отсутствует обязательный параметр branch_id")
-		}
-		month, ok := params["month"]
-		if !ok {
-			return fmt.Errorf("отсутствует обязательный параметр month")
-		}
-		format, ok := params["format"]
-		if !ok {
-			return fmt.Errorf("отсутствует обязательный параметр format")
-		}
+	genCtx, stop := watchRequest(ctx, w.repo, req.ID, w.actor, w.lease)
+	defer stop()
 
-		branchParams := &models.BranchPerformanceParams{
-			BranchID: int64(branchID.(float64)),
-			Month:    month.(string),
-			Format:   format.(string),
-		}
-		reportPath, err = w.reportSvc.GenerateBranchPerformanceReport(ctx, branchParams)
-	default:
-		return fmt.Errorf("неподдерживаемый тип отчета: %s", req.Type)
+	reportPath, err := w.reportSvc.Generate(genCtx, req.Type, req.Params)
+	if err := interrupted(genCtx); err != nil {
+		return err
+	}
+	if rejected(err) {
+		return err
 	}
-
 	if err != nil {
 		return fmt.Errorf("ошибка генерации отчета: %v", err)
 	}
 
-	return w.repo.UpdateRequestStatus(ctx, req.ID, models.StatusCompleted, nil, &reportPath)
+	return w.repo.UpdateRequestStatus(ctx, req.ID, models.StatusCompleted, nil, &reportPath, w.actor)
 }
@@ -0,0 +1,166 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KostySCH/Reports_go/pkg/memstore"
+	"github.com/KostySCH/Reports_go/reports_generator/internal/memtest"
+	"github.com/KostySCH/Reports_go/reports_generator/internal/models"
+	"github.com/KostySCH/Reports_go/reports_generator/internal/report"
+	"github.com/KostySCH/Reports_go/reports_generator/internal/repository/memory"
+	"github.com/KostySCH/Reports_go/reports_generator/internal/service"
+	"github.com/google/uuid"
+)
+
+const validParams = `{"branch_id":3,"month":"2024-05","format":"pdf"}`
+
+var created = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestWorker() (*Worker, *memtest.ReportService, *memstore.Store) {
+	store := memstore.New()
+	reports := memtest.NewReportService(report.NewRegistry(
+		// Базы и шаблонов нет: memtest.ReportService только разбирает параметры
+		service.NewBranchPerformanceReport(nil, nil),
+	))
+	return NewWorker(memory.NewReportRequestRepository(store), reports, 1, time.Minute), reports, store
+}
+
+// insertPending добавляет ожидающий запрос, созданный через age после created
+func insertPending(store *memstore.Store, params string, priority int, age time.Duration) uuid.UUID {
+	id := uuid.New()
+	store.Tx(func(tx *memstore.Tx) error {
+		tx.Insert(&memstore.Request{
+			ID:        id,
+			UserID:    1,
+			Type:      "branch_performance_report",
+			Params:    []byte(params),
+			Status:    models.StatusPending,
+			Priority:  priority,
+			CreatedAt: created.Add(age),
+			UpdatedAt: created.Add(age),
+		})
+		return nil
+	})
+	return id
+}
+
+func stored(store *memstore.Store, id uuid.UUID) (*memstore.Request, []string) {
+	var (
+		row     *memstore.Request
+		history []string
+	)
+	store.Tx(func(tx *memstore.Tx) error {
+		row = tx.Get(id).Clone()
+		for _, e := range tx.Events(id) {
+			history = append(history, fmt.Sprintf("%s %s->%s", e.Type, e.FromStatus, e.ToStatus))
+		}
+		return nil
+	})
+	return row, history
+}
+
+func mustProcess(t *testing.T, w *Worker, want int) {
+	t.Helper()
+	processed, err := w.processPending(context.Background())
+	if err != nil {
+		t.Fatalf("processPending: %v", err)
+	}
+	if processed != want {
+		t.Fatalf("processPending processed %d requests, want %d", processed, want)
+	}
+}
+
+func TestWorkerCompletesPendingRequest(t *testing.T) {
+	w, reports, store := newTestWorker()
+	id := insertPending(store, validParams, 0, 0)
+
+	mustProcess(t, w, 1)
+	mustProcess(t, w, 0)
+
+	row, history := stored(store, id)
+	if row.Status != models.StatusCompleted || row.ReportPath == nil || row.Error != nil {
+		t.Fatalf("request = %s, path %v, error %v; want COMPLETED with a path", row.Status, row.ReportPath, row.Error)
+	}
+	if !strings.HasPrefix(*row.ReportPath, "minio://reports-pdf/") {
+		t.Errorf("report path = %q, want pdf bucket", *row.ReportPath)
+	}
+	if row.LeaseOwner != nil {
+		t.Errorf("lease owner = %q, want the lease released", *row.LeaseOwner)
+	}
+	if row.CompletedAt == nil || !row.CompletedAt.Equal(row.UpdatedAt) {
+		t.Errorf("completed at = %v, want the completion time %v", row.CompletedAt, row.UpdatedAt)
+	}
+
+	generated, ok := reports.Reports()[*row.ReportPath]
+	if !ok {
+		t.Fatalf("no report generated at %s", *row.ReportPath)
+	}
+	params, ok := generated.Params.(*models.BranchPerformanceParams)
+	if !ok || params.BranchID != 3 || params.Month != "2024-05" {
+		t.Errorf("generated params = %+v", generated.Params)
+	}
+
+	wantHistory := []string{
+		"STATUS_CHANGED PENDING->IN_PROGRESS",
+		"STATUS_CHANGED IN_PROGRESS->COMPLETED",
+	}
+	if !reflect.DeepEqual(history, wantHistory) {
+		t.Errorf("history = %v, want %v", history, wantHistory)
+	}
+}
+
+func TestWorkerRecordsFailure(t *testing.T) {
+	tests := []struct {
+		name      string
+		params    string
+		fail      error
+		wantError string
+	}{
+		{name: "generation error", params: validParams, fail: errors.New("minio недоступен"),
+			wantError: "ошибка генерации отчета: minio недоступен"},
+		// Некорректные параметры записываются без префикса ошибки генерации
+		{name: "invalid params", params: `{"month":"2024-05","format":"pdf"}`,
+			wantError: "validation failed: params.branch_id: is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, reports, store := newTestWorker()
+			reports.FailWith(tt.fail)
+			id := insertPending(store, tt.params, 0, 0)
+
+			mustProcess(t, w, 1)
+
+			row, _ := stored(store, id)
+			if row.Status != models.StatusFailed || row.Error == nil {
+				t.Fatalf("request = %s, error %v; want FAILED with an error", row.Status, row.Error)
+			}
+			if *row.Error != tt.wantError {
+				t.Errorf("error = %q, want %q", *row.Error, tt.wantError)
+			}
+			if row.ReportPath != nil || row.CompletedAt != nil {
+				t.Errorf("failed request has report path %v, completed at %v", row.ReportPath, row.CompletedAt)
+			}
+		})
+	}
+}
+
+func TestWorkerProcessesQueueInOrder(t *testing.T) {
+	w, _, store := newTestWorker()
+	older := insertPending(store, validParams, 0, 0)
+	newer := insertPending(store, validParams, 0, time.Second)
+	urgent := insertPending(store, validParams, 5, 2*time.Second)
+
+	for _, want := range []uuid.UUID{urgent, older, newer} {
+		mustProcess(t, w, 1)
+		if row, _ := stored(store, want); row.Status != models.StatusCompleted {
+			t.Fatalf("request %s = %s, want it processed next", want, row.Status)
+		}
+	}
+	mustProcess(t, w, 0)
+}
@@ -3,7 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
-	"fmt"
+	"flag"
 	"log"
 	"net/http"
 	"os"
@@ -11,41 +11,62 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/KostySCH/Reports_go/pkg/appconfig"
+	"github.com/KostySCH/Reports_go/pkg/auth"
+	"github.com/KostySCH/Reports_go/pkg/health"
+	"github.com/KostySCH/Reports_go/pkg/migrations"
 	"github.com/KostySCH/Reports_go/reports_publisher/internal/config"
 	handler "github.com/KostySCH/Reports_go/reports_publisher/internal/handler"
+	"github.com/KostySCH/Reports_go/reports_publisher/internal/repository"
 	"github.com/KostySCH/Reports_go/reports_publisher/internal/service"
 	"github.com/KostySCH/Reports_go/reports_publisher/internal/worker"
+	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
 )
 
 func main() {
 	log.Println("Запуск приложения reports_publisher...")
 
+	configPath := appconfig.Flag(flag.CommandLine)
+	flag.Parse()
+	args := flag.Args()
+
 	// Загружаем конфигурацию
-	cfg, err := config.LoadConfig("../config/config.yaml")
+	cfg, source, err := config.LoadConfig(*configPath)
 	if err != nil {
 		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
 	}
-	log.Println("Конфигурация успешно загружена")
-
-	// Формируем строку подключения к БД
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Database.Host,
-		cfg.Database.Port,
-		cfg.Database.User,
-		cfg.Database.Password,
-		cfg.Database.DBName,
-		cfg.Database.SSLMode,
-	)
+	if len(args) > 0 && args[0] == appconfig.CommandName {
+		if err := appconfig.RunCommand(args[1:], cfg, source, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if source == "" {
+		log.Println("Файл конфигурации не найден, используются значения по умолчанию и переменные окружения")
+	} else {
+		log.Printf("Конфигурация загружена из %s", source)
+	}
 
 	// Инициализируем подключение к базе данных
-	db, err := sql.Open("postgres", dsn)
+	db, err := sql.Open("postgres", cfg.GetDSN())
 	if err != nil {
 		log.Fatalf("Ошибка подключения к базе данных: %v", err)
 	}
 	defer db.Close()
 	log.Println("Подключение к базе данных установлено")
 
+	// Подкоманда migrate управляет схемой и завершает работу
+	if len(args) > 0 && args[0] == migrations.CommandName {
+		if err := migrations.Run(context.Background(), db, args[1:], os.Stdout); err != nil {
+			log.Fatalf("Ошибка миграции: %v", err)
+		}
+		return
+	}
+	if err := migrations.EnsureUpToDate(context.Background(), db); err != nil {
+		log.Fatalf("Схема базы данных не соответствует версии сервиса: %v", err)
+	}
+
 	// Инициализируем MinIO сервис
 	minioSvc, err := service.NewMinioService(
 		cfg.Minio.Endpoint,
@@ -72,14 +93,28 @@ func main() {
 	docSvc := service.New(minioSvc, db)
 	log.Println("Сервис документов инициализирован")
 
+	// Инициализация аутентификации
+	authenticator, err := auth.New(cfg.Auth)
+	if err != nil {
+		log.Fatalf("Ошибка настройки аутентификации: %v", err)
+	}
+
 	// Инициализация HTTP сервера
-	handlers := handler.NewHandler(docSvc)
+	handlers := handler.NewHandler(docSvc, authenticator)
 	router := handlers.InitRoutes()
 	log.Println("Маршруты инициализированы")
 
+	// Проверки готовности
+	checker := health.New(5 * time.Second)
+	checker.Add("postgres", health.Ping(db))
+	checker.Add("minio", minioSvc.Ping)
+	checker.Add("kafka", kafkaSvc.Ping)
+	router.GET("/healthz", gin.WrapF(checker.Liveness))
+	router.GET("/readyz", gin.WrapF(checker.Readiness))
+
 	// Создаем HTTP сервер
 	httpServer := &http.Server{
-		Addr:    ":8082",
+		Addr:    cfg.Server.Addr,
 		Handler: router,
 	}
 
@@ -88,57 +123,56 @@ func main() {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	// Запускаем сервер в отдельной горутине
-	log.Printf("Запуск HTTP сервера на порту 8082...")
+	log.Printf("Запуск HTTP сервера на %s...", cfg.Server.Addr)
 	go func() {
 		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Ошибка запуска HTTP сервера: %v", err)
 		}
 	}()
 
-	// Даем серверу время на запуск
-	time.Sleep(2 * time.Second)
-
-	// Проверяем доступность сервера
-	log.Println("Проверка доступности сервера...")
-	resp, err := http.Get("http://localhost:8082/ping")
-	if err != nil {
-		log.Printf("Ошибка при проверке доступности сервера: %v", err)
-	} else {
-		defer resp.Body.Close()
-		log.Printf("Сервер отвечает, статус: %d", resp.StatusCode)
-	}
-
-	log.Println("Сервер запущен и готов к работе")
-
-	// Создаем и запускаем воркеры
+	// Создаем и запускаем воркеры. Отмена ctx останавливает выбор новой работы.
 	log.Println("Запуск воркеров...")
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	notificationWorker := worker.NewNotificationWorker(db, kafkaSvc)
-	go func() {
-		log.Println("Запуск воркера уведомлений...")
-		notificationWorker.Start(ctx)
-	}()
+	notificationWorker := worker.NewNotificationWorker(repository.NewNotificationRepository(db), kafkaSvc)
+	notificationWorker.Start(ctx)
 	log.Println("Воркер уведомлений запущен")
 
+	var webhookWorker *worker.WebhookWorker
+	if cfg.Webhooks.Enabled {
+		webhookSvc := service.NewWebhookService(cfg.Webhooks.Timeout, cfg.Webhooks.DefaultSecret, cfg.Webhooks.AllowPrivateNetworks)
+		webhookWorker = worker.NewWebhookWorker(db, webhookSvc, worker.WebhookOptions{
+			PollPeriod:      cfg.Webhooks.PollPeriod,
+			Timeout:         cfg.Webhooks.Timeout,
+			MaxAttempts:     cfg.Webhooks.MaxAttempts,
+			InitialBackoff:  cfg.Webhooks.InitialBackoff,
+			MaxBackoff:      cfg.Webhooks.MaxBackoff,
+			DownloadBaseURL: cfg.Webhooks.DownloadBaseURL,
+		})
+		webhookWorker.Start(ctx)
+		log.Println("Воркер вебхуков запущен")
+	}
+
 	log.Println("Приложение полностью инициализировано и готово к работе")
-	log.Println("Ожидание сигнала для завершения...")
 	<-sigChan
 
-	log.Println("Получен сигнал завершения, останавливаем сервер и воркеры...")
+	log.Println("Получен сигнал завершения, останавливаем воркеры и сервер...")
+	checker.Drain()
+	cancel()
 
 	// Создаем контекст с таймаутом для graceful shutdown
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer shutdownCancel()
 
-	// Останавливаем сервер
+	// Начатые отправки в Kafka и вебхуки доделываются до закрытия producer (defer kafkaSvc.Close)
+	notificationWorker.Shutdown(shutdownCtx)
+	if webhookWorker != nil {
+		webhookWorker.Shutdown(shutdownCtx)
+	}
+
 	if err := httpServer.Shutdown(shutdownCtx); err != nil {
 		log.Printf("Ошибка при остановке сервера: %v", err)
 	}
-
-	// Останавливаем воркеры
-	cancel()
-	time.Sleep(time.Second) // Даем время на graceful shutdown
 	log.Println("Приложение остановлено")
 }
@@ -1,91 +1,117 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"time"
 
-	"gopkg.in/yaml.v2"
+	"github.com/KostySCH/Reports_go/pkg/appconfig"
+	"github.com/KostySCH/Reports_go/pkg/auth"
 )
 
-type Config struct {
-	Database struct {
-		Host     string `yaml:"host"`
-		Port     int    `yaml:"port"`
-		User     string `yaml:"user"`
-		Password string `yaml:"password"`
-		DBName   string `yaml:"dbname"`
-		SSLMode  string `yaml:"sslmode"`
-	} `yaml:"database"`
+// EnvPrefix — префикс переменных окружения reports_publisher, например PUBLISHER_KAFKA_BROKERS
+const EnvPrefix = "PUBLISHER"
 
-	Minio struct {
-		Endpoint   string `yaml:"endpoint"`
-		AccessKey  string `yaml:"access_key"`
-		SecretKey  string `yaml:"secret_key"`
-		PDFBucket  string `yaml:"pdf_bucket"`
-		DOCXBucket string `yaml:"docx_bucket"`
-		UseSSL     bool   `yaml:"use_ssl"`
-	} `yaml:"minio"`
+type Config struct {
+	Database appconfig.Database `yaml:"database"`
+	Minio    appconfig.MinIO    `yaml:"minio"`
+	Kafka    Kafka              `yaml:"kafka"`
+	Webhooks Webhooks           `yaml:"webhooks"`
+	Server   Server             `yaml:"server"`
+	Auth     auth.Config        `yaml:"auth"`
+}
 
-	Kafka struct {
-		Brokers []string `yaml:"brokers"`
-		Topic   string   `yaml:"topic"`
-	} `yaml:"kafka"`
+type Kafka struct {
+	// Brokers в переменной окружения перечисляются через запятую
+	Brokers []string `yaml:"brokers"`
+	Topic   string   `yaml:"topic"`
 }
 
-func LoadConfig(configPath string) (*Config, error) {
-	config := &Config{}
+type Webhooks struct {
+	Enabled     bool          `yaml:"enabled"`
+	PollPeriod  time.Duration `yaml:"poll_period"`
+	Timeout     time.Duration `yaml:"timeout"`
+	MaxAttempts int           `yaml:"max_attempts"`
+	// InitialBackoff удваивается после каждой неудачной попытки, но не превышает MaxBackoff
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	// DownloadBaseURL — внешний адрес reports_publisher для ссылки на скачивание в вебхуке
+	DownloadBaseURL string `yaml:"download_base_url"`
+	// DefaultSecret подписывает вебхуки запросов без собственного callback_secret
+	DefaultSecret        string `yaml:"default_secret" secret:"true"`
+	AllowPrivateNetworks bool   `yaml:"allow_private_networks"`
+}
 
-	file, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка чтения файла конфигурации: %w", err)
-	}
+type Server struct {
+	Addr string `yaml:"addr"`
+	// ShutdownTimeout — сколько ждать завершения начатых отправок при остановке
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
 
-	err = yaml.Unmarshal(file, config)
+// LoadConfig читает конфигурацию из configPath (флаг --config) или из найденного config.yaml
+// и применяет переменные окружения PUBLISHER_*. Возвращает путь к прочитанному файлу.
+func LoadConfig(configPath string) (*Config, string, error) {
+	// Значения из файла перекрывают значения по умолчанию
+	config := getDefaultConfig()
+	source, err := appconfig.Load(config, appconfig.Options{EnvPrefix: EnvPrefix, Path: configPath})
 	if err != nil {
-		return nil, fmt.Errorf("ошибка разбора файла конфигурации: %w", err)
+		return nil, "", err
 	}
-
-	return config, nil
+	return config, source, nil
 }
 
 func getDefaultConfig() *Config {
 	return &Config{
-		Database: struct {
-			Host     string `yaml:"host"`
-			Port     int    `yaml:"port"`
-			User     string `yaml:"user"`
-			Password string `yaml:"password"`
-			DBName   string `yaml:"dbname"`
-			SSLMode  string `yaml:"sslmode"`
-		}{
-			Host:     "localhost",
-			Port:     5432,
-			User:     "postgres",
-			Password: "postgres",
-			DBName:   "reports_db",
-			SSLMode:  "disable",
-		},
-		Minio: struct {
-			Endpoint   string `yaml:"endpoint"`
-			AccessKey  string `yaml:"access_key"`
-			SecretKey  string `yaml:"secret_key"`
-			PDFBucket  string `yaml:"pdf_bucket"`
-			DOCXBucket string `yaml:"docx_bucket"`
-			UseSSL     bool   `yaml:"use_ssl"`
-		}{
-			Endpoint:   "localhost:9000",
-			AccessKey:  "minioadmin",
-			SecretKey:  "minioadmin",
-			PDFBucket:  "reports-pdf",
-			DOCXBucket: "reports-docx",
-			UseSSL:     false,
-		},
-		Kafka: struct {
-			Brokers []string `yaml:"brokers"`
-			Topic   string   `yaml:"topic"`
-		}{
+		Database: appconfig.DefaultDatabase(),
+		Minio:    appconfig.DefaultMinIO(),
+		Kafka: Kafka{
 			Brokers: []string{"localhost:9092"},
 			Topic:   "report-notifications",
 		},
+		Webhooks: Webhooks{
+			Enabled:         true,
+			PollPeriod:      5 * time.Second,
+			Timeout:         10 * time.Second,
+			MaxAttempts:     8,
+			InitialBackoff:  30 * time.Second,
+			MaxBackoff:      time.Hour,
+			DownloadBaseURL: "http://localhost:8082",
+		},
+		Server: Server{
+			Addr:            ":8082",
+			ShutdownTimeout: 30 * time.Second,
+		},
+	}
+}
+
+// Validate проверяет значения после применения переменных окружения
+func (c *Config) Validate() error {
+	errs := []error{
+		c.Database.Validate(),
+		c.Minio.Validate(),
+		appconfig.Required("kafka.topic", c.Kafka.Topic),
+		appconfig.Required("server.addr", c.Server.Addr),
+		appconfig.PositiveDuration("server.shutdown_timeout", c.Server.ShutdownTimeout),
 	}
+	if len(c.Kafka.Brokers) == 0 {
+		errs = append(errs, errors.New("kafka.brokers: нужен хотя бы один брокер"))
+	}
+	if c.Webhooks.Enabled {
+		errs = append(errs,
+			appconfig.PositiveDuration("webhooks.poll_period", c.Webhooks.PollPeriod),
+			appconfig.PositiveDuration("webhooks.timeout", c.Webhooks.Timeout),
+			appconfig.PositiveInt("webhooks.max_attempts", c.Webhooks.MaxAttempts),
+			appconfig.PositiveDuration("webhooks.initial_backoff", c.Webhooks.InitialBackoff),
+			appconfig.PositiveDuration("webhooks.max_backoff", c.Webhooks.MaxBackoff),
+			appconfig.Required("webhooks.download_base_url", c.Webhooks.DownloadBaseURL),
+		)
+		if c.Webhooks.MaxBackoff < c.Webhooks.InitialBackoff {
+			errs = append(errs, fmt.Errorf("webhooks.max_backoff: должно быть не меньше initial_backoff (%s)", c.Webhooks.InitialBackoff))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Config) GetDSN() string {
+	return c.Database.DSN()
 }
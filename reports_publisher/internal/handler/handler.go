@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -9,19 +10,24 @@ import (
 	"strings"
 	"time"
 
+	"github.com/KostySCH/Reports_go/pkg/auth"
 	"github.com/KostySCH/Reports_go/reports_publisher/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
 type Handler struct {
-	services *service.DocumentService
+	services      *service.DocumentService
+	authenticator *auth.Authenticator
 }
 
-func NewHandler(services *service.DocumentService) *Handler {
+func NewHandler(services *service.DocumentService, authenticator *auth.Authenticator) *Handler {
 	if services == nil {
 		log.Fatal("DocumentService не может быть nil")
 	}
-	return &Handler{services: services}
+	if authenticator == nil {
+		log.Fatal("Authenticator не может быть nil")
+	}
+	return &Handler{services: services, authenticator: authenticator}
 }
 
 func (h *Handler) InitRoutes() *gin.Engine {
@@ -76,8 +82,11 @@ func (h *Handler) InitRoutes() *gin.Engine {
 	log.Println("Создание групп маршрутов...")
 
 	api := router.Group("/api/v1")
+	api.Use(h.authenticate)
 	{
+		// Документы в бакетах не привязаны к владельцу, поэтому доступны только администраторам
 		documents := api.Group("/documents")
+		documents.Use(h.requireAdmin)
 		{
 			log.Println("Регистрация маршрутов для документов...")
 			documents.GET("/pdf", h.getPDFDocuments)
@@ -96,6 +105,29 @@ func (h *Handler) InitRoutes() *gin.Engine {
 	return router
 }
 
+// authenticate пропускает только запросы с действительным JWT или API-ключом
+func (h *Handler) authenticate(c *gin.Context) {
+	identity, err := h.authenticator.Authenticate(c.Request)
+	if err != nil {
+		log.Printf("Ошибка аутентификации: %v", err)
+		c.Header("WWW-Authenticate", `Bearer realm="reports"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Требуется аутентификация"})
+		return
+	}
+
+	c.Request = c.Request.WithContext(auth.WithIdentity(c.Request.Context(), identity))
+	c.Next()
+}
+
+// requireAdmin пропускает только пользователей с ролью администратора
+func (h *Handler) requireAdmin(c *gin.Context) {
+	if !auth.FromContext(c.Request.Context()).IsAdmin() {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Доступ запрещен"})
+		return
+	}
+	c.Next()
+}
+
 func (h *Handler) getPDFDocuments(c *gin.Context) {
 	docs, err := h.services.GetAvailablePDFs(c.Request.Context())
 	if err != nil {
@@ -136,6 +168,23 @@ func (h *Handler) downloadReportByUUID(c *gin.Context) {
 		return
 	}
 
+	// Скачать отчет может только заказавший его пользователь или администратор
+	ownerID, err := h.services.GetReportOwner(c.Request.Context(), uuid)
+	if errors.Is(err, service.ErrReportNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Отчет не найден"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения информации об отчете"})
+		return
+	}
+	identity := auth.FromContext(c.Request.Context())
+	if !identity.CanAccess(ownerID) {
+		log.Printf("Пользователь %s пытался скачать чужой отчет %s", identity, uuid)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Доступ запрещен"})
+		return
+	}
+
 	reader, err := h.services.GetReport(c.Request.Context(), uuid)
 	if err != nil {
 		log.Printf("Ошибка получения отчета: %v", err)
@@ -0,0 +1,41 @@
+// Package memtest содержит замены внешних зависимостей reports_publisher для тестов.
+// Пакет импортируется только из _test.go.
+package memtest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/KostySCH/Reports_go/reports_publisher/internal/service"
+)
+
+// Sender — замена service.KafkaService, которая запоминает уведомления вместо отправки
+type Sender struct {
+	mu   sync.Mutex
+	sent []service.ReportNotification
+	err  error
+}
+
+// FailWith заставляет следующие отправки завершаться ошибкой err; nil возвращает успешную отправку
+func (s *Sender) FailWith(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+func (s *Sender) SendNotification(ctx context.Context, notification service.ReportNotification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, notification)
+	return nil
+}
+
+// Sent возвращает отправленные уведомления в порядке отправки
+func (s *Sender) Sent() []service.ReportNotification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]service.ReportNotification(nil), s.sent...)
+}
@@ -0,0 +1,57 @@
+// Package memory реализует репозиторий уведомлений поверх memstore.Store — для тестов без Postgres.
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/KostySCH/Reports_go/pkg/memstore"
+	"github.com/KostySCH/Reports_go/reports_publisher/internal/repository"
+	"github.com/KostySCH/Reports_go/reports_publisher/internal/service"
+)
+
+type NotificationRepository struct {
+	store *memstore.Store
+}
+
+func NewNotificationRepository(store *memstore.Store) *NotificationRepository {
+	return &NotificationRepository{store: store}
+}
+
+// DeliverPending повторяет repository.NotificationRepository.DeliverPending. send вызывается
+// под блокировкой хранилища и не должен обращаться к нему.
+func (r *NotificationRepository) DeliverPending(ctx context.Context, limit int, actor string, send func(service.ReportNotification) error) (int, error) {
+	sent := 0
+	err := r.store.Tx(func(tx *memstore.Tx) error {
+		rows := tx.Select(func(row *memstore.Request) bool {
+			switch row.Status {
+			case "COMPLETED", "FAILED", "CANCELLED":
+				return !row.NotificationSent
+			}
+			return false
+		}, func(a, b *memstore.Request) bool {
+			return a.UpdatedAt.Before(b.UpdatedAt)
+		})
+
+		for i := 0; i < len(rows) && i < limit; i++ {
+			row := rows[i]
+			n := service.ReportNotification{
+				ReportID: row.ID.String(),
+				UserID:   fmt.Sprint(row.UserID),
+				Status:   row.Status,
+			}
+			if row.Error != nil {
+				n.Error = *row.Error
+			}
+			if err := send(n); err != nil {
+				continue
+			}
+
+			row.NotificationSent = true
+			tx.Record(repository.NotificationSentEvent(row.ID, row.Status, actor))
+			sent++
+		}
+		return nil
+	})
+	return sent, err
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/KostySCH/Reports_go/pkg/audit"
+	"github.com/KostySCH/Reports_go/reports_publisher/internal/service"
+	"github.com/google/uuid"
+)
+
+// NotificationRepository выбирает завершенные запросы, о которых еще не отправлено уведомление
+type NotificationRepository struct {
+	db *sql.DB
+}
+
+func NewNotificationRepository(db *sql.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// DeliverPending блокирует до limit завершенных запросов без уведомления и передает каждый в send.
+// Если send вернул nil, запрос помечается отправленным, а отправка записывается в историю от имени actor.
+// Возвращает число отправленных уведомлений.
+func (r *NotificationRepository) DeliverPending(ctx context.Context, limit int, actor string, send func(service.ReportNotification) error) (int, error) {
+	if err := r.db.PingContext(ctx); err != nil {
+		return 0, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT id, status, error, user_id
+		FROM reporting.report_requests
+		WHERE status IN ($1, $2, $3)
+		AND notification_sent = false
+		ORDER BY updated_at ASC
+		LIMIT $4
+		FOR UPDATE SKIP LOCKED
+	`
+	rows, err := tx.QueryContext(ctx, query, "COMPLETED", "FAILED", "CANCELLED", limit)
+	if err != nil {
+		return 0, err
+	}
+
+	var notifications []service.ReportNotification
+	for rows.Next() {
+		var (
+			n         service.ReportNotification
+			reportErr sql.NullString
+		)
+		if err := rows.Scan(&n.ReportID, &n.Status, &reportErr, &n.UserID); err != nil {
+			continue
+		}
+		n.Error = reportErr.String
+		notifications = append(notifications, n)
+	}
+
+	if err = rows.Err(); err != nil {
+		rows.Close()
+		return 0, err
+	}
+	rows.Close()
+
+	if len(notifications) == 0 {
+		return 0, nil
+	}
+
+	sent := 0
+	for _, n := range notifications {
+		if err := send(n); err != nil {
+			continue
+		}
+
+		updateQuery := `
+			UPDATE reporting.report_requests
+			SET notification_sent = true
+			WHERE id = $1
+		`
+		if _, err := tx.ExecContext(ctx, updateQuery, n.ReportID); err != nil {
+			return sent, err
+		}
+
+		requestID, err := uuid.Parse(n.ReportID)
+		if err != nil {
+			return sent, err
+		}
+		if err := audit.Record(ctx, tx, NotificationSentEvent(requestID, n.Status, actor)); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+
+	return sent, tx.Commit()
+}
+
+// NotificationSentEvent описывает отправку уведомления в истории запроса
+func NotificationSentEvent(requestID uuid.UUID, status, actor string) audit.Event {
+	return audit.Event{
+		RequestID: requestID,
+		Type:      audit.EventNotificationSent,
+		Actor:     actor,
+		Source:    audit.SourcePublisher,
+		Details:   map[string]interface{}{"channel": "kafka", "status": status},
+	}
+}
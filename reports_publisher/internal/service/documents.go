@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -12,6 +13,9 @@ import (
 	"github.com/KostySCH/Reports_go/reports_publisher/pkg/types"
 )
 
+// ErrReportNotFound возвращается, если запрос на отчет не найден
+var ErrReportNotFound = errors.New("отчет не найден")
+
 type DocumentService struct {
 	minioSvc *MinioService
 	db       *sql.DB
@@ -38,6 +42,24 @@ func (s *DocumentService) GetReportPath(ctx context.Context, reportID string, re
 	return nil
 }
 
+// GetReportOwner возвращает идентификатор пользователя, заказавшего отчет
+func (s *DocumentService) GetReportOwner(ctx context.Context, reportID string) (int, error) {
+	var userID int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT user_id
+		FROM reporting.report_requests
+		WHERE id = $1
+	`, reportID).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrReportNotFound
+	}
+	if err != nil {
+		log.Printf("Ошибка получения владельца отчета из БД: %v", err)
+		return 0, fmt.Errorf("ошибка получения владельца отчета: %v", err)
+	}
+	return userID, nil
+}
+
 func (s *DocumentService) GetReport(ctx context.Context, reportID string) (io.Reader, error) {
 	// Получаем путь к файлу из базы данных
 	var reportPath string
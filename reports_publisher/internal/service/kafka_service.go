@@ -10,10 +10,13 @@ import (
 )
 
 type KafkaService struct {
+	client   sarama.Client
 	producer sarama.SyncProducer
 	topic    string
 }
 
+// ReportNotification уведомление о завершении обработки отчета.
+// Status принимает значения COMPLETED, FAILED или CANCELLED.
 type ReportNotification struct {
 	ReportID string `json:"report_id"`
 	UserID   string `json:"user_id"`
@@ -29,12 +32,19 @@ func NewKafkaService(cfg *config.Config) (*KafkaService, error) {
 	config.Producer.Compression = sarama.CompressionSnappy
 	config.Producer.MaxMessageBytes = 1000000
 
-	producer, err := sarama.NewSyncProducer(cfg.Kafka.Brokers, config)
+	client, err := sarama.NewClient(cfg.Kafka.Brokers, config)
 	if err != nil {
+		return nil, fmt.Errorf("ошибка подключения к Kafka: %w", err)
+	}
+
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		client.Close()
 		return nil, fmt.Errorf("ошибка создания Kafka producer: %w", err)
 	}
 
 	return &KafkaService{
+		client:   client,
 		producer: producer,
 		topic:    cfg.Kafka.Topic,
 	}, nil
@@ -64,6 +74,29 @@ func (s *KafkaService) SendNotification(ctx context.Context, notification Report
 	}
 }
 
+// Ping проверяет, что брокеры Kafka доступны и знают топик уведомлений
+func (s *KafkaService) Ping(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- s.client.RefreshMetadata(s.topic)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("Kafka не ответила: %w", ctx.Err())
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("ошибка получения метаданных Kafka: %w", err)
+		}
+		return nil
+	}
+}
+
+// Close закрывает producer и клиент Kafka; вызывается после остановки воркеров уведомлений
 func (s *KafkaService) Close() error {
-	return s.producer.Close()
+	if err := s.producer.Close(); err != nil {
+		s.client.Close()
+		return err
+	}
+	return s.client.Close()
 }
@@ -115,3 +115,17 @@ func (s *MinioService) GetPresignedURL(ctx context.Context, filePath string, con
 
 	return url.String(), nil
 }
+
+// Ping проверяет, что MinIO доступен и бакеты отчетов существуют
+func (s *MinioService) Ping(ctx context.Context) error {
+	for _, bucket := range []string{s.pdfBucket, s.docxBucket} {
+		exists, err := s.client.BucketExists(ctx, bucket)
+		if err != nil {
+			return fmt.Errorf("ошибка проверки бакета %s: %w", bucket, err)
+		}
+		if !exists {
+			return fmt.Errorf("бакет %s не найден", bucket)
+		}
+	}
+	return nil
+}
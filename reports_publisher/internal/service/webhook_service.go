@@ -0,0 +1,139 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Заголовки вебхука
+const (
+	WebhookSignatureHeader = "X-Reports-Signature"
+	WebhookTimestampHeader = "X-Reports-Timestamp"
+	WebhookDeliveryHeader  = "X-Reports-Delivery"
+	WebhookEventHeader     = "X-Reports-Event"
+)
+
+// WebhookPayload тело вебхука о завершении обработки отчета.
+// Status принимает значения COMPLETED или FAILED.
+type WebhookPayload struct {
+	ID          string    `json:"id"`
+	Event       string    `json:"event"`
+	Type        string    `json:"type"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	DownloadURL string    `json:"download_url,omitempty"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+// WebhookEvent возвращает имя события для статуса отчета
+func WebhookEvent(status string) string {
+	if status == "COMPLETED" {
+		return "report.completed"
+	}
+	return "report.failed"
+}
+
+// SignWebhook вычисляет подпись sha256=<hex HMAC-SHA256(secret, timestamp + "." + body)>.
+// Получатель проверяет подпись и отбрасывает вебхуки со старым timestamp, защищаясь от повторов.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type WebhookService struct {
+	client        *http.Client
+	defaultSecret string
+}
+
+// NewWebhookService создает отправителя вебхуков. Без allowPrivateNetworks запросы на адреса
+// loopback, частных и link-local сетей запрещены, чтобы callback_url нельзя было направить во внутреннюю сеть.
+func NewWebhookService(timeout time.Duration, defaultSecret string, allowPrivateNetworks bool) *WebhookService {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivateNetworks {
+		dialer.Control = denyPrivateNetworks
+	}
+
+	return &WebhookService{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+			// Перенаправления не выполняем: ответ 3xx считается неудачной доставкой
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		defaultSecret: defaultSecret,
+	}
+}
+
+// Send отправляет вебхук и возвращает HTTP-статус ответа. Ошибка возвращается,
+// если запрос не удалось выполнить или получатель ответил не 2xx.
+func (s *WebhookService) Send(ctx context.Context, deliveryID, callbackURL, secret string, payload WebhookPayload) (int, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка сериализации вебхука: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "reports-publisher-webhook/1.0")
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookDeliveryHeader, deliveryID)
+	req.Header.Set(WebhookEventHeader, payload.Event)
+	if secret == "" {
+		secret = s.defaultSecret
+	}
+	if secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(secret, timestamp, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка отправки вебхука: %w", err)
+	}
+	defer resp.Body.Close()
+	// Дочитываем немного тела, чтобы соединение можно было переиспользовать
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("получатель ответил статусом %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// denyPrivateNetworks запрещает соединения с внутренними адресами; проверяется уже разрешенный IP,
+// поэтому DNS-имя, указывающее во внутреннюю сеть, тоже будет отклонено
+func denyPrivateNetworks(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("некорректный адрес %s", address)
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("адрес %s находится во внутренней сети", ip)
+	}
+	return nil
+}
@@ -2,32 +2,58 @@ package worker
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"time"
 
+	"github.com/KostySCH/Reports_go/pkg/audit"
+	"github.com/KostySCH/Reports_go/pkg/shutdown"
+	"github.com/KostySCH/Reports_go/reports_publisher/internal/repository"
 	"github.com/KostySCH/Reports_go/reports_publisher/internal/service"
 )
 
 const (
 	workerType = "Уведомления"
+
+	// notificationBatchSize — сколько уведомлений отправляет одна горутина за проход
+	notificationBatchSize = 10
+)
+
+// NotificationStore выбирает запросы для уведомления и отмечает отправленные.
+// Реализации: repository.NotificationRepository (Postgres) и memory.NotificationRepository.
+type NotificationStore interface {
+	DeliverPending(ctx context.Context, limit int, actor string, send func(service.ReportNotification) error) (int, error)
+}
+
+// NotificationSender доставляет уведомление пользователю, например service.KafkaService
+type NotificationSender interface {
+	SendNotification(ctx context.Context, notification service.ReportNotification) error
+}
+
+var (
+	_ NotificationStore  = (*repository.NotificationRepository)(nil)
+	_ NotificationSender = (*service.KafkaService)(nil)
 )
 
 type NotificationWorker struct {
-	db          *sql.DB
-	kafkaSvc    *service.KafkaService
+	store       NotificationStore
+	sender      NotificationSender
 	pollPeriod  time.Duration
 	workerID    int
 	concurrency int
+	actor       string
+	// drain дожидается начатых проходов, чтобы отправленные уведомления успели отметиться
+	drain *shutdown.Drain
 }
 
-func NewNotificationWorker(db *sql.DB, kafkaSvc *service.KafkaService) *NotificationWorker {
+func NewNotificationWorker(store NotificationStore, sender NotificationSender) *NotificationWorker {
 	return &NotificationWorker{
-		db:          db,
-		kafkaSvc:    kafkaSvc,
+		store:       store,
+		sender:      sender,
 		pollPeriod:  5 * time.Second,
 		workerID:    3,
 		concurrency: 10,
+		actor:       audit.WorkerActor("publisher-notification-3"),
+		drain:       shutdown.New(),
 	}
 }
 
@@ -35,11 +61,18 @@ func (w *NotificationWorker) Start(ctx context.Context) {
 	fmt.Printf("[Воркер %d - %s] Запуск\n", w.workerID, workerType)
 
 	for i := 0; i < w.concurrency; i++ {
-		go w.processNotifications(ctx)
+		w.drain.Go(func() { w.processNotifications(ctx) })
 	}
+}
 
-	<-ctx.Done()
+// Shutdown ждет завершения проходов, начатых до отмены контекста Start
+func (w *NotificationWorker) Shutdown(ctx context.Context) error {
 	fmt.Printf("[Воркер %d - %s] Остановка\n", w.workerID, workerType)
+	if err := w.drain.Wait(ctx); err != nil {
+		fmt.Printf("[Воркер %d - %s] Не все уведомления отправлены до остановки: %v\n", w.workerID, workerType, err)
+		return err
+	}
+	return nil
 }
 
 func (w *NotificationWorker) processNotifications(ctx context.Context) {
@@ -51,96 +84,22 @@ func (w *NotificationWorker) processNotifications(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			if err := w.db.PingContext(ctx); err != nil {
+			if _, err := w.DeliverPending(w.drain.Context()); err != nil {
 				fmt.Printf("[Воркер %d - %s] Ошибка БД: %v\n", w.workerID, workerType, err)
 				time.Sleep(time.Second)
-				continue
-			}
-
-			tx, err := w.db.BeginTx(ctx, &sql.TxOptions{
-				Isolation: sql.LevelReadCommitted,
-			})
-			if err != nil {
-				continue
-			}
-
-			query := `
-				SELECT id, status, error, user_id
-				FROM reporting.report_requests
-				WHERE status IN ($1, $2)
-				AND notification_sent = false
-				ORDER BY updated_at ASC
-				LIMIT 10
-				FOR UPDATE SKIP LOCKED
-			`
-			rows, err := tx.QueryContext(ctx, query, "COMPLETED", "FAILED")
-			if err != nil {
-				tx.Rollback()
-				continue
-			}
-
-			var notifications []struct {
-				id     string
-				userID string
-				status string
-				error  sql.NullString
-			}
-
-			for rows.Next() {
-				var n struct {
-					id     string
-					userID string
-					status string
-					error  sql.NullString
-				}
-				if err := rows.Scan(&n.id, &n.status, &n.error, &n.userID); err != nil {
-					continue
-				}
-				notifications = append(notifications, n)
-			}
-
-			if err = rows.Err(); err != nil {
-				rows.Close()
-				tx.Rollback()
-				continue
-			}
-			rows.Close()
-
-			if len(notifications) == 0 {
-				tx.Rollback()
-				continue
-			}
-
-			for _, n := range notifications {
-				notification := service.ReportNotification{
-					ReportID: n.id,
-					UserID:   n.userID,
-					Status:   n.status,
-				}
-				if n.error.Valid {
-					notification.Error = n.error.String
-				}
-
-				if err := w.kafkaSvc.SendNotification(ctx, notification); err != nil {
-					fmt.Printf("[Воркер %d - %s] Ошибка отправки уведомления: %v\n", w.workerID, workerType, err)
-					continue
-				}
-
-				updateQuery := `
-					UPDATE reporting.report_requests
-					SET notification_sent = true
-					WHERE id = $1
-				`
-				if _, err := tx.ExecContext(ctx, updateQuery, n.id); err != nil {
-					fmt.Printf("[Воркер %d - %s] Ошибка обновления статуса: %v\n", w.workerID, workerType, err)
-					continue
-				}
-			}
-
-			if err := tx.Commit(); err != nil {
-				fmt.Printf("[Воркер %d - %s] Ошибка коммита: %v\n", w.workerID, workerType, err)
-				continue
 			}
 		}
 	}
 }
+
+// DeliverPending выполняет один проход: отправляет уведомления о завершенных запросах
+// и возвращает число отправленных
+func (w *NotificationWorker) DeliverPending(ctx context.Context) (int, error) {
+	return w.store.DeliverPending(ctx, notificationBatchSize, w.actor, func(n service.ReportNotification) error {
+		if err := w.sender.SendNotification(ctx, n); err != nil {
+			fmt.Printf("[Воркер %d - %s] Ошибка отправки уведомления: %v\n", w.workerID, workerType, err)
+			return err
+		}
+		return nil
+	})
+}
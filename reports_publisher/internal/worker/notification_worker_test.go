@@ -0,0 +1,115 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KostySCH/Reports_go/pkg/memstore"
+	"github.com/KostySCH/Reports_go/reports_publisher/internal/memtest"
+	"github.com/KostySCH/Reports_go/reports_publisher/internal/repository/memory"
+	"github.com/google/uuid"
+)
+
+var finished = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestWorker() (*NotificationWorker, *memtest.Sender, *memstore.Store) {
+	store := memstore.New()
+	sender := &memtest.Sender{}
+	return NewNotificationWorker(memory.NewNotificationRepository(store), sender), sender, store
+}
+
+// insertRequest добавляет запрос пользователя 7, последний раз измененный через age после finished
+func insertRequest(store *memstore.Store, status string, errorMsg string, age time.Duration) uuid.UUID {
+	id := uuid.New()
+	store.Tx(func(tx *memstore.Tx) error {
+		row := &memstore.Request{
+			ID:        id,
+			UserID:    7,
+			Type:      "branch_performance_report",
+			Status:    status,
+			CreatedAt: finished,
+			UpdatedAt: finished.Add(age),
+		}
+		if errorMsg != "" {
+			row.Error = &errorMsg
+		}
+		tx.Insert(row)
+		return nil
+	})
+	return id
+}
+
+func deliverPending(t *testing.T, w *NotificationWorker, want int) {
+	t.Helper()
+	sent, err := w.DeliverPending(context.Background())
+	if err != nil {
+		t.Fatalf("DeliverPending: %v", err)
+	}
+	if sent != want {
+		t.Fatalf("DeliverPending sent %d notifications, want %d", sent, want)
+	}
+}
+
+func notifications(sender *memtest.Sender) []string {
+	var lines []string
+	for _, n := range sender.Sent() {
+		lines = append(lines, fmt.Sprintf("%s %s %s %s", n.ReportID, n.UserID, n.Status, n.Error))
+	}
+	return lines
+}
+
+func TestDeliverPendingNotifiesFinishedRequestsOnce(t *testing.T) {
+	w, sender, store := newTestWorker()
+	failed := insertRequest(store, "FAILED", "minio недоступен", time.Second)
+	completed := insertRequest(store, "COMPLETED", "", 0)
+	insertRequest(store, "PENDING", "", 0)
+	insertRequest(store, "IN_PROGRESS", "", 0)
+
+	deliverPending(t, w, 2)
+	deliverPending(t, w, 0)
+
+	want := []string{
+		completed.String() + " 7 COMPLETED ",
+		failed.String() + " 7 FAILED minio недоступен",
+	}
+	if got := notifications(sender); !reflect.DeepEqual(got, want) {
+		t.Errorf("notifications = %q, want %q", got, want)
+	}
+
+	store.Tx(func(tx *memstore.Tx) error {
+		for _, id := range []uuid.UUID{completed, failed} {
+			if !tx.Get(id).NotificationSent {
+				t.Errorf("request %s is not marked as notified", id)
+			}
+			events := tx.Events(id)
+			if len(events) != 1 || events[0].Type != "NOTIFICATION_SENT" || events[0].Actor != w.actor {
+				t.Errorf("request %s history = %+v, want one NOTIFICATION_SENT by %s", id, events, w.actor)
+			}
+		}
+		return nil
+	})
+}
+
+func TestDeliverPendingRetriesFailedSend(t *testing.T) {
+	w, sender, store := newTestWorker()
+	id := insertRequest(store, "COMPLETED", "", 0)
+
+	sender.FailWith(errors.New("kafka недоступна"))
+	deliverPending(t, w, 0)
+	store.Tx(func(tx *memstore.Tx) error {
+		if tx.Get(id).NotificationSent || len(tx.Events(id)) != 0 {
+			t.Error("request is marked as notified after a failed send")
+		}
+		return nil
+	})
+
+	sender.FailWith(nil)
+	deliverPending(t, w, 1)
+	if got := len(sender.Sent()); got != 1 {
+		t.Errorf("sent %d notifications, want 1", got)
+	}
+}
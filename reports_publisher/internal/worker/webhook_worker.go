@@ -0,0 +1,301 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/KostySCH/Reports_go/pkg/audit"
+	"github.com/KostySCH/Reports_go/pkg/shutdown"
+	"github.com/KostySCH/Reports_go/reports_publisher/internal/service"
+	"github.com/google/uuid"
+)
+
+const (
+	webhookWorkerType = "Вебхуки"
+
+	webhookStatePending   = "PENDING"
+	webhookStateDelivered = "DELIVERED"
+	webhookStateFailed    = "FAILED"
+
+	// webhookBatchSize — сколько вебхуков обрабатывается за один проход
+	webhookBatchSize = 10
+	// maxWebhookErrorLength ограничивает длину сохраняемой ошибки доставки
+	maxWebhookErrorLength = 1000
+)
+
+// WebhookOptions задает расписание повторов доставки вебхуков
+type WebhookOptions struct {
+	PollPeriod      time.Duration
+	Timeout         time.Duration
+	MaxAttempts     int
+	InitialBackoff  time.Duration
+	MaxBackoff      time.Duration
+	DownloadBaseURL string
+}
+
+// WebhookWorker отправляет вебхуки для запросов с callback_url, перешедших в COMPLETED или FAILED.
+// Для каждого такого перехода в reporting.webhook_deliveries создается доставка, а каждая попытка
+// записывается в reporting.webhook_delivery_attempts.
+type WebhookWorker struct {
+	db       *sql.DB
+	svc      *service.WebhookService
+	opts     WebhookOptions
+	workerID int
+	actor    string
+	drain    *shutdown.Drain
+}
+
+type webhookDelivery struct {
+	id             string
+	requestID      string
+	status         string
+	attempts       int
+	reportType     string
+	reportError    sql.NullString
+	callbackURL    string
+	callbackSecret sql.NullString
+}
+
+func NewWebhookWorker(db *sql.DB, svc *service.WebhookService, opts WebhookOptions) *WebhookWorker {
+	opts.DownloadBaseURL = strings.TrimRight(opts.DownloadBaseURL, "/")
+	return &WebhookWorker{
+		db:       db,
+		svc:      svc,
+		opts:     opts,
+		workerID: 4,
+		actor:    audit.WorkerActor("publisher-webhook-4"),
+		drain:    shutdown.New(),
+	}
+}
+
+func (w *WebhookWorker) Start(ctx context.Context) {
+	fmt.Printf("[Воркер %d - %s] Запуск\n", w.workerID, webhookWorkerType)
+	w.drain.Go(func() { w.run(ctx) })
+}
+
+// Shutdown ждет, пока завершится проход, начатый до отмены контекста Start, и сохранятся его попытки доставки
+func (w *WebhookWorker) Shutdown(ctx context.Context) error {
+	fmt.Printf("[Воркер %d - %s] Остановка\n", w.workerID, webhookWorkerType)
+	if err := w.drain.Wait(ctx); err != nil {
+		fmt.Printf("[Воркер %d - %s] Не все вебхуки доставлены до остановки: %v\n", w.workerID, webhookWorkerType, err)
+		return err
+	}
+	return nil
+}
+
+// run опрашивает очередь доставок, пока не отменен ctx. Сам проход выполняется в контексте drain,
+// чтобы начатые отправки и запись их результатов не обрывались сигналом остановки.
+func (w *WebhookWorker) run(ctx context.Context) {
+	ticker := time.NewTicker(w.opts.PollPeriod)
+	defer ticker.Stop()
+
+	work := w.drain.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.enqueue(work); err != nil {
+				fmt.Printf("[Воркер %d - %s] Ошибка постановки вебхуков в очередь: %v\n", w.workerID, webhookWorkerType, err)
+			}
+
+			deliveries, err := w.claim(work)
+			if err != nil {
+				fmt.Printf("[Воркер %d - %s] Ошибка получения вебхуков: %v\n", w.workerID, webhookWorkerType, err)
+				continue
+			}
+			for _, d := range deliveries {
+				w.deliver(work, d)
+			}
+		}
+	}
+}
+
+// enqueue создает доставки для запросов, чей финальный статус еще не был поставлен в очередь.
+// webhook_status запоминает статус, для которого доставка уже создана, поэтому после повторной
+// генерации (FAILED -> COMPLETED) будет отправлен новый вебхук.
+func (w *WebhookWorker) enqueue(ctx context.Context) error {
+	query := `
+		WITH due AS (
+			SELECT id, status
+			FROM reporting.report_requests
+			WHERE callback_url IS NOT NULL
+			AND status IN ($1, $2)
+			AND webhook_status IS DISTINCT FROM status
+			LIMIT 100
+			FOR UPDATE SKIP LOCKED
+		), marked AS (
+			UPDATE reporting.report_requests r
+			SET webhook_status = due.status
+			FROM due
+			WHERE r.id = due.id
+			RETURNING r.id, due.status
+		)
+		INSERT INTO reporting.webhook_deliveries (id, request_id, status, state, attempts, next_attempt_at, created_at, updated_at)
+		SELECT gen_random_uuid(), id, status, $3, 0, now(), now(), now()
+		FROM marked
+	`
+	_, err := w.db.ExecContext(ctx, query, "COMPLETED", "FAILED", webhookStatePending)
+	return err
+}
+
+// claim выбирает доставки, время которых пришло, и сдвигает их следующую попытку на время отправки,
+// чтобы другой экземпляр не отправил тот же вебхук параллельно
+func (w *WebhookWorker) claim(ctx context.Context) ([]webhookDelivery, error) {
+	query := `
+		UPDATE reporting.webhook_deliveries d
+		SET next_attempt_at = now() + make_interval(secs => $1), updated_at = now()
+		FROM reporting.report_requests r
+		WHERE r.id = d.request_id
+		AND d.id IN (
+			SELECT id
+			FROM reporting.webhook_deliveries
+			WHERE state = $2 AND next_attempt_at <= now()
+			ORDER BY next_attempt_at ASC
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.id, d.request_id, d.status, d.attempts, r.type, r.error, r.callback_url, r.callback_secret
+	`
+	lease := (2 * w.opts.Timeout).Seconds()
+	rows, err := w.db.QueryContext(ctx, query, lease, webhookStatePending, webhookBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []webhookDelivery
+	for rows.Next() {
+		var d webhookDelivery
+		err := rows.Scan(&d.id, &d.requestID, &d.status, &d.attempts, &d.reportType,
+			&d.reportError, &d.callbackURL, &d.callbackSecret)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (w *WebhookWorker) deliver(ctx context.Context, d webhookDelivery) {
+	payload := service.WebhookPayload{
+		ID:         d.requestID,
+		Event:      service.WebhookEvent(d.status),
+		Type:       d.reportType,
+		Status:     d.status,
+		OccurredAt: time.Now().UTC(),
+	}
+	if d.status == "COMPLETED" {
+		payload.DownloadURL = fmt.Sprintf("%s/api/v1/reports/%s/download", w.opts.DownloadBaseURL, d.requestID)
+	} else if d.reportError.Valid {
+		payload.Error = d.reportError.String
+	}
+
+	started := time.Now()
+	statusCode, sendErr := w.svc.Send(ctx, d.id, d.callbackURL, d.callbackSecret.String, payload)
+	duration := time.Since(started)
+
+	attempt := d.attempts + 1
+	state := webhookStateDelivered
+	nextAttemptAt := sql.NullTime{}
+	if sendErr != nil {
+		state = webhookStatePending
+		nextAttemptAt = sql.NullTime{Time: time.Now().Add(w.backoff(attempt)), Valid: true}
+		if attempt >= w.opts.MaxAttempts {
+			state = webhookStateFailed
+			nextAttemptAt = sql.NullTime{}
+		}
+		fmt.Printf("[Воркер %d - %s] Попытка %d доставки вебхука %s для отчета %s не удалась: %v\n",
+			w.workerID, webhookWorkerType, attempt, d.id, d.requestID, sendErr)
+	}
+
+	if err := w.record(ctx, d, attempt, state, statusCode, sendErr, duration, nextAttemptAt); err != nil {
+		fmt.Printf("[Воркер %d - %s] Ошибка сохранения попытки доставки %s: %v\n", w.workerID, webhookWorkerType, d.id, err)
+	}
+}
+
+// record сохраняет попытку доставки, новое состояние доставки и событие в истории запроса в одной транзакции
+func (w *WebhookWorker) record(ctx context.Context, d webhookDelivery, attempt int, state string, statusCode int,
+	sendErr error, duration time.Duration, nextAttemptAt sql.NullTime) error {
+	requestID, err := uuid.Parse(d.requestID)
+	if err != nil {
+		return err
+	}
+
+	var errText sql.NullString
+	if sendErr != nil {
+		message := sendErr.Error()
+		if len(message) > maxWebhookErrorLength {
+			message = message[:maxWebhookErrorLength]
+		}
+		errText = sql.NullString{String: message, Valid: true}
+	}
+	code := sql.NullInt64{Int64: int64(statusCode), Valid: statusCode != 0}
+
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO reporting.webhook_delivery_attempts (delivery_id, attempt, status_code, error, duration_ms, attempted_at)
+		VALUES ($1, $2, $3, $4, $5, now())
+	`, d.id, attempt, code, errText, duration.Milliseconds())
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE reporting.webhook_deliveries
+		SET state = $2, attempts = $3, last_status_code = $4, last_error = $5,
+			next_attempt_at = COALESCE($6, next_attempt_at),
+			delivered_at = CASE WHEN $2 = $7 THEN now() ELSE NULL END,
+			updated_at = now()
+		WHERE id = $1
+	`, d.id, state, attempt, code, errText, nextAttemptAt, webhookStateDelivered)
+	if err != nil {
+		return err
+	}
+
+	event := audit.Event{
+		RequestID: requestID,
+		Type:      audit.EventWebhookAttempt,
+		Actor:     w.actor,
+		Source:    audit.SourcePublisher,
+		Details: map[string]interface{}{
+			"delivery_id": d.id,
+			"event":       service.WebhookEvent(d.status),
+			"attempt":     attempt,
+			"state":       state,
+		},
+	}
+	if errText.Valid {
+		event.Error = &errText.String
+	}
+	if code.Valid {
+		event.Details["status_code"] = statusCode
+	}
+	if err := audit.Record(ctx, tx, event); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// backoff возвращает экспоненциальную паузу перед следующей попыткой со случайным разбросом ±20%
+func (w *WebhookWorker) backoff(attempt int) time.Duration {
+	delay := w.opts.InitialBackoff
+	for i := 1; i < attempt && delay < w.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > w.opts.MaxBackoff {
+		delay = w.opts.MaxBackoff
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay)/5*2+1)) - delay/5
+	return delay + jitter
+}
@@ -1,14 +1,28 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"flag"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/KostySCH/Reports_go/pkg/appconfig"
+	"github.com/KostySCH/Reports_go/pkg/auth"
+	"github.com/KostySCH/Reports_go/pkg/health"
+	"github.com/KostySCH/Reports_go/pkg/migrations"
+	"github.com/KostySCH/Reports_go/pkg/reporttype"
 	_ "github.com/KostySCH/Reports_go/reports_register/docs"
 	"github.com/KostySCH/Reports_go/reports_register/internal/config"
+	"github.com/KostySCH/Reports_go/reports_register/internal/events"
+	"github.com/KostySCH/Reports_go/reports_register/internal/generator"
 	"github.com/KostySCH/Reports_go/reports_register/internal/handler"
 	"github.com/KostySCH/Reports_go/reports_register/internal/repository/postgres"
+	"github.com/KostySCH/Reports_go/reports_register/internal/schedule"
 	"github.com/KostySCH/Reports_go/reports_register/internal/service"
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
@@ -20,31 +34,182 @@ import (
 // @description API для генерации отчетов
 // @host localhost:8080
 // @BasePath /api
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT в формате "Bearer <token>"
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 func main() {
 
-	cfg := config.Load()
+	configPath := appconfig.Flag(flag.CommandLine)
+	flag.Parse()
+	args := flag.Args()
 
-	db, _ := sql.Open("postgres", cfg.GetDSN())
+	cfg, source, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if len(args) > 0 && args[0] == appconfig.CommandName {
+		if err := appconfig.RunCommand(args[1:], cfg, source, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if source == "" {
+		log.Printf("Config file not found, using defaults and environment")
+	} else {
+		log.Printf("Loaded config from %s", source)
+	}
+
+	db, err := sql.Open("postgres", cfg.GetDSN())
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
 	defer db.Close()
 
 	if err := db.Ping(); err != nil {
 		log.Fatalf("Failed to ping database: %v", err)
 	}
 
+	if len(args) > 0 && args[0] == migrations.CommandName {
+		if err := migrations.Run(context.Background(), db, args[1:], os.Stdout); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+	if err := migrations.EnsureUpToDate(context.Background(), db); err != nil {
+		log.Fatalf("Refusing to start: %v", err)
+	}
+
+	authenticator, err := auth.New(cfg.Auth)
+	if err != nil {
+		log.Fatalf("Failed to configure authentication: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	types := reporttype.Default()
+
 	repo := postgres.NewReportRequestRepository(db)
-	svc := service.NewReportRequestService(repo)
+	limiter := service.NewLimiter(repo, service.Limits{
+		RequestsPerMinute: cfg.Limits.RequestsPerMinute,
+		MaxActivePerUser:  cfg.Limits.MaxActivePerUser,
+		MaxQueueDepth:     cfg.Limits.MaxQueueDepth,
+	})
+	opts := service.Options{
+		PublisherURL:         cfg.Publisher.BaseURL,
+		IdempotencyRetention: cfg.Idempotency.Retention,
+	}
+	if cfg.Reuse.Enabled {
+		opts.ReuseWindow = cfg.Reuse.Window
+	}
+	if cfg.ETA.Enabled {
+		opts.Estimator = service.NewQueueEstimator(repo, service.EstimatorOptions{
+			Window:   cfg.ETA.Window,
+			Workers:  cfg.ETA.Workers,
+			CacheTTL: cfg.ETA.CacheTTL,
+		})
+	}
+	svc := service.NewReportRequestService(repo, types, limiter, opts)
 	h := handler.NewReportRequestHandler(svc)
 
+	hub := events.NewHub(cfg.GetDSN())
+	hub.Start(ctx)
+	eventsHandler := handler.NewReportEventsHandler(svc, hub, cfg.Events.PollInterval, cfg.Events.Heartbeat)
+
+	batchRepo := postgres.NewReportBatchRepository(db)
+	batchSvc := service.NewReportBatchService(batchRepo, types, limiter)
+	batchHandler := handler.NewReportBatchHandler(batchSvc)
+
+	previewSvc := service.NewReportPreviewService(types, generator.NewClient(cfg.Generator.BaseURL, cfg.Generator.Token, cfg.Preview.Timeout))
+	previewHandler := handler.NewReportPreviewHandler(previewSvc)
+
+	definitionRepo := postgres.NewReportDefinitionRepository(db)
+	definitionSvc := service.NewReportDefinitionService(definitionRepo, types, svc)
+	definitionHandler := handler.NewReportDefinitionHandler(definitionSvc)
+
+	scheduleRepo := postgres.NewReportScheduleRepository(db)
+	scheduleSvc := service.NewReportScheduleService(scheduleRepo, types)
+	scheduleHandler := handler.NewReportScheduleHandler(scheduleSvc)
+
+	if cfg.Scheduler.Enabled {
+		schedule.NewScheduler(scheduleRepo, types, cfg.Scheduler.PollPeriod, cfg.Scheduler.BatchSize).Start(ctx)
+	}
+
+	checker := health.New(5 * time.Second)
+	checker.Add("postgres", health.Ping(db))
+
 	r := mux.NewRouter()
+	r.HandleFunc("/healthz", checker.Liveness).Methods("GET")
+	r.HandleFunc("/readyz", checker.Readiness).Methods("GET")
+
+	// EventSource не умеет передавать заголовки, поэтому поток событий принимает токен и из параметра запроса
+	r.Handle("/api/reports/{id}/events",
+		auth.TokenFromQuery(authenticator.Middleware(http.HandlerFunc(eventsHandler.Stream)))).Methods("GET")
 
 	api := r.PathPrefix("/api").Subrouter()
+	api.Use(authenticator.Middleware)
 	api.HandleFunc("/reports", h.Create).Methods("POST")
+	api.HandleFunc("/reports", h.List).Methods("GET")
+	api.HandleFunc("/reports/types", h.ListTypes).Methods("GET")
+	api.HandleFunc("/reports/retry", h.RetryMatching).Methods("POST")
+	api.HandleFunc("/reports/preview", previewHandler.Preview).Methods("POST")
+	api.HandleFunc("/reports/batch", batchHandler.Create).Methods("POST")
+	api.HandleFunc("/reports/batches/{id}", batchHandler.GetStatus).Methods("GET")
 	api.HandleFunc("/reports/{id}/status", h.GetStatus).Methods("GET")
+	api.HandleFunc("/reports/{id}/history", h.History).Methods("GET")
+	api.HandleFunc("/reports/{id}/cancel", h.Cancel).Methods("POST")
+	api.HandleFunc("/reports/{id}/retry", h.Retry).Methods("POST")
+
+	api.HandleFunc("/admin/stats", h.Stats).Methods("GET")
+
+	api.HandleFunc("/report-definitions", definitionHandler.Create).Methods("POST")
+	api.HandleFunc("/report-definitions", definitionHandler.List).Methods("GET")
+	api.HandleFunc("/report-definitions/{id}", definitionHandler.Get).Methods("GET")
+	api.HandleFunc("/report-definitions/{id}", definitionHandler.Update).Methods("PUT")
+	api.HandleFunc("/report-definitions/{id}", definitionHandler.Delete).Methods("DELETE")
+	api.HandleFunc("/report-definitions/{id}/shares", definitionHandler.Share).Methods("PUT")
+	api.HandleFunc("/report-definitions/{id}/run", definitionHandler.Run).Methods("POST")
+
+	api.HandleFunc("/schedules", scheduleHandler.Create).Methods("POST")
+	api.HandleFunc("/schedules", scheduleHandler.List).Methods("GET")
+	api.HandleFunc("/schedules/{id}", scheduleHandler.Get).Methods("GET")
+	api.HandleFunc("/schedules/{id}", scheduleHandler.Update).Methods("PUT")
+	api.HandleFunc("/schedules/{id}", scheduleHandler.Delete).Methods("DELETE")
 
 	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 
-	log.Printf("Starting server on :8080")
-	if err := http.ListenAndServe(":8080", r); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	srv := &http.Server{
+		Addr:    cfg.Server.Addr,
+		Handler: r,
+	}
+	// Потоки событий бесконечны, поэтому при остановке их нужно закрыть явно
+	srv.RegisterOnShutdown(eventsHandler.Close)
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		log.Printf("Starting server on %s", cfg.Server.Addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	<-sigChan
+	log.Printf("Shutting down, waiting up to %s for in-flight requests", cfg.Server.ShutdownTimeout)
+	checker.Drain()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer shutdownCancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server shutdown: %v", err)
 	}
+
+	// Останавливаем планировщик и подписку на уведомления Postgres
+	cancel()
+	log.Printf("Server stopped")
 }
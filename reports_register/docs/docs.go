@@ -15,9 +15,139 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/reports": {
+        "/api/admin/stats": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает для администраторов количество запросов по статусам и типам, текущую глубину очереди\nи возраст самого старого ожидающего запроса, среднее и перцентили времени от создания до COMPLETED,\nдолю неудач и самые частые ошибки за окно времени",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Статистика очереди отчетов",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "24h",
+                        "description": "Окно статистики в формате Go duration, не более 720h",
+                        "name": "window",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.QueueStats"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid window",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get queue statistics",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/report-definitions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает определения пользователя и открытые ему другими пользователями. Администратор видит все определения",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "definitions"
+                ],
+                "summary": "Получить список определений отчетов",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID владельца; без роли admin можно запросить только свои определения",
+                        "name": "owner_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.ReportDefinition"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid owner_id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list report definitions",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "Создает новый запрос на генерацию отчета",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Сохраняет именованный запрос на отчет: тип, параметры и настройки доставки",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,25 +155,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "reports"
+                    "definitions"
                 ],
-                "summary": "Создать новый запрос на отчет",
+                "summary": "Создать определение отчета",
                 "parameters": [
                     {
-                        "description": "Параметры запроса",
+                        "description": "Параметры определения",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.CreateReportRequest"
+                            "$ref": "#/definitions/handler.ReportDefinitionRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/model.ReportRequest"
+                            "$ref": "#/definitions/model.ReportDefinition"
                         }
                     },
                     "400": {
@@ -52,8 +182,26 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Report definition name already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ValidationErrorResponse"
+                        }
+                    },
                     "500": {
-                        "description": "Failed to create report request",
+                        "description": "Failed to create report definition",
                         "schema": {
                             "type": "string"
                         }
@@ -61,9 +209,75 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/reports/{id}/status": {
+        "/api/report-definitions/{id}": {
             "get": {
-                "description": "Получает текущий статус запроса на отчет",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "definitions"
+                ],
+                "summary": "Получить определение отчета",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID определения",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReportDefinition"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid definition id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Report definition not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Заменяет настройки определения; изменять его может только владелец или администратор",
                 "consumes": [
                     "application/json"
                 ],
@@ -71,86 +285,2233 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "reports"
+                    "definitions"
                 ],
-                "summary": "Получить статус отчета",
+                "summary": "Изменить определение отчета",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID отчета",
+                        "description": "ID определения",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Параметры определения",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.ReportDefinitionRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.ReportRequest"
+                            "$ref": "#/definitions/model.ReportDefinition"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "404": {
-                        "description": "Report not found",
+                        "description": "Report definition not found",
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "409": {
+                        "description": "Report definition name already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ValidationErrorResponse"
+                        }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "handler.CreateReportRequest": {
-            "type": "object",
-            "required": [
-                "params",
-                "type",
-                "user_id"
-            ],
-            "properties": {
-                "params": {
-                    "$ref": "#/definitions/handler.ReportParams"
-                },
-                "type": {
-                    "type": "string",
-                    "example": "daily_report"
-                },
-                "user_id": {
-                    "type": "string",
-                    "example": "123"
-                }
-            }
-        },
-        "handler.ReportParams": {
-            "type": "object",
-            "properties": {
-                "date": {
-                    "type": "string",
-                    "example": "2024-03-20"
-                },
-                "format": {
-                    "type": "string",
-                    "example": "pdf"
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "tags": [
+                    "definitions"
+                ],
+                "summary": "Удалить определение отчета",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID определения",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Invalid definition id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Report definition not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
                 }
             }
         },
-        "model.ReportRequest": {
-            "type": "object",
+        "/api/report-definitions/{id}/run": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Создает запрос на отчет по сохраненному определению от имени текущего пользователя. Тело необязательно: params и priority переопределяют сохраненные значения",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "definitions"
+                ],
+                "summary": "Запустить определение отчета",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID определения",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повторный запуск с тем же ключом вернет исходный запрос",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Переопределения параметров",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handler.RunDefinitionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReportRequest"
+                        },
+                        "headers": {
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true, если возвращен ранее созданный запрос"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Report definition not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Idempotency key reused with different parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ValidationErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many report requests",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Через сколько секунд можно повторить запрос"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to run report definition",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Report queue is full",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Через сколько секунд можно повторить запрос"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/report-definitions/{id}/shares": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Заменяет список пользователей, которые могут просматривать и запускать определение. Пустой список закрывает доступ",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "definitions"
+                ],
+                "summary": "Открыть определение отчета другим пользователям",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID определения",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Пользователи",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.ShareDefinitionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReportDefinition"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Report definition not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ValidationErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/reports": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает запросы на отчет с фильтрацией, сортировкой и курсорной пагинацией",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Получить список запросов на отчет",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя; без роли admin можно запросить только свои отчеты",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "PENDING,IN_PROGRESS",
+                        "description": "Статусы через запятую",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Тип отчета",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Нижняя граница created_at (RFC3339 или YYYY-MM-DD), включительно",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Верхняя граница created_at (RFC3339 или YYYY-MM-DD), не включительно",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "-created_at",
+                        "description": "Сортировка: created_at, -created_at, updated_at, -updated_at",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Размер страницы (не более 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы из next_cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReportRequestPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list report requests",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Создает новый запрос на генерацию отчета",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Создать новый запрос на отчет",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повторный запрос с тем же ключом вернет исходный запрос",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Параметры запроса",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.CreateReportRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReportRequest"
+                        },
+                        "headers": {
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true, если возвращен ранее созданный запрос"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Idempotency key reused with different parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ValidationErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many report requests",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Через сколько секунд можно повторить запрос"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to create report request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Report queue is full",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Через сколько секунд можно повторить запрос"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/reports/batch": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Создает все запросы пакета в одной транзакции: либо по списку reports, либо по одному типу для списка branch_ids",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "batches"
+                ],
+                "summary": "Создать пакет отчетов",
+                "parameters": [
+                    {
+                        "description": "Отчеты пакета",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.CreateBatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handler.CreateBatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ValidationErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many report requests",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to create report batch",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Report queue is full",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Через сколько секунд можно повторить запрос"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/reports/batches/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает сводный статус пакета, количество запросов по статусам и статус каждого запроса",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "batches"
+                ],
+                "summary": "Получить статус пакета отчетов",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пакета",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReportBatchStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid batch id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Batch not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get report batch",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/reports/preview": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Синхронно собирает данные отчета и возвращает их в JSON, не формируя и не сохраняя документ. Запрос не ставится в очередь и не учитывается в ограничениях на создание отчетов",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Предпросмотр данных отчета",
+                "parameters": [
+                    {
+                        "description": "Тип и параметры отчета",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.PreviewReportRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReportPreview"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "No data for report parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ValidationErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Failed to collect report data",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Report preview is temporarily unavailable",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Через сколько секунд можно повторить запрос"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Report preview timed out",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/reports/retry": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает в статус PENDING неудачные (по умолчанию) или отмененные запросы, подходящие под фильтр.\nБез роли admin затрагиваются только запросы текущего пользователя и нельзя сбросить счетчик попыток.\nКаждый возвращенный запрос расходует квоту своего владельца, как запрос в пакете.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Повторить генерацию отчетов по фильтру",
+                "parameters": [
+                    {
+                        "description": "Фильтр и параметры повтора",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.BulkRetryReportRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.BulkRetryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many report requests",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Через сколько секунд можно повторить запрос"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to retry report requests",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Report queue is full",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Через сколько секунд можно повторить запрос"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/reports/types": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает доступные типы отчетов и описание их параметров",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Получить типы отчетов",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/reporttype.Definition"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/reports/{id}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Отменяет ожидающий, выполняющийся или ожидающий повторной попытки запрос на отчет",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Отменить запрос на отчет",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID отчета",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Причина отмены",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handler.CancelReportRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReportRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid report id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Report not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Report can no longer be cancelled",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to cancel report request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/reports/{id}/events": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Server-Sent Events: сразу отправляет текущее состояние, затем событие status при каждой смене статуса\nили счетчика попыток. Поток закрывается, когда запрос переходит в COMPLETED или CANCELLED, а также\nв FAILED после исчерпания попыток; до этого FAILED сменяется повторной попыткой.\nДля EventSource токен можно передать в параметре access_token.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Поток изменений статуса отчета",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID отчета",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "JWT, если нельзя передать заголовок Authorization",
+                        "name": "access_token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Поток событий status с данными ReportStatusInfo",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReportStatusInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid report id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Report not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/reports/{id}/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает историю запроса на отчет в порядке записи: смены статусов, повторные попытки с ошибками, возвраты в очередь, уведомления и доставку вебхуков, с указанием сервиса и исполнителя",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Получить историю отчета",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID отчета",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.ReportRequestEvent"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid report id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Report not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get report history",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/reports/{id}/retry": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает неудачный или отмененный запрос в статус PENDING и записывает, кто и почему это сделал.\nВозвращенный запрос расходует квоту владельца; сбросить счетчик попыток может только администратор.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Повторить генерацию отчета",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID отчета",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Параметры повтора",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.RetryReportRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReportRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Report not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Only FAILED or CANCELLED reports can be retried",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many report requests",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Через сколько секунд можно повторить запрос"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to retry report request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Report queue is full",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Через сколько секунд можно повторить запрос"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/reports/{id}/status": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Получает текущий статус запроса на отчет: ошибку, количество попыток, путь к файлу и ссылку на скачивание для завершенных отчетов. Для ожидающих запросов возвращается место в очереди, для ожидающих и генерирующихся — ожидаемое время готовности",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Получить статус отчета",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID отчета",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReportStatusInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid report id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Report not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get report status",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/schedules": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Получить список расписаний",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID владельца; без роли admin можно запросить только свои расписания",
+                        "name": "owner_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.ReportSchedule"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid owner_id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list report schedules",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Создает расписание регулярной генерации отчета по cron-выражению",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Создать расписание отчета",
+                "parameters": [
+                    {
+                        "description": "Параметры расписания",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.ReportScheduleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReportSchedule"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ValidationErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to create report schedule",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/schedules/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Получить расписание",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID расписания",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReportSchedule"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid schedule id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Schedule not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Заменяет настройки расписания и пересчитывает время следующего запуска",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Изменить расписание",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID расписания",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Параметры расписания",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.ReportScheduleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReportSchedule"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Schedule not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ValidationErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Удалить расписание",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID расписания",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Invalid schedule id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Schedule not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handler.BatchReportDefinition": {
+            "type": "object",
+            "properties": {
+                "params": {
+                    "$ref": "#/definitions/handler.ReportParams"
+                },
+                "priority": {
+                    "description": "Priority переопределяет приоритет пакета для этого отчета",
+                    "type": "integer",
+                    "example": 5
+                },
+                "type": {
+                    "type": "string",
+                    "example": "branch_performance_report"
+                }
+            }
+        },
+        "handler.BulkRetryReportRequest": {
+            "type": "object",
+            "properties": {
+                "created_from": {
+                    "type": "string"
+                },
+                "created_to": {
+                    "type": "string"
+                },
+                "limit": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "branch data fixed"
+                },
+                "reset_retry_count": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "statuses": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "FAILED"
+                    ]
+                },
+                "type": {
+                    "type": "string",
+                    "example": "branch_performance_report"
+                },
+                "user_id": {
+                    "type": "integer",
+                    "example": 123
+                }
+            }
+        },
+        "handler.BulkRetryResponse": {
+            "type": "object",
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "requeued": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.CancelReportRequest": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "example": "wrong month"
+                }
+            }
+        },
+        "handler.CreateBatchRequest": {
+            "type": "object",
+            "properties": {
+                "branch_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        1,
+                        2,
+                        3
+                    ]
+                },
+                "params": {
+                    "$ref": "#/definitions/handler.ReportParams"
+                },
+                "priority": {
+                    "description": "Priority — приоритет всех отчетов пакета от 0 до 9, по умолчанию 5",
+                    "type": "integer",
+                    "example": 3
+                },
+                "reports": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.BatchReportDefinition"
+                    }
+                },
+                "type": {
+                    "type": "string",
+                    "example": "branch_performance_report"
+                }
+            }
+        },
+        "handler.CreateBatchResponse": {
+            "type": "object",
+            "properties": {
+                "batch_id": {
+                    "type": "string"
+                },
+                "request_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.CreateReportRequest": {
+            "type": "object",
+            "required": [
+                "params",
+                "type"
+            ],
+            "properties": {
+                "callback_secret": {
+                    "description": "CallbackSecret — ключ подписи: заголовок X-Reports-Signature содержит sha256=HMAC-SHA256(secret, timestamp + \".\" + body)",
+                    "type": "string",
+                    "example": "s3cr3t"
+                },
+                "callback_url": {
+                    "description": "CallbackURL — адрес, на который придет POST с результатом, когда отчет перейдет в COMPLETED или FAILED",
+                    "type": "string",
+                    "example": "https://partner.example.com/hooks/reports"
+                },
+                "params": {
+                    "$ref": "#/definitions/handler.ReportParams"
+                },
+                "priority": {
+                    "description": "Priority — приоритет от 0 до 9, по умолчанию 5; запросы с большим приоритетом генерируются раньше",
+                    "type": "integer",
+                    "example": 5
+                },
+                "reuse": {
+                    "description": "Reuse — можно ли отдать готовый отчет с теми же типом и параметрами, если он завершился недавно;\nпо умолчанию true, действует только при включенном повторном использовании",
+                    "type": "boolean",
+                    "example": true
+                },
+                "type": {
+                    "type": "string",
+                    "example": "branch_performance_report"
+                }
+            }
+        },
+        "handler.PreviewReportRequest": {
+            "type": "object",
+            "properties": {
+                "params": {
+                    "$ref": "#/definitions/handler.ReportParams"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "branch_performance_report"
+                }
+            }
+        },
+        "handler.ReportDefinitionRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "params",
+                "type"
+            ],
+            "properties": {
+                "callback_secret": {
+                    "description": "CallbackSecret — ключ подписи вебхука; при изменении можно не передавать, если callback_url не меняется",
+                    "type": "string",
+                    "example": "s3cr3t"
+                },
+                "callback_url": {
+                    "description": "CallbackURL — адрес вебхука для каждого запроса, созданного по определению",
+                    "type": "string",
+                    "example": "https://partner.example.com/hooks/reports"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Branch 12 monthly"
+                },
+                "params": {
+                    "$ref": "#/definitions/handler.ReportParams"
+                },
+                "priority": {
+                    "description": "Priority — приоритет создаваемых запросов от 0 до 9, по умолчанию 5",
+                    "type": "integer",
+                    "example": 5
+                },
+                "reuse": {
+                    "description": "Reuse — можно ли при запуске отдать готовый отчет с теми же параметрами, по умолчанию true",
+                    "type": "boolean",
+                    "example": true
+                },
+                "type": {
+                    "type": "string",
+                    "example": "branch_performance_report"
+                }
+            }
+        },
+        "handler.ReportParams": {
+            "type": "object",
+            "additionalProperties": true
+        },
+        "handler.ReportScheduleRequest": {
+            "type": "object",
+            "required": [
+                "cron",
+                "name",
+                "params_template",
+                "type"
+            ],
+            "properties": {
+                "cron": {
+                    "type": "string",
+                    "example": "0 6 1 * *"
+                },
+                "enabled": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Monthly branch 12 report"
+                },
+                "params_template": {
+                    "$ref": "#/definitions/handler.ReportParams"
+                },
+                "priority": {
+                    "type": "integer",
+                    "example": 5
+                },
+                "timezone": {
+                    "type": "string",
+                    "example": "Europe/Moscow"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "branch_performance_report"
+                }
+            }
+        },
+        "handler.RetryReportRequest": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "example": "branch data fixed"
+                },
+                "reset_retry_count": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "handler.RunDefinitionRequest": {
+            "type": "object",
+            "properties": {
+                "params": {
+                    "description": "Params переопределяют сохраненные параметры, например месяц отчета",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/handler.ReportParams"
+                        }
+                    ]
+                },
+                "priority": {
+                    "description": "Priority переопределяет сохраненный приоритет",
+                    "type": "integer",
+                    "example": 7
+                }
+            }
+        },
+        "handler.ShareDefinitionRequest": {
+            "type": "object",
+            "properties": {
+                "user_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        7,
+                        12
+                    ]
+                }
+            }
+        },
+        "handler.ValidationErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Validation failed"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/reporttype.FieldError"
+                    }
+                }
+            }
+        },
+        "model.BatchItemStatus": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "params": {
+                    "type": "object"
+                },
+                "status": {
+                    "$ref": "#/definitions/model.ReportStatus"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.BatchStatus": {
+            "type": "string",
+            "enum": [
+                "PENDING",
+                "IN_PROGRESS",
+                "COMPLETED",
+                "PARTIALLY_COMPLETED",
+                "FAILED",
+                "CANCELLED"
+            ],
+            "x-enum-varnames": [
+                "BatchPending",
+                "BatchInProgress",
+                "BatchCompleted",
+                "BatchPartiallyCompleted",
+                "BatchFailed",
+                "BatchCancelled"
+            ]
+        },
+        "model.CompletionStats": {
+            "type": "object",
+            "properties": {
+                "avg_seconds": {
+                    "type": "number"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "max_seconds": {
+                    "type": "number"
+                },
+                "p50_seconds": {
+                    "type": "number"
+                },
+                "p90_seconds": {
+                    "type": "number"
+                },
+                "p95_seconds": {
+                    "type": "number"
+                },
+                "p99_seconds": {
+                    "type": "number"
+                }
+            }
+        },
+        "model.ErrorStats": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.QueueStats": {
+            "type": "object",
+            "properties": {
+                "by_status": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "by_type": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.TypeStats"
+                    }
+                },
+                "completed": {
+                    "description": "Completed, Failed и FailureRate считаются по запросам, завершенным в окне;\nFailureRate = Failed / (Completed + Failed)",
+                    "type": "integer"
+                },
+                "completion": {
+                    "$ref": "#/definitions/model.CompletionStats"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "failure_rate": {
+                    "type": "number"
+                },
+                "from": {
+                    "description": "From и To — границы окна; счетчики по статусам и типам считаются по запросам, созданным в окне",
+                    "type": "string"
+                },
+                "in_progress": {
+                    "type": "integer"
+                },
+                "oldest_pending_age_seconds": {
+                    "description": "OldestPendingAgeSeconds — возраст самого старого ожидающего запроса; 0, если очередь пуста",
+                    "type": "number"
+                },
+                "pending": {
+                    "description": "Pending и InProgress — текущая глубина очереди без учета окна",
+                    "type": "integer"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "top_errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ErrorStats"
+                    }
+                }
+            }
+        },
+        "model.ReportBatchStatus": {
+            "type": "object",
+            "properties": {
+                "counts": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "done": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "requests": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.BatchItemStatus"
+                    }
+                },
+                "status": {
+                    "$ref": "#/definitions/model.BatchStatus"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.ReportDefinition": {
+            "type": "object",
             "properties": {
+                "callback_url": {
+                    "description": "CallbackURL и CallbackSecret передаются в каждый созданный по определению запрос",
+                    "type": "string"
+                },
                 "created_at": {
                     "type": "string"
                 },
                 "id": {
                     "type": "string"
                 },
+                "name": {
+                    "type": "string"
+                },
+                "owner_id": {
+                    "type": "integer"
+                },
                 "params": {
-                    "description": "JSONB в PostgreSQL",
+                    "type": "object"
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "reuse": {
+                    "description": "Reuse — можно ли при запуске отдать готовый отчет с теми же типом и параметрами",
+                    "type": "boolean"
+                },
+                "shared_with": {
+                    "description": "SharedWith — пользователи, которые могут просматривать и запускать определение",
                     "type": "array",
                     "items": {
                         "type": "integer"
                     }
                 },
+                "type": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ReportPreview": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "description": "Data — данные в том виде, в каком они легли бы в документ; структура зависит от типа отчета",
+                    "type": "object"
+                },
+                "generated_at": {
+                    "type": "string"
+                },
+                "params": {
+                    "type": "object"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "branch_performance_report"
+                }
+            }
+        },
+        "model.ReportRequest": {
+            "type": "object",
+            "properties": {
+                "batch_id": {
+                    "type": "string"
+                },
+                "callback_url": {
+                    "type": "string"
+                },
+                "completed_at": {
+                    "description": "CompletedAt — когда сформирован результат; у повторно использованного результата — время источника",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "idempotency_key": {
+                    "type": "string"
+                },
+                "params": {
+                    "type": "object"
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "report_path": {
+                    "type": "string"
+                },
+                "requeue_reason": {
+                    "type": "string"
+                },
+                "requeued_at": {
+                    "type": "string"
+                },
+                "requeued_by": {
+                    "type": "string"
+                },
+                "retry_count": {
+                    "type": "integer"
+                },
+                "reused_from": {
+                    "description": "ReusedFrom — завершенный запрос, чей результат использован вместо повторной генерации",
+                    "type": "string"
+                },
+                "schedule_id": {
+                    "type": "string"
+                },
+                "scheduled_for": {
+                    "type": "string"
+                },
                 "status": {
                     "$ref": "#/definitions/model.ReportStatus"
                 },
@@ -161,6 +2522,120 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.ReportRequestEvent": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "description": "Actor — пользователь (user:\u003cid\u003e, apikey:\u003cname\u003e), расписание или воркер (\u003chost\u003e:\u003cpid\u003e/\u003cname\u003e)",
+                    "type": "string",
+                    "example": "host-1:4242/generator-worker-1"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "details": {
+                    "type": "object"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "event_type": {
+                    "type": "string",
+                    "example": "STATUS_CHANGED"
+                },
+                "from_status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ReportStatus"
+                        }
+                    ],
+                    "example": "IN_PROGRESS"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "retry_count": {
+                    "type": "integer"
+                },
+                "source": {
+                    "description": "Source — сервис, записавший событие",
+                    "type": "string",
+                    "example": "reports_generator"
+                },
+                "to_status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ReportStatus"
+                        }
+                    ],
+                    "example": "FAILED"
+                }
+            }
+        },
+        "model.ReportRequestPage": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ReportRequest"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ReportSchedule": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "cron": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_run_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "next_run_at": {
+                    "type": "string"
+                },
+                "owner_id": {
+                    "type": "integer"
+                },
+                "params_template": {
+                    "type": "object"
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "timezone": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updated_at": {
                     "type": "string"
                 }
             }
@@ -171,15 +2646,185 @@ const docTemplate = `{
                 "PENDING",
                 "IN_PROGRESS",
                 "COMPLETED",
-                "FAILED"
+                "FAILED",
+                "CANCELLED"
             ],
             "x-enum-varnames": [
                 "StatusPending",
                 "StatusInProgress",
                 "StatusCompleted",
-                "StatusFailed"
+                "StatusFailed",
+                "StatusCancelled"
+            ]
+        },
+        "model.ReportStatusInfo": {
+            "type": "object",
+            "properties": {
+                "completed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "download_url": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "estimated_completion_at": {
+                    "description": "EstimatedCompletionAt — ожидаемое время готовности по недавним длительностям генерации;\nотсутствует, если оценить его не по чему",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "queue_position": {
+                    "description": "QueuePosition — место ожидающего запроса в очереди генерации, начиная с 1",
+                    "type": "integer"
+                },
+                "report_path": {
+                    "type": "string"
+                },
+                "requeue_reason": {
+                    "type": "string"
+                },
+                "requeued_at": {
+                    "type": "string"
+                },
+                "requeued_by": {
+                    "type": "string"
+                },
+                "retry_count": {
+                    "type": "integer"
+                },
+                "reused_from": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/model.ReportStatus"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.TypeStats": {
+            "type": "object",
+            "properties": {
+                "by_status": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "branch_performance_report"
+                }
+            }
+        },
+        "reporttype.Definition": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "branch_performance_report"
+                },
+                "params": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/reporttype.Param"
+                    }
+                }
+            }
+        },
+        "reporttype.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "params.month"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "must match format YYYY-MM"
+                }
+            }
+        },
+        "reporttype.Param": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "enum": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "format": {
+                    "type": "string",
+                    "example": "YYYY-MM"
+                },
+                "maximum": {
+                    "type": "number"
+                },
+                "minimum": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "required": {
+                    "type": "boolean"
+                },
+                "type": {
+                    "$ref": "#/definitions/reporttype.ParamKind"
+                }
+            }
+        },
+        "reporttype.ParamKind": {
+            "type": "string",
+            "enum": [
+                "integer",
+                "number",
+                "string",
+                "boolean"
+            ],
+            "x-enum-varnames": [
+                "KindInteger",
+                "KindNumber",
+                "KindString",
+                "KindBoolean"
             ]
         }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
+        "BearerAuth": {
+            "description": "JWT в формате \"Bearer \u003ctoken\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
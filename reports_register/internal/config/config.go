@@ -1,92 +1,182 @@
 package config
 
 import (
-	"fmt"
-	"os"
-	"path/filepath"
+	"errors"
+	"time"
 
-	"gopkg.in/yaml.v3"
+	"github.com/KostySCH/Reports_go/pkg/appconfig"
+	"github.com/KostySCH/Reports_go/pkg/auth"
 )
 
+// EnvPrefix — префикс переменных окружения reports_register, например REGISTER_DATABASE_PASSWORD
+const EnvPrefix = "REGISTER"
+
 type Config struct {
-	Database struct {
-		Host     string `yaml:"host"`
-		Port     string `yaml:"port"`
-		User     string `yaml:"user"`
-		Password string `yaml:"password"`
-		DBName   string `yaml:"dbname"`
-		SSLMode  string `yaml:"sslmode"`
-	} `yaml:"database"`
+	Database    appconfig.Database `yaml:"database"`
+	Publisher   Publisher          `yaml:"publisher"`
+	Generator   Generator          `yaml:"generator"`
+	Preview     Preview            `yaml:"preview"`
+	Idempotency Idempotency        `yaml:"idempotency"`
+	Reuse       Reuse              `yaml:"reuse"`
+	Scheduler   Scheduler          `yaml:"scheduler"`
+	Limits      Limits             `yaml:"limits"`
+	Events      Events             `yaml:"events"`
+	ETA         ETA                `yaml:"eta"`
+	Server      Server             `yaml:"server"`
+	Auth        auth.Config        `yaml:"auth"`
 }
 
-func Load() *Config {
-	config := &Config{}
+type Publisher struct {
+	BaseURL string `yaml:"base_url"`
+}
 
-	// Получаем текущую рабочую директорию
-	workDir, err := os.Getwd()
-	if err != nil {
-		fmt.Printf("Error getting working directory: %v\n", err)
-		return getDefaultConfig()
-	}
+// Generator — внутренний HTTP API reports_generator
+type Generator struct {
+	BaseURL string `yaml:"base_url"`
+	// Token подтверждает генератору, что вызов пришел от reports_register; совпадает с preview.token генератора
+	Token string `yaml:"token" secret:"true"`
+}
 
-	// Пробуем найти конфигурационный файл в разных местах
-	configPaths := []string{
-		filepath.Join(workDir, "config.yaml"),                       // В текущей директории
-		filepath.Join(workDir, "config", "config.yaml"),             // В поддиректории config
-		filepath.Join(workDir, "..", "config", "config.yaml"),       // В родительской директории/config
-		filepath.Join(workDir, "..", "..", "config", "config.yaml"), // В корне проекта
-	}
+type Preview struct {
+	// Timeout ограничивает ожидание ответа генератора на запрос предпросмотра
+	Timeout time.Duration `yaml:"timeout"`
+}
 
-	var configData []byte
-	for _, path := range configPaths {
-		data, err := os.ReadFile(path)
-		if err == nil {
-			configData = data
-			fmt.Printf("Found config file at: %s\n", path)
-			break
-		}
-	}
+type Idempotency struct {
+	Retention time.Duration `yaml:"retention"`
+}
 
-	if configData == nil {
-		fmt.Printf("Config file not found in any of the expected locations\n")
-		return getDefaultConfig()
-	}
+type Reuse struct {
+	Enabled bool          `yaml:"enabled"`
+	Window  time.Duration `yaml:"window"`
+}
 
-	// Парсим YAML
-	if err := yaml.Unmarshal(configData, config); err != nil {
-		fmt.Printf("Error parsing config file: %v\n", err)
-		return getDefaultConfig()
-	}
+type Scheduler struct {
+	Enabled    bool          `yaml:"enabled"`
+	PollPeriod time.Duration `yaml:"poll_period"`
+	BatchSize  int           `yaml:"batch_size"`
+}
+
+type Limits struct {
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	MaxActivePerUser  int `yaml:"max_active_per_user"`
+	MaxQueueDepth     int `yaml:"max_queue_depth"`
+}
+
+type Events struct {
+	PollInterval time.Duration `yaml:"poll_interval"`
+	Heartbeat    time.Duration `yaml:"heartbeat"`
+}
+
+// ETA — оценка места в очереди и времени готовности в ответе на запрос статуса
+type ETA struct {
+	Enabled bool `yaml:"enabled"`
+	// Window — за какой период берутся длительности генерации
+	Window time.Duration `yaml:"window"`
+	// Workers — сколько отчетов генератор формирует одновременно, worker.concurrency генератора
+	Workers  int           `yaml:"workers"`
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+}
 
-	return config
+type Server struct {
+	Addr string `yaml:"addr"`
+	// ShutdownTimeout — сколько ждать завершения обрабатываемых HTTP-запросов при остановке
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// Load читает конфигурацию из path (флаг --config) или из найденного config.yaml
+// и применяет переменные окружения REGISTER_*. Возвращает путь к прочитанному файлу.
+func Load(path string) (*Config, string, error) {
+	config := getDefaultConfig()
+	source, err := appconfig.Load(config, appconfig.Options{EnvPrefix: EnvPrefix, Path: path})
+	if err != nil {
+		return nil, "", err
+	}
+	return config, source, nil
 }
 
 func getDefaultConfig() *Config {
 	return &Config{
-		Database: struct {
-			Host     string `yaml:"host"`
-			Port     string `yaml:"port"`
-			User     string `yaml:"user"`
-			Password string `yaml:"password"`
-			DBName   string `yaml:"dbname"`
-			SSLMode  string `yaml:"sslmode"`
-		}{
-			Host:     "localhost",
-			Port:     "5432",
-			User:     "postgres",
-			Password: "postgres",
-			DBName:   "reports_db",
-			SSLMode:  "disable",
+		Database: appconfig.DefaultDatabase(),
+		Publisher: Publisher{
+			BaseURL: "http://localhost:8082",
+		},
+		Generator: Generator{
+			BaseURL: "http://localhost:8081",
+		},
+		Preview: Preview{
+			Timeout: 15 * time.Second,
+		},
+		Idempotency: Idempotency{
+			Retention: 24 * time.Hour,
+		},
+		Reuse: Reuse{
+			Enabled: false,
+			Window:  15 * time.Minute,
+		},
+		Scheduler: Scheduler{
+			Enabled:    true,
+			PollPeriod: 30 * time.Second,
+			BatchSize:  100,
+		},
+		Limits: Limits{
+			RequestsPerMinute: 30,
+			MaxActivePerUser:  20,
+			MaxQueueDepth:     10000,
 		},
+		Events: Events{
+			PollInterval: 5 * time.Second,
+			Heartbeat:    15 * time.Second,
+		},
+		ETA: ETA{
+			Enabled:  true,
+			Window:   24 * time.Hour,
+			Workers:  10,
+			CacheTTL: time.Minute,
+		},
+		Server: Server{
+			Addr:            ":8080",
+			ShutdownTimeout: 30 * time.Second,
+		},
+	}
+}
+
+// Validate проверяет значения после применения переменных окружения
+func (c *Config) Validate() error {
+	errs := []error{
+		c.Database.Validate(),
+		appconfig.Required("publisher.base_url", c.Publisher.BaseURL),
+		appconfig.Required("generator.base_url", c.Generator.BaseURL),
+		appconfig.Required("generator.token", c.Generator.Token),
+		appconfig.PositiveDuration("preview.timeout", c.Preview.Timeout),
+		appconfig.PositiveDuration("idempotency.retention", c.Idempotency.Retention),
+		appconfig.NonNegativeInt("limits.requests_per_minute", c.Limits.RequestsPerMinute),
+		appconfig.NonNegativeInt("limits.max_active_per_user", c.Limits.MaxActivePerUser),
+		appconfig.NonNegativeInt("limits.max_queue_depth", c.Limits.MaxQueueDepth),
+		appconfig.PositiveDuration("events.poll_interval", c.Events.PollInterval),
+		appconfig.PositiveDuration("events.heartbeat", c.Events.Heartbeat),
+		appconfig.Required("server.addr", c.Server.Addr),
+		appconfig.PositiveDuration("server.shutdown_timeout", c.Server.ShutdownTimeout),
+	}
+	if c.Reuse.Enabled {
+		errs = append(errs, appconfig.PositiveDuration("reuse.window", c.Reuse.Window))
+	}
+	if c.Scheduler.Enabled {
+		errs = append(errs,
+			appconfig.PositiveDuration("scheduler.poll_period", c.Scheduler.PollPeriod),
+			appconfig.PositiveInt("scheduler.batch_size", c.Scheduler.BatchSize),
+		)
+	}
+	if c.ETA.Enabled {
+		errs = append(errs,
+			appconfig.PositiveDuration("eta.window", c.ETA.Window),
+			appconfig.PositiveInt("eta.workers", c.ETA.Workers),
+			appconfig.PositiveDuration("eta.cache_ttl", c.ETA.CacheTTL),
+		)
 	}
+	return errors.Join(errs...)
 }
 
 func (c *Config) GetDSN() string {
-	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		c.Database.Host,
-		c.Database.Port,
-		c.Database.User,
-		c.Database.Password,
-		c.Database.DBName,
-		c.Database.SSLMode)
+	return c.Database.DSN()
 }
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultConfigNeedsOnlyGeneratorToken(t *testing.T) {
+	cfg := getDefaultConfig()
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "generator.token") {
+		t.Fatalf("Validate() = %v, want a missing generator.token", err)
+	}
+
+	cfg.Generator.Token = "secret"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() with a token = %v", err)
+	}
+}
+
+func TestValidateOptionalSections(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(*Config)
+		wantErr string
+	}{
+		{
+			name:    "reuse window when enabled",
+			change:  func(c *Config) { c.Reuse.Enabled, c.Reuse.Window = true, 0 },
+			wantErr: "reuse.window",
+		},
+		{
+			name:   "reuse window ignored when disabled",
+			change: func(c *Config) { c.Reuse.Enabled, c.Reuse.Window = false, 0 },
+		},
+		{
+			name:    "scheduler batch size when enabled",
+			change:  func(c *Config) { c.Scheduler.BatchSize = 0 },
+			wantErr: "scheduler.batch_size",
+		},
+		{
+			name:   "scheduler ignored when disabled",
+			change: func(c *Config) { c.Scheduler.Enabled, c.Scheduler.PollPeriod = false, 0 },
+		},
+		{
+			name:    "eta workers when enabled",
+			change:  func(c *Config) { c.ETA.Workers = 0 },
+			wantErr: "eta.workers",
+		},
+		{
+			name:   "zero limits disable the checks",
+			change: func(c *Config) { c.Limits = Limits{} },
+		},
+		{
+			name:    "negative limit",
+			change:  func(c *Config) { c.Limits.MaxQueueDepth = -1 },
+			wantErr: "limits.max_queue_depth",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := getDefaultConfig()
+			cfg.Generator.Token = "secret"
+			tt.change(cfg)
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %v, want it to mention %s", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+// Package events доставляет уведомления об изменении запросов на отчет через Postgres LISTEN/NOTIFY.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+var log = logrus.New()
+
+func init() {
+	log.SetFormatter(&logrus.JSONFormatter{})
+	log.SetLevel(logrus.DebugLevel)
+}
+
+// Channel — канал NOTIFY, в который триггер на reporting.report_requests пишет изменения
+// (миграция 0007_status_notify)
+const Channel = "report_request_changes"
+
+// Change описывает изменение запроса на отчет из уведомления
+type Change struct {
+	ID         uuid.UUID `json:"id"`
+	Status     string    `json:"status"`
+	RetryCount int       `json:"retry_count"`
+}
+
+// Hub держит одно соединение LISTEN и раздает уведомления подписчикам по идентификатору запроса.
+// Подписчик получает только сигнал о том, что запрос изменился, и сам перечитывает его состояние,
+// поэтому пропущенные и повторные уведомления не нарушают порядок событий.
+type Hub struct {
+	listener *pq.Listener
+
+	mu   sync.Mutex
+	subs map[uuid.UUID]map[chan struct{}]struct{}
+}
+
+func NewHub(dsn string) *Hub {
+	h := &Hub{subs: make(map[uuid.UUID]map[chan struct{}]struct{})}
+	h.listener = pq.NewListener(dsn, time.Second, time.Minute, h.onListenerEvent)
+	return h
+}
+
+// Start подписывается на канал уведомлений и раздает их до отмены ctx
+func (h *Hub) Start(ctx context.Context) {
+	if err := h.listener.Listen(Channel); err != nil {
+		// Подписчики продолжат получать обновления опросом базы
+		log.WithError(err).Warn("Failed to listen for report request changes")
+	}
+
+	go func() {
+		defer h.listener.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n := <-h.listener.Notify:
+				if n == nil {
+					// После переподключения уведомления могли потеряться
+					h.broadcast()
+					continue
+				}
+				h.dispatch(n.Extra)
+			}
+		}
+	}()
+}
+
+func (h *Hub) onListenerEvent(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventDisconnected:
+		log.WithError(err).Warn("Report events listener disconnected")
+	case pq.ListenerEventReconnected:
+		log.Info("Report events listener reconnected")
+	case pq.ListenerEventConnectionAttemptFailed:
+		log.WithError(err).Warn("Report events listener failed to reconnect")
+	}
+}
+
+// Subscribe возвращает канал сигналов об изменении запроса id и функцию отписки
+func (h *Hub) Subscribe(id uuid.UUID) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	h.mu.Lock()
+	if h.subs[id] == nil {
+		h.subs[id] = make(map[chan struct{}]struct{})
+	}
+	h.subs[id][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.subs[id], ch)
+		if len(h.subs[id]) == 0 {
+			delete(h.subs, id)
+		}
+		h.mu.Unlock()
+	}
+}
+
+func (h *Hub) dispatch(payload string) {
+	var change Change
+	if err := json.Unmarshal([]byte(payload), &change); err != nil {
+		log.WithError(err).WithField("payload", payload).Warn("Invalid report change notification")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[change.ID] {
+		signal(ch)
+	}
+}
+
+func (h *Hub) broadcast() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, subs := range h.subs {
+		for ch := range subs {
+			signal(ch)
+		}
+	}
+}
+
+// signal не блокируется: если сигнал уже ждет обработки, новый ничего не добавляет
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,104 @@
+// Package generator — клиент внутреннего HTTP API reports_generator
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+)
+
+const (
+	// maxErrorBody ограничивает текст ошибки генератора, попадающий в ответ и журнал
+	maxErrorBody = 1024
+	// serviceTokenHeader — заголовок с общим токеном, который проверяет внутренний API генератора
+	serviceTokenHeader = "X-Service-Token"
+)
+
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// NewClient создает клиента генератора; token — общий с генератором токен сервисов,
+// timeout ограничивает каждый вызов целиком
+func NewClient(baseURL, token string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: timeout},
+	}
+}
+
+type previewRequest struct {
+	Type   string                 `json:"type"`
+	Params map[string]interface{} `json:"params"`
+}
+
+type previewResponse struct {
+	Type        string          `json:"type"`
+	Data        json.RawMessage `json:"data"`
+	GeneratedAt time.Time       `json:"generated_at"`
+}
+
+// Preview собирает данные отчета reportType с уже проверенными params
+func (c *Client) Preview(ctx context.Context, reportType string, params map[string]interface{}) (*model.ReportPreview, error) {
+	body, err := json.Marshal(previewRequest{Type: reportType, Params: params})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/internal/preview", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(serviceTokenHeader, c.token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		var netErr interface{ Timeout() bool }
+		if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+			return nil, model.ErrPreviewTimeout
+		}
+		return nil, fmt.Errorf("%w: %v", model.ErrPreviewUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, model.ErrPreviewNoData
+	case http.StatusGatewayTimeout:
+		return nil, model.ErrPreviewTimeout
+	case http.StatusServiceUnavailable:
+		return nil, model.ErrPreviewUnavailable
+	default:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return nil, fmt.Errorf("generator preview failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	var result previewResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid generator preview response: %w", err)
+	}
+
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ReportPreview{
+		Type:        reportType,
+		Params:      model.JSON(paramsJSON),
+		Data:        result.Data,
+		GeneratedAt: result.GeneratedAt,
+	}, nil
+}
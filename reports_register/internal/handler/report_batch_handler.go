@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/KostySCH/Reports_go/pkg/auth"
+	"github.com/KostySCH/Reports_go/pkg/reporttype"
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+	"github.com/KostySCH/Reports_go/reports_register/internal/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+type ReportBatchHandler struct {
+	service *service.ReportBatchService
+}
+
+func NewReportBatchHandler(service *service.ReportBatchService) *ReportBatchHandler {
+	return &ReportBatchHandler{service: service}
+}
+
+// BatchReportDefinition представляет один отчет в пакете
+type BatchReportDefinition struct {
+	Type   string       `json:"type" example:"branch_performance_report"`
+	Params ReportParams `json:"params"`
+	// Priority переопределяет приоритет пакета для этого отчета
+	Priority *int `json:"priority" example:"5"`
+}
+
+// CreateBatchRequest представляет запрос на создание пакета отчетов. Можно передать либо
+// список отчетов в reports, либо один тип с общими params и список branch_ids — тогда для
+// каждого филиала создается отдельный отчет с параметром branch_id. Пользователь определяется по токену.
+type CreateBatchRequest struct {
+	Reports   []BatchReportDefinition `json:"reports"`
+	Type      string                  `json:"type" example:"branch_performance_report"`
+	Params    ReportParams            `json:"params"`
+	BranchIDs []int64                 `json:"branch_ids" example:"1,2,3"`
+	// Priority — приоритет всех отчетов пакета от 0 до 9, по умолчанию 5
+	Priority *int `json:"priority" example:"3"`
+}
+
+// items разворачивает запрос в список отчетов пакета
+func (req CreateBatchRequest) items() ([]service.BatchItem, error) {
+	if len(req.Reports) > 0 && len(req.BranchIDs) > 0 {
+		return nil, errors.New("either reports or branch_ids must be provided, not both")
+	}
+
+	items := make([]service.BatchItem, 0, len(req.Reports)+len(req.BranchIDs))
+	for _, report := range req.Reports {
+		priority := req.Priority
+		if report.Priority != nil {
+			priority = report.Priority
+		}
+		items = append(items, service.BatchItem{Type: report.Type, Params: report.Params, Priority: priority})
+	}
+	for _, branchID := range req.BranchIDs {
+		params := make(map[string]interface{}, len(req.Params)+1)
+		for name, value := range req.Params {
+			params[name] = value
+		}
+		// Приводим к float64, как после декодирования JSON, чтобы проверка по реестру работала одинаково
+		params["branch_id"] = float64(branchID)
+		items = append(items, service.BatchItem{Type: req.Type, Params: params, Priority: req.Priority})
+	}
+	return items, nil
+}
+
+// CreateBatchResponse представляет результат создания пакета отчетов
+type CreateBatchResponse struct {
+	BatchID    uuid.UUID   `json:"batch_id"`
+	Total      int         `json:"total"`
+	RequestIDs []uuid.UUID `json:"request_ids"`
+}
+
+// @Summary Создать пакет отчетов
+// @Description Создает все запросы пакета в одной транзакции: либо по списку reports, либо по одному типу для списка branch_ids
+// @Tags batches
+// @Accept json
+// @Produce json
+// @Param request body CreateBatchRequest true "Отчеты пакета"
+// @Success 201 {object} CreateBatchResponse
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 422 {object} ValidationErrorResponse
+// @Failure 429 {string} string "Too many report requests"
+// @Failure 500 {string} string "Failed to create report batch"
+// @Failure 503 {string} string "Report queue is full"
+// @Header 503 {integer} Retry-After "Через сколько секунд можно повторить запрос"
+// @Router /api/reports/batch [post]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportBatchHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req CreateBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	items, err := req.items()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	caller := auth.FromContext(r.Context())
+	batch, ids, err := h.service.Create(r.Context(), caller, items)
+	var verr *reporttype.ValidationError
+	if errors.As(err, &verr) {
+		writeValidationError(w, verr)
+		return
+	}
+	var lerr *service.LimitError
+	if errors.As(err, &lerr) {
+		writeLimitError(w, lerr)
+		return
+	}
+	if err != nil {
+		log.WithError(err).WithField("user_id", caller.UserID).Error("Failed to create report batch")
+		http.Error(w, "Failed to create report batch", http.StatusInternalServerError)
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"batch_id": batch.ID,
+		"total":    batch.Total,
+	}).Info("Successfully created report batch")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateBatchResponse{
+		BatchID:    batch.ID,
+		Total:      batch.Total,
+		RequestIDs: ids,
+	})
+}
+
+// @Summary Получить статус пакета отчетов
+// @Description Возвращает сводный статус пакета, количество запросов по статусам и статус каждого запроса
+// @Tags batches
+// @Produce json
+// @Param id path string true "ID пакета"
+// @Success 200 {object} model.ReportBatchStatus
+// @Failure 400 {string} string "Invalid batch id"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Batch not found"
+// @Failure 500 {string} string "Failed to get report batch"
+// @Router /api/reports/batches/{id} [get]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportBatchHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid batch id", http.StatusBadRequest)
+		return
+	}
+
+	status, err := h.service.GetStatus(r.Context(), id, auth.FromContext(r.Context()))
+	if errors.Is(err, model.ErrBatchNotFound) {
+		http.Error(w, "Batch not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, model.ErrForbidden) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		log.WithError(err).WithField("batch_id", id).Error("Failed to get report batch")
+		http.Error(w, "Failed to get report batch", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,364 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/KostySCH/Reports_go/pkg/auth"
+	"github.com/KostySCH/Reports_go/pkg/reporttype"
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+	"github.com/KostySCH/Reports_go/reports_register/internal/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+type ReportDefinitionHandler struct {
+	service *service.ReportDefinitionService
+}
+
+func NewReportDefinitionHandler(service *service.ReportDefinitionService) *ReportDefinitionHandler {
+	return &ReportDefinitionHandler{service: service}
+}
+
+// ReportDefinitionRequest представляет тело запроса на создание или изменение определения отчета.
+// Тип и параметры проверяются так же, как при создании запроса; формат документа задается в params.
+// Владельцем определения становится пользователь из токена.
+type ReportDefinitionRequest struct {
+	Name   string       `json:"name" example:"Branch 12 monthly" binding:"required"`
+	Type   string       `json:"type" example:"branch_performance_report" binding:"required"`
+	Params ReportParams `json:"params" binding:"required"`
+	// Priority — приоритет создаваемых запросов от 0 до 9, по умолчанию 5
+	Priority *int `json:"priority" example:"5"`
+	// CallbackURL — адрес вебхука для каждого запроса, созданного по определению
+	CallbackURL string `json:"callback_url" example:"https://partner.example.com/hooks/reports"`
+	// CallbackSecret — ключ подписи вебхука; при изменении можно не передавать, если callback_url не меняется
+	CallbackSecret string `json:"callback_secret" example:"s3cr3t"`
+	// Reuse — можно ли при запуске отдать готовый отчет с теми же параметрами, по умолчанию true
+	Reuse *bool `json:"reuse" example:"true"`
+}
+
+func (req ReportDefinitionRequest) input(ownerID int) service.DefinitionInput {
+	reuse := true
+	if req.Reuse != nil {
+		reuse = *req.Reuse
+	}
+	return service.DefinitionInput{
+		Name:           req.Name,
+		OwnerID:        ownerID,
+		Type:           req.Type,
+		Params:         req.Params,
+		Priority:       req.Priority,
+		CallbackURL:    req.CallbackURL,
+		CallbackSecret: req.CallbackSecret,
+		Reuse:          reuse,
+	}
+}
+
+// ShareDefinitionRequest задает полный список пользователей, которым открыто определение
+type ShareDefinitionRequest struct {
+	UserIDs []int `json:"user_ids" example:"7,12"`
+}
+
+// RunDefinitionRequest представляет необязательное тело запуска определения
+type RunDefinitionRequest struct {
+	// Params переопределяют сохраненные параметры, например месяц отчета
+	Params ReportParams `json:"params"`
+	// Priority переопределяет сохраненный приоритет
+	Priority *int `json:"priority" example:"7"`
+}
+
+// @Summary Создать определение отчета
+// @Description Сохраняет именованный запрос на отчет: тип, параметры и настройки доставки
+// @Tags definitions
+// @Accept json
+// @Produce json
+// @Param request body ReportDefinitionRequest true "Параметры определения"
+// @Success 201 {object} model.ReportDefinition
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 409 {string} string "Report definition name already exists"
+// @Failure 422 {object} ValidationErrorResponse
+// @Failure 500 {string} string "Failed to create report definition"
+// @Router /api/report-definitions [post]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportDefinitionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req ReportDefinitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	definition, err := h.service.Create(r.Context(), req.input(auth.FromContext(r.Context()).UserID))
+	if !h.checkError(w, err, "Failed to create report definition") {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(definition)
+}
+
+// @Summary Получить список определений отчетов
+// @Description Возвращает определения пользователя и открытые ему другими пользователями. Администратор видит все определения
+// @Tags definitions
+// @Produce json
+// @Param owner_id query int false "ID владельца; без роли admin можно запросить только свои определения"
+// @Success 200 {array} model.ReportDefinition
+// @Failure 400 {string} string "Invalid owner_id"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Failed to list report definitions"
+// @Router /api/report-definitions [get]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportDefinitionHandler) List(w http.ResponseWriter, r *http.Request) {
+	var ownerID *int
+	if value := r.URL.Query().Get("owner_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid owner_id", http.StatusBadRequest)
+			return
+		}
+		ownerID = &id
+	}
+
+	definitions, err := h.service.List(r.Context(), auth.FromContext(r.Context()), ownerID)
+	if !h.checkError(w, err, "Failed to list report definitions") {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(definitions)
+}
+
+// @Summary Получить определение отчета
+// @Tags definitions
+// @Produce json
+// @Param id path string true "ID определения"
+// @Success 200 {object} model.ReportDefinition
+// @Failure 400 {string} string "Invalid definition id"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Report definition not found"
+// @Router /api/report-definitions/{id} [get]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportDefinitionHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid definition id", http.StatusBadRequest)
+		return
+	}
+
+	definition, err := h.service.Get(r.Context(), id, auth.FromContext(r.Context()))
+	if !h.checkError(w, err, "Failed to get report definition") {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(definition)
+}
+
+// @Summary Изменить определение отчета
+// @Description Заменяет настройки определения; изменять его может только владелец или администратор
+// @Tags definitions
+// @Accept json
+// @Produce json
+// @Param id path string true "ID определения"
+// @Param request body ReportDefinitionRequest true "Параметры определения"
+// @Success 200 {object} model.ReportDefinition
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Report definition not found"
+// @Failure 409 {string} string "Report definition name already exists"
+// @Failure 422 {object} ValidationErrorResponse
+// @Router /api/report-definitions/{id} [put]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportDefinitionHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid definition id", http.StatusBadRequest)
+		return
+	}
+
+	var req ReportDefinitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	caller := auth.FromContext(r.Context())
+	definition, err := h.service.Update(r.Context(), id, caller, req.input(caller.UserID))
+	if !h.checkError(w, err, "Failed to update report definition") {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(definition)
+}
+
+// @Summary Удалить определение отчета
+// @Tags definitions
+// @Param id path string true "ID определения"
+// @Success 204
+// @Failure 400 {string} string "Invalid definition id"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Report definition not found"
+// @Router /api/report-definitions/{id} [delete]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportDefinitionHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid definition id", http.StatusBadRequest)
+		return
+	}
+
+	if !h.checkError(w, h.service.Delete(r.Context(), id, auth.FromContext(r.Context())), "Failed to delete report definition") {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Открыть определение отчета другим пользователям
+// @Description Заменяет список пользователей, которые могут просматривать и запускать определение. Пустой список закрывает доступ
+// @Tags definitions
+// @Accept json
+// @Produce json
+// @Param id path string true "ID определения"
+// @Param request body ShareDefinitionRequest true "Пользователи"
+// @Success 200 {object} model.ReportDefinition
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Report definition not found"
+// @Failure 422 {object} ValidationErrorResponse
+// @Router /api/report-definitions/{id}/shares [put]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportDefinitionHandler) Share(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid definition id", http.StatusBadRequest)
+		return
+	}
+
+	var req ShareDefinitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	definition, err := h.service.Share(r.Context(), id, auth.FromContext(r.Context()), req.UserIDs)
+	if !h.checkError(w, err, "Failed to share report definition") {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(definition)
+}
+
+// @Summary Запустить определение отчета
+// @Description Создает запрос на отчет по сохраненному определению от имени текущего пользователя. Тело необязательно: params и priority переопределяют сохраненные значения
+// @Tags definitions
+// @Accept json
+// @Produce json
+// @Param id path string true "ID определения"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повторный запуск с тем же ключом вернет исходный запрос"
+// @Param request body RunDefinitionRequest false "Переопределения параметров"
+// @Success 200 {object} model.ReportRequest
+// @Header 200 {string} Idempotent-Replayed "true, если возвращен ранее созданный запрос"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Report definition not found"
+// @Failure 409 {string} string "Idempotency key reused with different parameters"
+// @Failure 422 {object} ValidationErrorResponse
+// @Failure 429 {string} string "Too many report requests"
+// @Header 429 {integer} Retry-After "Через сколько секунд можно повторить запрос"
+// @Failure 500 {string} string "Failed to run report definition"
+// @Failure 503 {string} string "Report queue is full"
+// @Header 503 {integer} Retry-After "Через сколько секунд можно повторить запрос"
+// @Router /api/report-definitions/{id}/run [post]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportDefinitionHandler) Run(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid definition id", http.StatusBadRequest)
+		return
+	}
+
+	var req RunDefinitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	idempotencyKey := r.Header.Get(idempotencyKeyHeader)
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+		return
+	}
+
+	caller := auth.FromContext(r.Context())
+	report, replayed, err := h.service.Run(r.Context(), id, caller, service.RunInput{
+		Params:         req.Params,
+		Priority:       req.Priority,
+		IdempotencyKey: idempotencyKey,
+	})
+	if errors.Is(err, model.ErrIdempotencyConflict) {
+		http.Error(w, "Idempotency key reused with different parameters", http.StatusConflict)
+		return
+	}
+	var lerr *service.LimitError
+	if errors.As(err, &lerr) {
+		writeLimitError(w, lerr)
+		return
+	}
+	if !h.checkError(w, err, "Failed to run report definition") {
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"definition_id": id,
+		"report_id":     report.ID,
+		"replayed":      replayed,
+	}).Info("Successfully ran report definition")
+
+	if replayed {
+		w.Header().Set(idempotentReplayedHeader, "true")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// checkError пишет ответ с ошибкой и возвращает false, если err не nil
+func (h *ReportDefinitionHandler) checkError(w http.ResponseWriter, err error, message string) bool {
+	var verr *reporttype.ValidationError
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &verr):
+		writeValidationError(w, verr)
+	case errors.Is(err, model.ErrDefinitionNotFound):
+		http.Error(w, "Report definition not found", http.StatusNotFound)
+	case errors.Is(err, model.ErrDefinitionNameTaken):
+		http.Error(w, "Report definition name already exists", http.StatusConflict)
+	case errors.Is(err, model.ErrForbidden):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	default:
+		log.WithError(err).Error(message)
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+	return false
+}
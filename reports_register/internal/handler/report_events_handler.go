@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/KostySCH/Reports_go/pkg/auth"
+	"github.com/KostySCH/Reports_go/reports_register/internal/events"
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+	"github.com/KostySCH/Reports_go/reports_register/internal/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type ReportEventsHandler struct {
+	service *service.ReportRequestService
+	hub     *events.Hub
+	// pollInterval — как часто перечитывать запрос, если уведомление потерялось
+	pollInterval time.Duration
+	// heartbeat — как часто отправлять комментарий, чтобы прокси не закрывали соединение
+	heartbeat time.Duration
+	// closing закрывается при остановке сервера, чтобы открытые потоки не задерживали Shutdown
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+func NewReportEventsHandler(service *service.ReportRequestService, hub *events.Hub, pollInterval, heartbeat time.Duration) *ReportEventsHandler {
+	return &ReportEventsHandler{
+		service:      service,
+		hub:          hub,
+		pollInterval: pollInterval,
+		heartbeat:    heartbeat,
+		closing:      make(chan struct{}),
+	}
+}
+
+// Close завершает все открытые потоки событий. EventSource переподключится к другому экземпляру сервиса.
+func (h *ReportEventsHandler) Close() {
+	h.closeOnce.Do(func() { close(h.closing) })
+}
+
+// @Summary Поток изменений статуса отчета
+// @Description Server-Sent Events: сразу отправляет текущее состояние, затем событие status при каждой смене статуса
+// @Description или счетчика попыток. Поток закрывается, когда запрос переходит в COMPLETED или CANCELLED, а также
+// @Description в FAILED после исчерпания попыток; до этого FAILED сменяется повторной попыткой.
+// @Description Для EventSource токен можно передать в параметре access_token.
+// @Tags reports
+// @Produce text/event-stream
+// @Param id path string true "ID отчета"
+// @Param access_token query string false "JWT, если нельзя передать заголовок Authorization"
+// @Success 200 {object} model.ReportStatusInfo "Поток событий status с данными ReportStatusInfo"
+// @Failure 400 {string} string "Invalid report id"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Report not found"
+// @Router /api/reports/{id}/events [get]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportEventsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid report id", http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// Подписываемся до первого чтения, чтобы не пропустить изменение между чтением и подпиской
+	changes, unsubscribe := h.hub.Subscribe(id)
+	defer unsubscribe()
+
+	ctx := r.Context()
+	caller := auth.FromContext(ctx)
+
+	info, err := h.service.GetStatus(ctx, id, caller)
+	switch {
+	case errors.Is(err, model.ErrNotFound):
+		http.Error(w, "Report not found", http.StatusNotFound)
+		return
+	case errors.Is(err, model.ErrForbidden):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	case err != nil:
+		log.WithError(err).WithField("report_id", id).Error("Failed to get report status")
+		http.Error(w, "Failed to get report status", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeStatusEvent(w, info); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	poll := time.NewTicker(h.pollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	last := info
+	for !last.Status.Final(last.RetryCount) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.closing:
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+			continue
+		case <-changes:
+		case <-poll.C:
+		}
+
+		info, err := h.service.GetStatus(ctx, id, caller)
+		if err != nil {
+			log.WithError(err).WithField("report_id", id).Warn("Failed to refresh report status for event stream")
+			continue
+		}
+		if info.Status == last.Status && info.RetryCount == last.RetryCount {
+			continue
+		}
+
+		if err := writeStatusEvent(w, info); err != nil {
+			return
+		}
+		flusher.Flush()
+		last = info
+	}
+}
+
+// writeStatusEvent пишет событие status; идентификатор события — время последнего изменения запроса
+func writeStatusEvent(w http.ResponseWriter, info *model.ReportStatusInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: status\ndata: %s\n\n", info.UpdatedAt.UnixNano(), data)
+	return err
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KostySCH/Reports_go/pkg/auth"
+	"github.com/KostySCH/Reports_go/pkg/memstore"
+	"github.com/KostySCH/Reports_go/pkg/queue"
+	"github.com/KostySCH/Reports_go/pkg/reporttype"
+	"github.com/KostySCH/Reports_go/reports_register/internal/events"
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+	"github.com/KostySCH/Reports_go/reports_register/internal/repository/memory"
+	"github.com/KostySCH/Reports_go/reports_register/internal/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const testUserID = 7
+
+// streamServer поднимает поток событий поверх memstore. Hub никогда не подключается к базе,
+// поэтому изменения доходят до потока только опросом.
+func streamServer(t *testing.T, store *memstore.Store) *httptest.Server {
+	t.Helper()
+	svc := service.NewReportRequestService(memory.NewReportRequestRepository(store), reporttype.Default(), nil, service.Options{})
+	hub := events.NewHub("host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+	h := NewReportEventsHandler(svc, hub, 10*time.Millisecond, time.Minute)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/reports/{id}/events", h.Stream).Methods(http.MethodGet)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := auth.WithIdentity(r.Context(), &auth.Identity{UserID: testUserID})
+		router.ServeHTTP(w, r.WithContext(ctx))
+	}))
+	t.Cleanup(func() {
+		h.Close()
+		srv.Close()
+	})
+	return srv
+}
+
+func insertRequest(store *memstore.Store, status model.ReportStatus, retryCount int) uuid.UUID {
+	id := uuid.New()
+	store.Tx(func(tx *memstore.Tx) error {
+		tx.Insert(&memstore.Request{
+			ID:         id,
+			UserID:     testUserID,
+			Type:       "branch_performance_report",
+			Params:     []byte(`{}`),
+			Status:     string(status),
+			RetryCount: retryCount,
+			CreatedAt:  tx.Now(),
+			UpdatedAt:  tx.Now(),
+		})
+		return nil
+	})
+	return id
+}
+
+func setStatus(store *memstore.Store, id uuid.UUID, status model.ReportStatus, retryCount int) {
+	store.Tx(func(tx *memstore.Tx) error {
+		r := tx.Get(id)
+		r.Status = string(status)
+		r.RetryCount = retryCount
+		r.UpdatedAt = tx.Now()
+		return nil
+	})
+}
+
+// readEvents возвращает канал с данными событий status; канал закрывается, когда сервер закрыл поток
+func readEvents(t *testing.T, srv *httptest.Server, id uuid.UUID) <-chan model.ReportStatusInfo {
+	t.Helper()
+	resp, err := http.Get(srv.URL + "/api/reports/" + id.String() + "/events")
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	out := make(chan model.ReportStatusInfo)
+	go func() {
+		defer close(out)
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var info model.ReportStatusInfo
+			if err := json.Unmarshal([]byte(data), &info); err != nil {
+				t.Errorf("decode event %q: %v", data, err)
+				return
+			}
+			out <- info
+		}
+	}()
+	return out
+}
+
+func nextEvent(t *testing.T, events <-chan model.ReportStatusInfo) (model.ReportStatusInfo, bool) {
+	t.Helper()
+	select {
+	case info, ok := <-events:
+		return info, ok
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for stream")
+		return model.ReportStatusInfo{}, false
+	}
+}
+
+func TestStreamKeepsFailedRequestWithRetriesLeftOpen(t *testing.T) {
+	store := memstore.New()
+	srv := streamServer(t, store)
+	id := insertRequest(store, model.StatusFailed, 0)
+
+	stream := readEvents(t, srv, id)
+	want := []struct {
+		status     model.ReportStatus
+		retryCount int
+	}{
+		{model.StatusFailed, 0},
+		{model.StatusInProgress, 1},
+		{model.StatusCompleted, 1},
+	}
+	for i, w := range want {
+		if i > 0 {
+			setStatus(store, id, w.status, w.retryCount)
+		}
+		info, ok := nextEvent(t, stream)
+		if !ok {
+			t.Fatalf("stream closed before event %d (%s)", i, w.status)
+		}
+		if info.Status != w.status || info.RetryCount != w.retryCount {
+			t.Fatalf("event %d = %s/%d, want %s/%d", i, info.Status, info.RetryCount, w.status, w.retryCount)
+		}
+	}
+	if _, ok := nextEvent(t, stream); ok {
+		t.Fatal("stream stayed open after COMPLETED")
+	}
+}
+
+func TestStreamClosesOnFinalStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     model.ReportStatus
+		retryCount int
+	}{
+		{name: "failed with retries exhausted", status: model.StatusFailed, retryCount: queue.MaxRetries},
+		{name: "completed", status: model.StatusCompleted},
+		{name: "cancelled", status: model.StatusCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memstore.New()
+			srv := streamServer(t, store)
+			id := insertRequest(store, tt.status, tt.retryCount)
+
+			stream := readEvents(t, srv, id)
+			info, ok := nextEvent(t, stream)
+			if !ok {
+				t.Fatal("stream closed without the current status")
+			}
+			if info.Status != tt.status {
+				t.Fatalf("status = %s, want %s", info.Status, tt.status)
+			}
+			if _, ok := nextEvent(t, stream); ok {
+				t.Fatal("stream stayed open after a final status")
+			}
+		})
+	}
+}
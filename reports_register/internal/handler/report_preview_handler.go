@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/KostySCH/Reports_go/pkg/auth"
+	"github.com/KostySCH/Reports_go/pkg/reporttype"
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+	"github.com/KostySCH/Reports_go/reports_register/internal/service"
+	"github.com/sirupsen/logrus"
+)
+
+type ReportPreviewHandler struct {
+	service *service.ReportPreviewService
+}
+
+func NewReportPreviewHandler(service *service.ReportPreviewService) *ReportPreviewHandler {
+	return &ReportPreviewHandler{service: service}
+}
+
+// PreviewReportRequest представляет запрос предпросмотра; параметры те же, что при создании отчета
+type PreviewReportRequest struct {
+	Type   string       `json:"type" example:"branch_performance_report"`
+	Params ReportParams `json:"params"`
+}
+
+// @Summary Предпросмотр данных отчета
+// @Description Синхронно собирает данные отчета и возвращает их в JSON, не формируя и не сохраняя документ. Запрос не ставится в очередь и не учитывается в ограничениях на создание отчетов
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param request body PreviewReportRequest true "Тип и параметры отчета"
+// @Success 200 {object} model.ReportPreview
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "No data for report parameters"
+// @Failure 422 {object} ValidationErrorResponse
+// @Failure 502 {string} string "Failed to collect report data"
+// @Failure 503 {string} string "Report preview is temporarily unavailable"
+// @Failure 504 {string} string "Report preview timed out"
+// @Header 503 {integer} Retry-After "Через сколько секунд можно повторить запрос"
+// @Router /api/reports/preview [post]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportPreviewHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	var req PreviewReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	caller := auth.FromContext(r.Context())
+	preview, err := h.service.Preview(r.Context(), req.Type, req.Params)
+	var verr *reporttype.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		writeValidationError(w, verr)
+		return
+	case errors.Is(err, model.ErrPreviewNoData):
+		http.Error(w, "No data for report parameters", http.StatusNotFound)
+		return
+	case errors.Is(err, model.ErrPreviewTimeout):
+		http.Error(w, "Report preview timed out", http.StatusGatewayTimeout)
+		return
+	case errors.Is(err, model.ErrPreviewUnavailable):
+		log.WithError(err).Warn("Report preview is unavailable")
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "Report preview is temporarily unavailable", http.StatusServiceUnavailable)
+		return
+	case err != nil:
+		log.WithError(err).WithField("user_id", caller.UserID).Error("Failed to collect report preview")
+		http.Error(w, "Failed to collect report data", http.StatusBadGateway)
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"type":    preview.Type,
+		"user_id": caller.UserID,
+	}).Info("Report preview collected")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preview)
+}
@@ -2,9 +2,22 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/KostySCH/Reports_go/pkg/auth"
+	"github.com/KostySCH/Reports_go/pkg/reporttype"
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
 	"github.com/KostySCH/Reports_go/reports_register/internal/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
 
@@ -24,14 +37,28 @@ func NewReportRequestHandler(service *service.ReportRequestService) *ReportReque
 	return &ReportRequestHandler{service: service}
 }
 
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
 // ReportParams представляет параметры отчета
 type ReportParams map[string]interface{}
 
-// CreateReportRequest представляет запрос на создание отчета
+// CreateReportRequest представляет запрос на создание отчета. Пользователь определяется по токену.
 type CreateReportRequest struct {
-	UserID int          `json:"user_id" example:"123" binding:"required"`
-	Type   string       `json:"type" example:"daily_report" binding:"required"`
+	Type   string       `json:"type" example:"branch_performance_report" binding:"required"`
 	Params ReportParams `json:"params" binding:"required"`
+	// Priority — приоритет от 0 до 9, по умолчанию 5; запросы с большим приоритетом генерируются раньше
+	Priority *int `json:"priority" example:"5"`
+	// CallbackURL — адрес, на который придет POST с результатом, когда отчет перейдет в COMPLETED или FAILED
+	CallbackURL string `json:"callback_url" example:"https://partner.example.com/hooks/reports"`
+	// CallbackSecret — ключ подписи: заголовок X-Reports-Signature содержит sha256=HMAC-SHA256(secret, timestamp + "." + body)
+	CallbackSecret string `json:"callback_secret" example:"s3cr3t"`
+	// Reuse — можно ли отдать готовый отчет с теми же типом и параметрами, если он завершился недавно;
+	// по умолчанию true, действует только при включенном повторном использовании
+	Reuse *bool `json:"reuse" example:"true"`
 }
 
 // @Summary Создать новый запрос на отчет
@@ -39,12 +66,25 @@ type CreateReportRequest struct {
 // @Tags reports
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повторный запрос с тем же ключом вернет исходный запрос"
 // @Param request body CreateReportRequest true "Параметры запроса"
 // @Success 200 {object} model.ReportRequest
+// @Header 200 {string} Idempotent-Replayed "true, если возвращен ранее созданный запрос"
 // @Failure 400 {string} string "Invalid request body"
+// @Failure 409 {string} string "Idempotency key reused with different parameters"
+// @Failure 422 {object} ValidationErrorResponse
+// @Failure 429 {string} string "Too many report requests"
+// @Header 429 {integer} Retry-After "Через сколько секунд можно повторить запрос"
 // @Failure 500 {string} string "Failed to create report request"
+// @Failure 503 {string} string "Report queue is full"
+// @Header 503 {integer} Retry-After "Через сколько секунд можно повторить запрос"
+// @Failure 401 {string} string "Unauthorized"
 // @Router /api/reports [post]
+// @Security BearerAuth
+// @Security ApiKeyAuth
 func (h *ReportRequestHandler) Create(w http.ResponseWriter, r *http.Request) {
+	caller := auth.FromContext(r.Context())
+
 	log.WithFields(logrus.Fields{
 		"method": r.Method,
 		"path":   r.URL.Path,
@@ -57,16 +97,47 @@ func (h *ReportRequestHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	idempotencyKey := r.Header.Get(idempotencyKeyHeader)
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+		return
+	}
+
 	log.WithFields(logrus.Fields{
-		"user_id": req.UserID,
-		"type":    req.Type,
-		"params":  req.Params,
+		"user_id":         caller.UserID,
+		"type":            req.Type,
+		"params":          req.Params,
+		"idempotency_key": idempotencyKey,
 	}).Debug("Parsed request parameters")
 
-	report, err := h.service.Create(r.Context(), req.UserID, req.Type, req.Params)
+	report, replayed, err := h.service.Create(r.Context(), service.CreateInput{
+		UserID:         caller.UserID,
+		Actor:          caller.String(),
+		SkipReuse:      req.Reuse != nil && !*req.Reuse,
+		Type:           req.Type,
+		Params:         req.Params,
+		IdempotencyKey: idempotencyKey,
+		Priority:       req.Priority,
+		CallbackURL:    req.CallbackURL,
+		CallbackSecret: req.CallbackSecret,
+	})
+	var verr *reporttype.ValidationError
+	if errors.As(err, &verr) {
+		writeValidationError(w, verr)
+		return
+	}
+	if errors.Is(err, model.ErrIdempotencyConflict) {
+		http.Error(w, "Idempotency key reused with different parameters", http.StatusConflict)
+		return
+	}
+	var lerr *service.LimitError
+	if errors.As(err, &lerr) {
+		writeLimitError(w, lerr)
+		return
+	}
 	if err != nil {
 		log.WithError(err).WithFields(logrus.Fields{
-			"user_id": req.UserID,
+			"user_id": caller.UserID,
 			"type":    req.Type,
 		}).Error("Failed to create report request")
 		http.Error(w, "Failed to create report request", http.StatusInternalServerError)
@@ -76,22 +147,522 @@ func (h *ReportRequestHandler) Create(w http.ResponseWriter, r *http.Request) {
 	log.WithFields(logrus.Fields{
 		"report_id": report.ID,
 		"status":    report.Status,
+		"replayed":  replayed,
 	}).Info("Successfully created report request")
 
+	if replayed {
+		w.Header().Set(idempotentReplayedHeader, "true")
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(report)
 }
 
+// ValidationErrorResponse представляет ответ с ошибками проверки запроса
+type ValidationErrorResponse struct {
+	Error  string                  `json:"error" example:"Validation failed"`
+	Fields []reporttype.FieldError `json:"fields"`
+}
+
+func writeValidationError(w http.ResponseWriter, verr *reporttype.ValidationError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(ValidationErrorResponse{
+		Error:  "Validation failed",
+		Fields: verr.Fields,
+	})
+}
+
+// writeLimitError отвечает 429, если исчерпана квота пользователя, или 503, если переполнена общая очередь
+func writeLimitError(w http.ResponseWriter, lerr *service.LimitError) {
+	seconds := int(math.Ceil(lerr.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+
+	status := http.StatusTooManyRequests
+	if lerr.Global {
+		status = http.StatusServiceUnavailable
+	}
+	http.Error(w, lerr.Reason, status)
+}
+
+// @Summary Получить типы отчетов
+// @Description Возвращает доступные типы отчетов и описание их параметров
+// @Tags reports
+// @Produce json
+// @Success 200 {array} reporttype.Definition
+// @Failure 401 {string} string "Unauthorized"
+// @Router /api/reports/types [get]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportRequestHandler) ListTypes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.service.Types())
+}
+
 // @Summary Получить статус отчета
-// @Description Получает текущий статус запроса на отчет
+// @Description Получает текущий статус запроса на отчет: ошибку, количество попыток, путь к файлу и ссылку на скачивание для завершенных отчетов. Для ожидающих запросов возвращается место в очереди, для ожидающих и генерирующихся — ожидаемое время готовности
 // @Tags reports
 // @Accept json
 // @Produce json
 // @Param id path string true "ID отчета"
-// @Success 200 {object} model.ReportRequest
+// @Success 200 {object} model.ReportStatusInfo
+// @Failure 400 {string} string "Invalid report id"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 404 {string} string "Report not found"
+// @Failure 500 {string} string "Failed to get report status"
 // @Router /api/reports/{id}/status [get]
+// @Security BearerAuth
+// @Security ApiKeyAuth
 func (h *ReportRequestHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement get report request status endpoint
-	http.Error(w, "Not implemented", http.StatusNotImplemented)
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid report id", http.StatusBadRequest)
+		return
+	}
+
+	info, err := h.service.GetStatus(r.Context(), id, auth.FromContext(r.Context()))
+	if errors.Is(err, model.ErrNotFound) {
+		http.Error(w, "Report not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, model.ErrForbidden) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		log.WithError(err).WithField("report_id", id).Error("Failed to get report status")
+		http.Error(w, "Failed to get report status", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// @Summary Получить историю отчета
+// @Description Возвращает историю запроса на отчет в порядке записи: смены статусов, повторные попытки с ошибками, возвраты в очередь, уведомления и доставку вебхуков, с указанием сервиса и исполнителя
+// @Tags reports
+// @Produce json
+// @Param id path string true "ID отчета"
+// @Success 200 {array} model.ReportRequestEvent
+// @Failure 400 {string} string "Invalid report id"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Report not found"
+// @Failure 500 {string} string "Failed to get report history"
+// @Router /api/reports/{id}/history [get]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportRequestHandler) History(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid report id", http.StatusBadRequest)
+		return
+	}
+
+	events, err := h.service.History(r.Context(), id, auth.FromContext(r.Context()))
+	if errors.Is(err, model.ErrNotFound) {
+		http.Error(w, "Report not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, model.ErrForbidden) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		log.WithError(err).WithField("report_id", id).Error("Failed to get report history")
+		http.Error(w, "Failed to get report history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// @Summary Получить список запросов на отчет
+// @Description Возвращает запросы на отчет с фильтрацией, сортировкой и курсорной пагинацией
+// @Tags reports
+// @Produce json
+// @Param user_id query int false "ID пользователя; без роли admin можно запросить только свои отчеты"
+// @Param status query string false "Статусы через запятую" example(PENDING,IN_PROGRESS)
+// @Param type query string false "Тип отчета"
+// @Param created_from query string false "Нижняя граница created_at (RFC3339 или YYYY-MM-DD), включительно"
+// @Param created_to query string false "Верхняя граница created_at (RFC3339 или YYYY-MM-DD), не включительно"
+// @Param sort query string false "Сортировка: created_at, -created_at, updated_at, -updated_at" default(-created_at)
+// @Param limit query int false "Размер страницы (не более 100)" default(20)
+// @Param cursor query string false "Курсор следующей страницы из next_cursor"
+// @Success 200 {object} model.ReportRequestPage
+// @Failure 400 {string} string "Invalid query parameters"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Failed to list report requests"
+// @Router /api/reports [get]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportRequestHandler) List(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseListFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.UserID, err = restrictToCaller(auth.FromContext(r.Context()), filter.UserID); err != nil {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	page, err := h.service.List(r.Context(), *filter)
+	if err != nil {
+		http.Error(w, "Failed to list report requests", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// restrictToCaller ограничивает выборку запросами пользователя caller, если он не администратор.
+// Администратор может запросить отчеты любого пользователя или всех пользователей.
+func restrictToCaller(caller *auth.Identity, userID *int) (*int, error) {
+	if caller.IsAdmin() {
+		return userID, nil
+	}
+	if userID != nil && *userID != caller.UserID {
+		return nil, model.ErrForbidden
+	}
+	return &caller.UserID, nil
+}
+
+// parseListFilter разбирает параметры запроса списка отчетов
+func parseListFilter(query url.Values) (*model.ReportRequestFilter, error) {
+	filter := &model.ReportRequestFilter{
+		Type: query.Get("type"),
+	}
+
+	if value := query.Get("user_id"); value != "" {
+		userID, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user_id: %s", value)
+		}
+		filter.UserID = &userID
+	}
+
+	if value := query.Get("status"); value != "" {
+		for _, item := range strings.Split(value, ",") {
+			status := model.ReportStatus(strings.ToUpper(strings.TrimSpace(item)))
+			if !status.Valid() {
+				return nil, fmt.Errorf("invalid status: %s", item)
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	var err error
+	if filter.CreatedFrom, err = parseTimeParam(query, "created_from"); err != nil {
+		return nil, err
+	}
+	if filter.CreatedTo, err = parseTimeParam(query, "created_to"); err != nil {
+		return nil, err
+	}
+
+	if filter.SortField, filter.SortDesc, err = model.ParseSort(query.Get("sort")); err != nil {
+		return nil, err
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid limit: %s", value)
+		}
+		filter.Limit = limit
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		if filter.Cursor, err = model.DecodeListCursor(value, filter.SortField, filter.SortDesc); err != nil {
+			return nil, err
+		}
+	}
+
+	return filter, nil
+}
+
+// parseTimeParam разбирает время в формате RFC3339 или дату YYYY-MM-DD
+func parseTimeParam(query url.Values, name string) (*time.Time, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid %s: expected RFC3339 or YYYY-MM-DD", name)
+}
+
+// CancelReportRequest представляет необязательное тело запроса на отмену отчета
+type CancelReportRequest struct {
+	Reason string `json:"reason" example:"wrong month"`
+}
+
+// @Summary Отменить запрос на отчет
+// @Description Отменяет ожидающий, выполняющийся или ожидающий повторной попытки запрос на отчет
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param id path string true "ID отчета"
+// @Param request body CancelReportRequest false "Причина отмены"
+// @Success 200 {object} model.ReportRequest
+// @Failure 400 {string} string "Invalid report id"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Report not found"
+// @Failure 409 {string} string "Report can no longer be cancelled"
+// @Failure 500 {string} string "Failed to cancel report request"
+// @Router /api/reports/{id}/cancel [post]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportRequestHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid report id", http.StatusBadRequest)
+		return
+	}
+
+	var req CancelReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.Cancel(r.Context(), id, auth.FromContext(r.Context()), req.Reason)
+	switch {
+	case errors.Is(err, model.ErrNotFound):
+		http.Error(w, "Report not found", http.StatusNotFound)
+		return
+	case errors.Is(err, model.ErrForbidden):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	case errors.Is(err, model.ErrStatusConflict):
+		http.Error(w, "Report can no longer be cancelled", http.StatusConflict)
+		return
+	case err != nil:
+		log.WithError(err).WithField("report_id", id).Error("Failed to cancel report request")
+		http.Error(w, "Failed to cancel report request", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// RetryReportRequest представляет запрос на ручной возврат отчета в очередь.
+// Инициатор повтора определяется по токену. Сбросить счетчик попыток может только администратор:
+// сброс снимает ограничение генератора на число повторов.
+type RetryReportRequest struct {
+	ResetRetryCount bool   `json:"reset_retry_count" example:"true"`
+	Reason          string `json:"reason" example:"branch data fixed"`
+}
+
+// BulkRetryReportRequest представляет запрос на возврат в очередь всех отчетов, подходящих под фильтр
+type BulkRetryReportRequest struct {
+	RetryReportRequest
+	UserID      *int       `json:"user_id" example:"123"`
+	Statuses    []string   `json:"statuses" example:"FAILED"`
+	Type        string     `json:"type" example:"branch_performance_report"`
+	CreatedFrom *time.Time `json:"created_from"`
+	CreatedTo   *time.Time `json:"created_to"`
+	Limit       int        `json:"limit" example:"100"`
+}
+
+// BulkRetryResponse представляет результат массового возврата отчетов в очередь
+type BulkRetryResponse struct {
+	Requeued int         `json:"requeued"`
+	IDs      []uuid.UUID `json:"ids"`
+}
+
+// @Summary Повторить генерацию отчета
+// @Description Возвращает неудачный или отмененный запрос в статус PENDING и записывает, кто и почему это сделал.
+// @Description Возвращенный запрос расходует квоту владельца; сбросить счетчик попыток может только администратор.
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param id path string true "ID отчета"
+// @Param request body RetryReportRequest true "Параметры повтора"
+// @Success 200 {object} model.ReportRequest
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Report not found"
+// @Failure 409 {string} string "Only FAILED or CANCELLED reports can be retried"
+// @Failure 429 {string} string "Too many report requests"
+// @Header 429 {integer} Retry-After "Через сколько секунд можно повторить запрос"
+// @Failure 500 {string} string "Failed to retry report request"
+// @Failure 503 {string} string "Report queue is full"
+// @Header 503 {integer} Retry-After "Через сколько секунд можно повторить запрос"
+// @Router /api/reports/{id}/retry [post]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportRequestHandler) Retry(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid report id", http.StatusBadRequest)
+		return
+	}
+
+	var req RetryReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	caller := auth.FromContext(r.Context())
+	if req.ResetRetryCount && !caller.IsAdmin() {
+		http.Error(w, "Only administrators can reset the retry count", http.StatusForbidden)
+		return
+	}
+
+	var lerr *service.LimitError
+	report, err := h.service.Retry(r.Context(), id, caller, service.RetryInput{
+		ResetRetryCount: req.ResetRetryCount,
+		RequestedBy:     caller.String(),
+		Reason:          req.Reason,
+	})
+	switch {
+	case errors.Is(err, model.ErrNotFound):
+		http.Error(w, "Report not found", http.StatusNotFound)
+		return
+	case errors.Is(err, model.ErrForbidden):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	case errors.Is(err, model.ErrStatusConflict):
+		http.Error(w, "Only FAILED or CANCELLED reports can be retried", http.StatusConflict)
+		return
+	case errors.As(err, &lerr):
+		writeLimitError(w, lerr)
+		return
+	case err != nil:
+		log.WithError(err).WithField("report_id", id).Error("Failed to retry report request")
+		http.Error(w, "Failed to retry report request", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// @Summary Повторить генерацию отчетов по фильтру
+// @Description Возвращает в статус PENDING неудачные (по умолчанию) или отмененные запросы, подходящие под фильтр.
+// @Description Без роли admin затрагиваются только запросы текущего пользователя и нельзя сбросить счетчик попыток.
+// @Description Каждый возвращенный запрос расходует квоту своего владельца, как запрос в пакете.
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param request body BulkRetryReportRequest true "Фильтр и параметры повтора"
+// @Success 200 {object} BulkRetryResponse
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many report requests"
+// @Header 429 {integer} Retry-After "Через сколько секунд можно повторить запрос"
+// @Failure 500 {string} string "Failed to retry report requests"
+// @Failure 503 {string} string "Report queue is full"
+// @Header 503 {integer} Retry-After "Через сколько секунд можно повторить запрос"
+// @Router /api/reports/retry [post]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportRequestHandler) RetryMatching(w http.ResponseWriter, r *http.Request) {
+	var req BulkRetryReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	caller := auth.FromContext(r.Context())
+	userID, err := restrictToCaller(caller, req.UserID)
+	if err != nil {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if req.ResetRetryCount && !caller.IsAdmin() {
+		http.Error(w, "Only administrators can reset the retry count", http.StatusForbidden)
+		return
+	}
+
+	filter := model.ReportRequestFilter{
+		UserID:      userID,
+		Type:        req.Type,
+		CreatedFrom: req.CreatedFrom,
+		CreatedTo:   req.CreatedTo,
+	}
+	for _, item := range req.Statuses {
+		filter.Statuses = append(filter.Statuses, model.ReportStatus(strings.ToUpper(item)))
+	}
+
+	ids, err := h.service.RetryMatching(r.Context(), caller, filter, req.Limit, service.RetryInput{
+		ResetRetryCount: req.ResetRetryCount,
+		RequestedBy:     caller.String(),
+		Reason:          req.Reason,
+	})
+	if errors.Is(err, model.ErrStatusConflict) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var lerr *service.LimitError
+	if errors.As(err, &lerr) {
+		writeLimitError(w, lerr)
+		return
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to retry report requests")
+		http.Error(w, "Failed to retry report requests", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BulkRetryResponse{
+		Requeued: len(ids),
+		IDs:      ids,
+	})
+}
+
+// @Summary Статистика очереди отчетов
+// @Description Возвращает для администраторов количество запросов по статусам и типам, текущую глубину очереди
+// @Description и возраст самого старого ожидающего запроса, среднее и перцентили времени от создания до COMPLETED,
+// @Description долю неудач и самые частые ошибки за окно времени
+// @Tags admin
+// @Produce json
+// @Param window query string false "Окно статистики в формате Go duration, не более 720h" default(24h)
+// @Success 200 {object} model.QueueStats
+// @Failure 400 {string} string "Invalid window"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Failed to get queue statistics"
+// @Router /api/admin/stats [get]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportRequestHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	window := service.DefaultStatsWindow
+	if value := r.URL.Query().Get("window"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 || parsed > service.MaxStatsWindow {
+			http.Error(w, "Invalid window", http.StatusBadRequest)
+			return
+		}
+		window = parsed
+	}
+
+	stats, err := h.service.Stats(r.Context(), auth.FromContext(r.Context()), window)
+	if errors.Is(err, model.ErrForbidden) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to get queue statistics")
+		http.Error(w, "Failed to get queue statistics", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
 }
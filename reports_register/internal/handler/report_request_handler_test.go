@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KostySCH/Reports_go/pkg/auth"
+	"github.com/KostySCH/Reports_go/pkg/memstore"
+	"github.com/KostySCH/Reports_go/pkg/reporttype"
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+	"github.com/KostySCH/Reports_go/reports_register/internal/repository/memory"
+	"github.com/KostySCH/Reports_go/reports_register/internal/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func TestWriteLimitError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            *service.LimitError
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{name: "user quota", err: &service.LimitError{Reason: "quota", RetryAfter: 30 * time.Second}, wantStatus: http.StatusTooManyRequests, wantRetryAfter: "30"},
+		{name: "queue full", err: &service.LimitError{Global: true, Reason: "full", RetryAfter: time.Minute}, wantStatus: http.StatusServiceUnavailable, wantRetryAfter: "60"},
+		{name: "fraction rounds up", err: &service.LimitError{Reason: "quota", RetryAfter: 1200 * time.Millisecond}, wantStatus: http.StatusTooManyRequests, wantRetryAfter: "2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			writeLimitError(w, tt.err)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+			if got := strings.TrimSpace(w.Body.String()); got != tt.err.Reason {
+				t.Errorf("body = %q, want %q", got, tt.err.Reason)
+			}
+		})
+	}
+}
+
+// retryRouter обслуживает повтор отчетов от имени caller
+func retryRouter(store *memstore.Store, limits service.Limits, caller *auth.Identity) http.Handler {
+	repo := memory.NewReportRequestRepository(store)
+	svc := service.NewReportRequestService(repo, reporttype.Default(), service.NewLimiter(repo, limits), service.Options{})
+	h := NewReportRequestHandler(svc)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/reports/retry", h.RetryMatching).Methods(http.MethodPost)
+	router.HandleFunc("/api/reports/{id}/retry", h.Retry).Methods(http.MethodPost)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		router.ServeHTTP(w, r.WithContext(auth.WithIdentity(r.Context(), caller)))
+	})
+}
+
+func retryCountOf(store *memstore.Store, id uuid.UUID) (model.ReportStatus, int) {
+	var (
+		status model.ReportStatus
+		count  int
+	)
+	store.Tx(func(tx *memstore.Tx) error {
+		row := tx.Get(id)
+		status, count = model.ReportStatus(row.Status), row.RetryCount
+		return nil
+	})
+	return status, count
+}
+
+func TestRetryResetRetryCountRequiresAdmin(t *testing.T) {
+	user := &auth.Identity{UserID: testUserID}
+	admin := &auth.Identity{UserID: 1, Roles: []string{auth.RoleAdmin}}
+	tests := []struct {
+		name       string
+		caller     *auth.Identity
+		bulk       bool
+		body       string
+		wantStatus int
+		wantRetry  int
+	}{
+		{name: "user keeps retry count", caller: user, body: `{}`, wantStatus: http.StatusOK, wantRetry: 5},
+		{name: "user resets retry count", caller: user, body: `{"reset_retry_count":true}`, wantStatus: http.StatusForbidden, wantRetry: 5},
+		{name: "admin resets retry count", caller: admin, body: `{"reset_retry_count":true}`, wantStatus: http.StatusOK},
+		{name: "bulk user keeps retry count", caller: user, bulk: true, body: `{}`, wantStatus: http.StatusOK, wantRetry: 5},
+		{name: "bulk user resets retry count", caller: user, bulk: true, body: `{"reset_retry_count":true}`, wantStatus: http.StatusForbidden, wantRetry: 5},
+		{name: "bulk admin resets retry count", caller: admin, bulk: true, body: `{"reset_retry_count":true}`, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memstore.New()
+			id := insertRequest(store, model.StatusFailed, 5)
+
+			path := "/api/reports/" + id.String() + "/retry"
+			if tt.bulk {
+				path = "/api/reports/retry"
+			}
+			w := httptest.NewRecorder()
+			retryRouter(store, service.Limits{}, tt.caller).ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(tt.body)))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			wantStatus := model.StatusPending
+			if tt.wantStatus != http.StatusOK {
+				wantStatus = model.StatusFailed
+			}
+			if status, retry := retryCountOf(store, id); status != wantStatus || retry != tt.wantRetry {
+				t.Errorf("request = %s/%d, want %s/%d", status, retry, wantStatus, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestRetryMatchingAppliesQuotaByItemCount(t *testing.T) {
+	store := memstore.New()
+	for i := 0; i < 3; i++ {
+		insertRequest(store, model.StatusFailed, 1)
+	}
+	caller := &auth.Identity{UserID: testUserID}
+
+	w := httptest.NewRecorder()
+	retryRouter(store, service.Limits{MaxActivePerUser: 2}, caller).
+		ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/reports/retry", strings.NewReader(`{}`)))
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusTooManyRequests, w.Body)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Retry-After header is missing")
+	}
+}
@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/KostySCH/Reports_go/pkg/auth"
+	"github.com/KostySCH/Reports_go/pkg/reporttype"
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+	"github.com/KostySCH/Reports_go/reports_register/internal/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type ReportScheduleHandler struct {
+	service *service.ReportScheduleService
+}
+
+func NewReportScheduleHandler(service *service.ReportScheduleService) *ReportScheduleHandler {
+	return &ReportScheduleHandler{service: service}
+}
+
+// ReportScheduleRequest представляет тело запроса на создание или изменение расписания.
+// В строковых значениях params_template можно использовать относительные периоды:
+// {{today}}, {{yesterday}}, {{current_month}}, {{previous_month}}, {{current_year}}, {{previous_year}}.
+// Владельцем расписания становится пользователь из токена.
+type ReportScheduleRequest struct {
+	Name           string       `json:"name" example:"Monthly branch 12 report" binding:"required"`
+	Cron           string       `json:"cron" example:"0 6 1 * *" binding:"required"`
+	Timezone       string       `json:"timezone" example:"Europe/Moscow"`
+	Type           string       `json:"type" example:"branch_performance_report" binding:"required"`
+	ParamsTemplate ReportParams `json:"params_template" binding:"required"`
+	Enabled        *bool        `json:"enabled" example:"true"`
+	Priority       *int         `json:"priority" example:"5"`
+}
+
+func (req ReportScheduleRequest) input(ownerID int) service.ScheduleInput {
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	return service.ScheduleInput{
+		Name:           req.Name,
+		OwnerID:        ownerID,
+		Cron:           req.Cron,
+		Timezone:       req.Timezone,
+		Type:           req.Type,
+		ParamsTemplate: req.ParamsTemplate,
+		Enabled:        enabled,
+		Priority:       req.Priority,
+	}
+}
+
+// @Summary Создать расписание отчета
+// @Description Создает расписание регулярной генерации отчета по cron-выражению
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Param request body ReportScheduleRequest true "Параметры расписания"
+// @Success 201 {object} model.ReportSchedule
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 422 {object} ValidationErrorResponse
+// @Failure 500 {string} string "Failed to create report schedule"
+// @Router /api/schedules [post]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportScheduleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req ReportScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	sched, err := h.service.Create(r.Context(), req.input(auth.FromContext(r.Context()).UserID))
+	var verr *reporttype.ValidationError
+	if errors.As(err, &verr) {
+		writeValidationError(w, verr)
+		return
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to create report schedule")
+		http.Error(w, "Failed to create report schedule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sched)
+}
+
+// @Summary Получить список расписаний
+// @Tags schedules
+// @Produce json
+// @Param owner_id query int false "ID владельца; без роли admin можно запросить только свои расписания"
+// @Success 200 {array} model.ReportSchedule
+// @Failure 400 {string} string "Invalid owner_id"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Failed to list report schedules"
+// @Router /api/schedules [get]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportScheduleHandler) List(w http.ResponseWriter, r *http.Request) {
+	var ownerID *int
+	if value := r.URL.Query().Get("owner_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid owner_id", http.StatusBadRequest)
+			return
+		}
+		ownerID = &id
+	}
+
+	ownerID, err := restrictToCaller(auth.FromContext(r.Context()), ownerID)
+	if err != nil {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	schedules, err := h.service.List(r.Context(), ownerID)
+	if err != nil {
+		log.WithError(err).Error("Failed to list report schedules")
+		http.Error(w, "Failed to list report schedules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedules)
+}
+
+// @Summary Получить расписание
+// @Tags schedules
+// @Produce json
+// @Param id path string true "ID расписания"
+// @Success 200 {object} model.ReportSchedule
+// @Failure 400 {string} string "Invalid schedule id"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Schedule not found"
+// @Router /api/schedules/{id} [get]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportScheduleHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid schedule id", http.StatusBadRequest)
+		return
+	}
+
+	sched, err := h.service.Get(r.Context(), id, auth.FromContext(r.Context()))
+	if !h.checkError(w, err, "Failed to get report schedule") {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sched)
+}
+
+// @Summary Изменить расписание
+// @Description Заменяет настройки расписания и пересчитывает время следующего запуска
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Param id path string true "ID расписания"
+// @Param request body ReportScheduleRequest true "Параметры расписания"
+// @Success 200 {object} model.ReportSchedule
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Schedule not found"
+// @Failure 422 {object} ValidationErrorResponse
+// @Router /api/schedules/{id} [put]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportScheduleHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid schedule id", http.StatusBadRequest)
+		return
+	}
+
+	var req ReportScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	caller := auth.FromContext(r.Context())
+	sched, err := h.service.Update(r.Context(), id, caller, req.input(caller.UserID))
+	if !h.checkError(w, err, "Failed to update report schedule") {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sched)
+}
+
+// @Summary Удалить расписание
+// @Tags schedules
+// @Param id path string true "ID расписания"
+// @Success 204
+// @Failure 400 {string} string "Invalid schedule id"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Schedule not found"
+// @Router /api/schedules/{id} [delete]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReportScheduleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid schedule id", http.StatusBadRequest)
+		return
+	}
+
+	if !h.checkError(w, h.service.Delete(r.Context(), id, auth.FromContext(r.Context())), "Failed to delete report schedule") {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkError пишет ответ с ошибкой и возвращает false, если err не nil
+func (h *ReportScheduleHandler) checkError(w http.ResponseWriter, err error, message string) bool {
+	var verr *reporttype.ValidationError
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &verr):
+		writeValidationError(w, verr)
+	case errors.Is(err, model.ErrScheduleNotFound):
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+	case errors.Is(err, model.ErrForbidden):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	default:
+		log.WithError(err).Error(message)
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JSON хранит JSONB-значение и отдается клиенту как вложенный JSON, а не base64.
+// Пустое значение записывается в базу как NULL и сериализуется как null.
+type JSON json.RawMessage
+
+// Value реализует driver.Valuer: пустое значение записывается как NULL
+func (j JSON) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return nil, nil
+	}
+	return json.RawMessage(j).MarshalJSON()
+}
+
+// Scan реализует sql.Scanner для колонок JSONB, которые lib/pq возвращает как []byte
+func (j *JSON) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append((*j)[0:0], v...)
+	case string:
+		*j = append((*j)[0:0], v...)
+	default:
+		return fmt.Errorf("model.JSON: unsupported Scan type %T", value)
+	}
+	return nil
+}
+
+// MarshalJSON возвращает значение как есть; пустое значение сериализуется как null
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return json.RawMessage(j).MarshalJSON()
+}
+
+// UnmarshalJSON сохраняет копию data без разбора
+func (j *JSON) UnmarshalJSON(data []byte) error {
+	if j == nil {
+		return fmt.Errorf("model.JSON: UnmarshalJSON on nil pointer")
+	}
+	*j = append((*j)[0:0], data...)
+	return nil
+}
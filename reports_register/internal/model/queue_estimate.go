@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// QueueSnapshot описывает очередь генерации перед ожидающим запросом
+type QueueSnapshot struct {
+	// Position — место запроса среди PENDING в порядке выборки генератором, начиная с 1
+	Position int
+	// Ahead — количество ожидающих запросов перед ним по типам отчетов
+	Ahead map[string]int
+	// Running — запросы, которые генерируются сейчас
+	Running []RunningRequest
+}
+
+// RunningRequest — запрос в статусе IN_PROGRESS
+type RunningRequest struct {
+	Type      string
+	StartedAt time.Time
+}
+
+// GenerationTime — средняя длительность генерации отчетов одного типа
+type GenerationTime struct {
+	Type       string
+	Count      int
+	AvgSeconds float64
+}
@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// QueueStats описывает состояние очереди и пропускную способность генерации за окно времени
+type QueueStats struct {
+	// From и To — границы окна; счетчики по статусам и типам считаются по запросам, созданным в окне
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+
+	ByStatus map[ReportStatus]int `json:"by_status"`
+	ByType   []TypeStats          `json:"by_type"`
+
+	// Pending и InProgress — текущая глубина очереди без учета окна
+	Pending    int `json:"pending"`
+	InProgress int `json:"in_progress"`
+	// OldestPendingAgeSeconds — возраст самого старого ожидающего запроса; 0, если очередь пуста
+	OldestPendingAgeSeconds float64 `json:"oldest_pending_age_seconds"`
+
+	Completion CompletionStats `json:"completion"`
+
+	// Completed, Failed и FailureRate считаются по запросам, завершенным в окне;
+	// FailureRate = Failed / (Completed + Failed)
+	Completed   int     `json:"completed"`
+	Failed      int     `json:"failed"`
+	FailureRate float64 `json:"failure_rate"`
+
+	TopErrors []ErrorStats `json:"top_errors"`
+}
+
+// TypeStats содержит количество запросов одного типа по статусам
+type TypeStats struct {
+	Type     string               `json:"type" example:"branch_performance_report"`
+	Total    int                  `json:"total"`
+	ByStatus map[ReportStatus]int `json:"by_status"`
+}
+
+// CompletionStats описывает время от создания запроса до перехода в COMPLETED в секундах.
+// Запросы, получившие готовый результат без генерации, не учитываются.
+type CompletionStats struct {
+	Count int     `json:"count"`
+	Avg   float64 `json:"avg_seconds"`
+	P50   float64 `json:"p50_seconds"`
+	P90   float64 `json:"p90_seconds"`
+	P95   float64 `json:"p95_seconds"`
+	P99   float64 `json:"p99_seconds"`
+	Max   float64 `json:"max_seconds"`
+}
+
+// ErrorStats — текст ошибки и количество запросов, завершившихся с ней
+type ErrorStats struct {
+	Error string `json:"error"`
+	Count int    `json:"count"`
+}
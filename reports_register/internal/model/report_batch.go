@@ -0,0 +1,83 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrBatchNotFound возвращается, если пакет запросов не найден
+var ErrBatchNotFound = errors.New("report batch not found")
+
+// BatchStatus — сводный статус пакета запросов на отчет
+type BatchStatus string
+
+const (
+	BatchPending            BatchStatus = "PENDING"
+	BatchInProgress         BatchStatus = "IN_PROGRESS"
+	BatchCompleted          BatchStatus = "COMPLETED"
+	BatchPartiallyCompleted BatchStatus = "PARTIALLY_COMPLETED"
+	BatchFailed             BatchStatus = "FAILED"
+	BatchCancelled          BatchStatus = "CANCELLED"
+)
+
+// ReportBatch представляет пакет запросов на отчет, созданных одним вызовом
+type ReportBatch struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	Total     int       `json:"total" db:"total"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// BatchItemStatus представляет статус одного запроса из пакета
+type BatchItemStatus struct {
+	ID     uuid.UUID    `json:"id"`
+	Type   string       `json:"type"`
+	Params JSON         `json:"params" swaggertype:"object"`
+	Status ReportStatus `json:"status"`
+	Error  *string      `json:"error,omitempty"`
+}
+
+// ReportBatchStatus представляет сводное состояние пакета запросов
+type ReportBatchStatus struct {
+	ReportBatch
+	Status   BatchStatus          `json:"status"`
+	Done     bool                 `json:"done"`
+	Counts   map[ReportStatus]int `json:"counts"`
+	Requests []BatchItemStatus    `json:"requests"`
+}
+
+// NewReportBatchStatus вычисляет сводный статус пакета по статусам его запросов.
+// Пакет без запросов считается ожидающим и не завершенным.
+func NewReportBatchStatus(batch *ReportBatch, items []BatchItemStatus) *ReportBatchStatus {
+	counts := make(map[ReportStatus]int)
+	for _, item := range items {
+		counts[item.Status]++
+	}
+
+	active := counts[StatusPending] + counts[StatusInProgress]
+	status := &ReportBatchStatus{
+		ReportBatch: *batch,
+		Done:        len(items) > 0 && active == 0,
+		Counts:      counts,
+		Requests:    items,
+	}
+
+	switch {
+	case counts[StatusPending] == len(items):
+		status.Status = BatchPending
+	case active > 0:
+		status.Status = BatchInProgress
+	case counts[StatusCompleted] == len(items):
+		status.Status = BatchCompleted
+	case counts[StatusCompleted] > 0:
+		status.Status = BatchPartiallyCompleted
+	case counts[StatusCancelled] == len(items):
+		status.Status = BatchCancelled
+	default:
+		status.Status = BatchFailed
+	}
+
+	return status
+}
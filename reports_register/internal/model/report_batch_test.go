@@ -0,0 +1,56 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNewReportBatchStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []ReportStatus
+		want     BatchStatus
+		wantDone bool
+	}{
+		{name: "empty", want: BatchPending},
+		{name: "all pending", statuses: []ReportStatus{StatusPending, StatusPending}, want: BatchPending},
+		{name: "pending and in progress", statuses: []ReportStatus{StatusPending, StatusInProgress}, want: BatchInProgress},
+		{name: "completed and pending", statuses: []ReportStatus{StatusCompleted, StatusPending}, want: BatchInProgress},
+		{name: "failed and in progress", statuses: []ReportStatus{StatusFailed, StatusInProgress}, want: BatchInProgress},
+		{name: "all completed", statuses: []ReportStatus{StatusCompleted, StatusCompleted}, want: BatchCompleted, wantDone: true},
+		{name: "completed and failed", statuses: []ReportStatus{StatusCompleted, StatusFailed}, want: BatchPartiallyCompleted, wantDone: true},
+		{name: "completed and cancelled", statuses: []ReportStatus{StatusCancelled, StatusCompleted}, want: BatchPartiallyCompleted, wantDone: true},
+		{name: "all cancelled", statuses: []ReportStatus{StatusCancelled, StatusCancelled}, want: BatchCancelled, wantDone: true},
+		{name: "all failed", statuses: []ReportStatus{StatusFailed, StatusFailed}, want: BatchFailed, wantDone: true},
+		{name: "failed and cancelled", statuses: []ReportStatus{StatusFailed, StatusCancelled}, want: BatchFailed, wantDone: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batch := &ReportBatch{ID: uuid.New(), UserID: 7, Total: len(tt.statuses)}
+			items := make([]BatchItemStatus, 0, len(tt.statuses))
+			for _, status := range tt.statuses {
+				items = append(items, BatchItemStatus{ID: uuid.New(), Type: "branch_performance", Status: status})
+			}
+
+			got := NewReportBatchStatus(batch, items)
+			if got.Status != tt.want {
+				t.Errorf("status = %s, want %s", got.Status, tt.want)
+			}
+			if got.Done != tt.wantDone {
+				t.Errorf("done = %v, want %v", got.Done, tt.wantDone)
+			}
+			if got.ID != batch.ID || len(got.Requests) != len(items) {
+				t.Errorf("batch = %+v with %d requests, want %+v with %d", got.ReportBatch, len(got.Requests), *batch, len(items))
+			}
+
+			counted := 0
+			for _, n := range got.Counts {
+				counted += n
+			}
+			if counted != len(items) {
+				t.Errorf("counts %v cover %d requests, want %d", got.Counts, counted, len(items))
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrDefinitionNotFound возвращается, если определение отчета не найдено или недоступно пользователю
+	ErrDefinitionNotFound = errors.New("report definition not found")
+	// ErrDefinitionNameTaken возвращается, если у владельца уже есть определение с таким именем
+	ErrDefinitionNameTaken = errors.New("report definition name already exists")
+)
+
+// ReportDefinition — сохраненный пользователем запрос на отчет, который можно запускать повторно
+type ReportDefinition struct {
+	ID       uuid.UUID `json:"id" db:"id"`
+	Name     string    `json:"name" db:"name"`
+	OwnerID  int       `json:"owner_id" db:"owner_id"`
+	Type     string    `json:"type" db:"type"`
+	Params   JSON      `json:"params" db:"params" swaggertype:"object"`
+	Priority int       `json:"priority" db:"priority"`
+	// CallbackURL и CallbackSecret передаются в каждый созданный по определению запрос
+	CallbackURL *string `json:"callback_url,omitempty" db:"callback_url"`
+	// CallbackSecret подписывает вебхук и никогда не возвращается клиенту
+	CallbackSecret *string `json:"-" db:"callback_secret"`
+	// Reuse — можно ли при запуске отдать готовый отчет с теми же типом и параметрами
+	Reuse bool `json:"reuse" db:"reuse"`
+	// SharedWith — пользователи, которые могут просматривать и запускать определение
+	SharedWith []int     `json:"shared_with" db:"-"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CanRun сообщает, может ли пользователь userID просматривать и запускать определение
+func (d *ReportDefinition) CanRun(userID int) bool {
+	if d.OwnerID == userID {
+		return true
+	}
+	for _, id := range d.SharedWith {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
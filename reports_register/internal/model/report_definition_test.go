@@ -0,0 +1,27 @@
+package model
+
+import "testing"
+
+func TestReportDefinitionCanRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		sharedWith []int
+		userID     int
+		want       bool
+	}{
+		{name: "owner", userID: 7, want: true},
+		{name: "owner listed as shared", sharedWith: []int{7}, userID: 7, want: true},
+		{name: "shared user", sharedWith: []int{3, 9}, userID: 9, want: true},
+		{name: "other user", sharedWith: []int{3, 9}, userID: 4},
+		{name: "not shared", userID: 4},
+		{name: "zero user id", userID: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			definition := &ReportDefinition{OwnerID: 7, SharedWith: tt.sharedWith}
+			if got := definition.CanRun(tt.userID); got != tt.want {
+				t.Errorf("CanRun(%d) = %v, want %v", tt.userID, got, tt.want)
+			}
+		})
+	}
+}
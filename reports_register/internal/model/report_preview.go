@@ -0,0 +1,25 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrPreviewNoData — для параметров предпросмотра нет данных, например филиал не существует
+	ErrPreviewNoData = errors.New("no data for report parameters")
+	// ErrPreviewTimeout — сбор данных не уложился в отведенное время
+	ErrPreviewTimeout = errors.New("report preview timed out")
+	// ErrPreviewUnavailable — генератор недоступен или занят другими предпросмотрами
+	ErrPreviewUnavailable = errors.New("report preview is temporarily unavailable")
+)
+
+// ReportPreview — данные отчета, собранные без формирования и сохранения документа
+type ReportPreview struct {
+	Type   string `json:"type" example:"branch_performance_report"`
+	Params JSON   `json:"params" swaggertype:"object"`
+	// Data — данные в том виде, в каком они легли бы в документ; структура зависит от типа отчета
+	Data        json.RawMessage `json:"data" swaggertype:"object"`
+	GeneratedAt time.Time       `json:"generated_at"`
+}
@@ -1,11 +1,36 @@
 package model
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"time"
 
+	"github.com/KostySCH/Reports_go/pkg/queue"
 	"github.com/google/uuid"
 )
 
+var (
+	// ErrNotFound возвращается, если запрос на отчет не найден
+	ErrNotFound = errors.New("report request not found")
+	// ErrDuplicateIdempotencyKey возвращается при вставке запроса с уже занятым ключом идемпотентности
+	ErrDuplicateIdempotencyKey = errors.New("duplicate idempotency key")
+	// ErrIdempotencyConflict возвращается, если ключ идемпотентности повторно использован с другими параметрами
+	ErrIdempotencyConflict = errors.New("idempotency key reused with different parameters")
+	// ErrStatusConflict возвращается, если действие недопустимо в текущем статусе запроса
+	ErrStatusConflict = errors.New("action is not allowed in the current report status")
+	// ErrForbidden возвращается, если пользователь не владелец ресурса и не администратор
+	ErrForbidden = errors.New("access denied")
+)
+
+// Приоритеты запросов: генератор берет ожидающие запросы по убыванию приоритета, затем по времени создания
+const (
+	MinPriority     = 0
+	MaxPriority     = 9
+	DefaultPriority = 5
+)
+
 // ReportStatus представляет возможные статусы отчета
 type ReportStatus string
 
@@ -14,17 +39,101 @@ const (
 	StatusInProgress ReportStatus = "IN_PROGRESS"
 	StatusCompleted  ReportStatus = "COMPLETED"
 	StatusFailed     ReportStatus = "FAILED"
+	StatusCancelled  ReportStatus = "CANCELLED"
 )
 
+// Final сообщает, что запрос с retryCount попытками больше не изменит статус без участия
+// пользователя. FAILED окончателен, только когда генератор исчерпал попытки: до этого
+// запрос вернется в обработку.
+func (s ReportStatus) Final(retryCount int) bool {
+	switch s {
+	case StatusCompleted, StatusCancelled:
+		return true
+	case StatusFailed:
+		return queue.RetriesExhausted(retryCount)
+	}
+	return false
+}
+
+// Valid проверяет, что статус входит в список известных
+func (s ReportStatus) Valid() bool {
+	switch s {
+	case StatusPending, StatusInProgress, StatusCompleted, StatusFailed, StatusCancelled:
+		return true
+	}
+	return false
+}
+
 // ReportRequest представляет запрос на генерацию отчета
 type ReportRequest struct {
-	ID        uuid.UUID    `json:"id" db:"id"`
-	UserID    int          `json:"user_id" db:"user_id"`
-	Type      string       `json:"type" db:"type"`
-	Params    []byte       `json:"params" db:"params"`
-	Status    ReportStatus `json:"status" db:"status"`
-	CreatedAt time.Time    `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time    `json:"updated_at" db:"updated_at"`
+	ID         uuid.UUID    `json:"id" db:"id"`
+	UserID     int          `json:"user_id" db:"user_id"`
+	Type       string       `json:"type" db:"type"`
+	Params     JSON         `json:"params" db:"params" swaggertype:"object"`
+	Status     ReportStatus `json:"status" db:"status"`
+	Error      *string      `json:"error,omitempty" db:"error"`
+	RetryCount int          `json:"retry_count" db:"retry_count"`
+	ReportPath *string      `json:"report_path,omitempty" db:"report_path"`
+	CreatedAt  time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at" db:"updated_at"`
+
+	IdempotencyKey *string    `json:"idempotency_key,omitempty" db:"idempotency_key"`
+	RequeuedBy     *string    `json:"requeued_by,omitempty" db:"requeued_by"`
+	RequeueReason  *string    `json:"requeue_reason,omitempty" db:"requeue_reason"`
+	RequeuedAt     *time.Time `json:"requeued_at,omitempty" db:"requeued_at"`
+	ScheduleID     *uuid.UUID `json:"schedule_id,omitempty" db:"schedule_id"`
+	ScheduledFor   *time.Time `json:"scheduled_for,omitempty" db:"scheduled_for"`
+	BatchID        *uuid.UUID `json:"batch_id,omitempty" db:"batch_id"`
+	Priority       int        `json:"priority" db:"priority"`
+	CallbackURL    *string    `json:"callback_url,omitempty" db:"callback_url"`
+	// CallbackSecret подписывает вебхук и никогда не возвращается клиенту
+	CallbackSecret *string `json:"-" db:"callback_secret"`
+	// ReusedFrom — завершенный запрос, чей результат использован вместо повторной генерации
+	ReusedFrom *uuid.UUID `json:"reused_from,omitempty" db:"reused_from"`
+	// CompletedAt — когда сформирован результат; у повторно использованного результата — время источника
+	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+// ParamsHash возвращает sha256 от типа отчета и канонического JSON параметров
+// (ключи отсортированы, без пробелов); совпадение хеша означает одинаковый отчет
+func ParamsHash(reportType string, params JSON) (string, error) {
+	var value interface{}
+	if err := json.Unmarshal(params, &value); err != nil {
+		return "", err
+	}
+	canonical, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(append([]byte(reportType+"\n"), canonical...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ReportStatusInfo представляет состояние запроса на отчет для клиента
+type ReportStatusInfo struct {
+	ID          uuid.UUID    `json:"id"`
+	Type        string       `json:"type"`
+	Status      ReportStatus `json:"status"`
+	Error       *string      `json:"error,omitempty"`
+	RetryCount  int          `json:"retry_count"`
+	Priority    int          `json:"priority"`
+	ReportPath  *string      `json:"report_path,omitempty"`
+	DownloadURL string       `json:"download_url,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+
+	RequeuedBy    *string    `json:"requeued_by,omitempty"`
+	RequeueReason *string    `json:"requeue_reason,omitempty"`
+	RequeuedAt    *time.Time `json:"requeued_at,omitempty"`
+	ReusedFrom    *uuid.UUID `json:"reused_from,omitempty"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+
+	// QueuePosition — место ожидающего запроса в очереди генерации, начиная с 1
+	QueuePosition *int `json:"queue_position,omitempty"`
+	// EstimatedCompletionAt — ожидаемое время готовности по недавним длительностям генерации;
+	// отсутствует, если оценить его не по чему
+	EstimatedCompletionAt *time.Time `json:"estimated_completion_at,omitempty"`
 }
 
 // NewReportRequest создает новый запрос на отчет
@@ -35,7 +144,7 @@ func NewReportRequest(userID uuid.UUID, reportType string, params []byte) *Repor
 		UserID:    int(userID.ID()),
 		Status:    StatusPending,
 		Type:      reportType,
-		Params:    params,
+		Params:    JSON(params),
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
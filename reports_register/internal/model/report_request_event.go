@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReportRequestEvent — запись истории запроса на отчет: смена статуса, повторная попытка,
+// возврат в очередь, уведомление или доставка вебхука
+type ReportRequestEvent struct {
+	ID         int64         `json:"id" db:"id"`
+	RequestID  uuid.UUID     `json:"request_id" db:"request_id"`
+	EventType  string        `json:"event_type" db:"event_type" example:"STATUS_CHANGED"`
+	FromStatus *ReportStatus `json:"from_status,omitempty" db:"from_status" example:"IN_PROGRESS"`
+	ToStatus   *ReportStatus `json:"to_status,omitempty" db:"to_status" example:"FAILED"`
+	RetryCount *int          `json:"retry_count,omitempty" db:"retry_count"`
+	Error      *string       `json:"error,omitempty" db:"error"`
+	// Actor — пользователь (user:<id>, apikey:<name>), расписание или воркер (<host>:<pid>/<name>)
+	Actor string `json:"actor" db:"actor" example:"host-1:4242/generator-worker-1"`
+	// Source — сервис, записавший событие
+	Source    string    `json:"source" db:"source" example:"reports_generator"`
+	Details   JSON      `json:"details,omitempty" db:"details" swaggertype:"object"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+// SortField определяет колонку, по которой упорядочивается список запросов
+type SortField string
+
+const (
+	SortByCreatedAt SortField = "created_at"
+	SortByUpdatedAt SortField = "updated_at"
+)
+
+// ErrInvalidCursor возвращается, если курсор пагинации поврежден или не соответствует сортировке
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ReportRequestFilter описывает фильтры, сортировку и пагинацию списка запросов на отчет
+type ReportRequestFilter struct {
+	UserID      *int
+	Statuses    []ReportStatus
+	Type        string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	SortField   SortField
+	SortDesc    bool
+	Limit       int
+	Cursor      *ListCursor
+}
+
+// ParseSort разбирает параметр сортировки вида "created_at" или "-created_at"
+func ParseSort(value string) (SortField, bool, error) {
+	if value == "" {
+		return SortByCreatedAt, true, nil
+	}
+
+	desc := strings.HasPrefix(value, "-")
+	field := SortField(strings.TrimPrefix(value, "-"))
+	switch field {
+	case SortByCreatedAt, SortByUpdatedAt:
+		return field, desc, nil
+	}
+	return "", false, fmt.Errorf("unsupported sort field: %s", field)
+}
+
+// ListCursor указывает на последнюю запись предыдущей страницы
+type ListCursor struct {
+	Sort  SortField `json:"s"`
+	Desc  bool      `json:"d"`
+	Value time.Time `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+// NewListCursor создает курсор, указывающий на переданный запрос
+func NewListCursor(request *ReportRequest, field SortField, desc bool) *ListCursor {
+	value := request.CreatedAt
+	if field == SortByUpdatedAt {
+		value = request.UpdatedAt
+	}
+	return &ListCursor{Sort: field, Desc: desc, Value: value, ID: request.ID}
+}
+
+// Encode кодирует курсор в непрозрачную строку для клиента
+func (c *ListCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeListCursor восстанавливает курсор и проверяет, что он выдан для той же сортировки
+func DecodeListCursor(value string, field SortField, desc bool) (*ListCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor ListCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Sort != field || cursor.Desc != desc || cursor.ID == uuid.Nil {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// ReportRequestPage представляет одну страницу списка запросов на отчет
+type ReportRequestPage struct {
+	Items      []*ReportRequest `json:"items"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
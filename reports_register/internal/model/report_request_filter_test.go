@@ -0,0 +1,93 @@
+package model
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestListCursorRoundTrip(t *testing.T) {
+	request := &ReportRequest{
+		ID:        uuid.New(),
+		CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC),
+		UpdatedAt: time.Date(2024, 5, 2, 8, 30, 0, 0, time.FixedZone("MSK", 3*60*60)),
+	}
+	tests := []struct {
+		field     SortField
+		desc      bool
+		wantValue time.Time
+	}{
+		{SortByCreatedAt, false, request.CreatedAt},
+		{SortByCreatedAt, true, request.CreatedAt},
+		{SortByUpdatedAt, false, request.UpdatedAt},
+		{SortByUpdatedAt, true, request.UpdatedAt},
+	}
+	for _, tt := range tests {
+		encoded := NewListCursor(request, tt.field, tt.desc).Encode()
+
+		cursor, err := DecodeListCursor(encoded, tt.field, tt.desc)
+		if err != nil {
+			t.Fatalf("DecodeListCursor(%s, desc=%v): %v", tt.field, tt.desc, err)
+		}
+		if cursor.ID != request.ID || !cursor.Value.Equal(tt.wantValue) {
+			t.Errorf("cursor(%s, desc=%v) = %v/%v, want %v/%v", tt.field, tt.desc, cursor.ID, cursor.Value, request.ID, tt.wantValue)
+		}
+	}
+}
+
+func TestDecodeListCursorRejectsMalformed(t *testing.T) {
+	valid := NewListCursor(&ReportRequest{ID: uuid.New(), CreatedAt: time.Now()}, SortByCreatedAt, true).Encode()
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name  string
+		value string
+		field SortField
+		desc  bool
+	}{
+		{name: "not base64", value: "not a cursor!", field: SortByCreatedAt, desc: true},
+		{name: "padded base64", value: valid + "==", field: SortByCreatedAt, desc: true},
+		{name: "not json", value: encode("created_at"), field: SortByCreatedAt, desc: true},
+		{name: "wrong json types", value: encode(`{"s":"created_at","d":true,"v":"yesterday","id":"x"}`), field: SortByCreatedAt, desc: true},
+		{name: "missing id", value: encode(`{"s":"created_at","d":true,"v":"2024-05-01T12:00:00Z"}`), field: SortByCreatedAt, desc: true},
+		{name: "other sort field", value: valid, field: SortByUpdatedAt, desc: true},
+		{name: "other direction", value: valid, field: SortByCreatedAt, desc: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := DecodeListCursor(tt.value, tt.field, tt.desc)
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeListCursor = %+v, %v; want %v", cursor, err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		value     string
+		wantField SortField
+		wantDesc  bool
+		wantErr   bool
+	}{
+		{value: "", wantField: SortByCreatedAt, wantDesc: true},
+		{value: "created_at", wantField: SortByCreatedAt},
+		{value: "-created_at", wantField: SortByCreatedAt, wantDesc: true},
+		{value: "-updated_at", wantField: SortByUpdatedAt, wantDesc: true},
+		{value: "priority", wantErr: true},
+		{value: "--created_at", wantErr: true},
+	}
+	for _, tt := range tests {
+		field, desc, err := ParseSort(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSort(%q) error = %v, want error %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if field != tt.wantField || desc != tt.wantDesc {
+			t.Errorf("ParseSort(%q) = %s, %v; want %s, %v", tt.value, field, desc, tt.wantField, tt.wantDesc)
+		}
+	}
+}
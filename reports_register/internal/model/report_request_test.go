@@ -0,0 +1,29 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/KostySCH/Reports_go/pkg/queue"
+)
+
+func TestReportStatusFinal(t *testing.T) {
+	tests := []struct {
+		status     ReportStatus
+		retryCount int
+		want       bool
+	}{
+		{StatusPending, 0, false},
+		{StatusInProgress, 0, false},
+		{StatusCompleted, 0, true},
+		{StatusCancelled, 0, true},
+		{StatusFailed, 0, false},
+		{StatusFailed, queue.MaxRetries - 1, false},
+		{StatusFailed, queue.MaxRetries, true},
+		{StatusFailed, queue.MaxRetries + 1, true},
+	}
+	for _, tt := range tests {
+		if got := tt.status.Final(tt.retryCount); got != tt.want {
+			t.Errorf("%s.Final(%d) = %v, want %v", tt.status, tt.retryCount, got, tt.want)
+		}
+	}
+}
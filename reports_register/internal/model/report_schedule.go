@@ -0,0 +1,29 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrScheduleNotFound возвращается, если расписание не найдено
+var ErrScheduleNotFound = errors.New("report schedule not found")
+
+// ReportSchedule представляет расписание регулярной генерации отчета
+type ReportSchedule struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	Name           string     `json:"name" db:"name"`
+	OwnerID        int        `json:"owner_id" db:"owner_id"`
+	CronExpr       string     `json:"cron" db:"cron_expr"`
+	Timezone       string     `json:"timezone" db:"timezone"`
+	Type           string     `json:"type" db:"type"`
+	ParamsTemplate JSON       `json:"params_template" db:"params_template" swaggertype:"object"`
+	Enabled        bool       `json:"enabled" db:"enabled"`
+	Priority       int        `json:"priority" db:"priority"`
+	NextRunAt      time.Time  `json:"next_run_at" db:"next_run_at"`
+	LastRunAt      *time.Time `json:"last_run_at,omitempty" db:"last_run_at"`
+	LastError      *string    `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// UserLoad описывает нагрузку пользователя на очередь отчетов
+type UserLoad struct {
+	// Recent — количество запросов, созданных в текущем окне ограничения частоты
+	Recent int
+	// OldestRecent — время создания самого раннего запроса в окне
+	OldestRecent time.Time
+	// Active — количество запросов в статусах PENDING и IN_PROGRESS
+	Active int
+}
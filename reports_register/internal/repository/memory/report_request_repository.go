@@ -0,0 +1,631 @@
+// Package memory реализует репозитории reports_register поверх memstore.Store — для тестов и
+// локального запуска без Postgres. Поведение повторяет пакет postgres, включая запись истории.
+package memory
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"time"
+
+	"github.com/KostySCH/Reports_go/pkg/audit"
+	"github.com/KostySCH/Reports_go/pkg/memstore"
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+	"github.com/google/uuid"
+)
+
+type ReportRequestRepository struct {
+	store *memstore.Store
+}
+
+func NewReportRequestRepository(store *memstore.Store) *ReportRequestRepository {
+	return &ReportRequestRepository{store: store}
+}
+
+func toModel(r *memstore.Request) *model.ReportRequest {
+	return &model.ReportRequest{
+		ID:             r.ID,
+		UserID:         r.UserID,
+		Type:           r.Type,
+		Params:         model.JSON(append([]byte(nil), r.Params...)),
+		Status:         model.ReportStatus(r.Status),
+		Error:          r.Error,
+		RetryCount:     r.RetryCount,
+		ReportPath:     r.ReportPath,
+		CreatedAt:      r.CreatedAt,
+		UpdatedAt:      r.UpdatedAt,
+		IdempotencyKey: r.IdempotencyKey,
+		RequeuedBy:     r.RequeuedBy,
+		RequeueReason:  r.RequeueReason,
+		RequeuedAt:     r.RequeuedAt,
+		ScheduleID:     r.ScheduleID,
+		ScheduledFor:   r.ScheduledFor,
+		BatchID:        r.BatchID,
+		Priority:       r.Priority,
+		CallbackURL:    r.CallbackURL,
+		ReusedFrom:     r.ReusedFrom,
+		CompletedAt:    r.CompletedAt,
+	}
+}
+
+func fromModel(request *model.ReportRequest, paramsHash string) *memstore.Request {
+	return &memstore.Request{
+		ID:             request.ID,
+		UserID:         request.UserID,
+		Type:           request.Type,
+		Params:         append([]byte(nil), request.Params...),
+		ParamsHash:     paramsHash,
+		Status:         string(request.Status),
+		Error:          request.Error,
+		RetryCount:     request.RetryCount,
+		ReportPath:     request.ReportPath,
+		Priority:       request.Priority,
+		CreatedAt:      request.CreatedAt,
+		UpdatedAt:      request.UpdatedAt,
+		IdempotencyKey: request.IdempotencyKey,
+		ScheduleID:     request.ScheduleID,
+		ScheduledFor:   request.ScheduledFor,
+		BatchID:        request.BatchID,
+		CallbackURL:    request.CallbackURL,
+		CallbackSecret: request.CallbackSecret,
+		ReusedFrom:     request.ReusedFrom,
+		CompletedAt:    request.CompletedAt,
+	}
+}
+
+// Create создает новый запрос на отчет; actor записывается в историю запроса
+func (r *ReportRequestRepository) Create(ctx context.Context, request *model.ReportRequest, actor string) error {
+	paramsHash, err := model.ParamsHash(request.Type, request.Params)
+	if err != nil {
+		return err
+	}
+
+	return r.store.Tx(func(tx *memstore.Tx) error {
+		if request.IdempotencyKey != nil {
+			duplicates := tx.Select(func(row *memstore.Request) bool {
+				return row.UserID == request.UserID && row.IdempotencyKey != nil && *row.IdempotencyKey == *request.IdempotencyKey
+			}, nil)
+			if len(duplicates) > 0 {
+				return model.ErrDuplicateIdempotencyKey
+			}
+		}
+
+		tx.Insert(fromModel(request, paramsHash))
+
+		details := map[string]interface{}{"type": request.Type, "priority": request.Priority}
+		if request.ReusedFrom != nil {
+			details["reused_from"] = *request.ReusedFrom
+		}
+		tx.Record(audit.Event{
+			RequestID: request.ID,
+			Type:      audit.EventCreated,
+			ToStatus:  string(request.Status),
+			Actor:     actor,
+			Source:    audit.SourceRegister,
+			Details:   details,
+		})
+		return nil
+	})
+}
+
+// GetByID получает запрос на отчет по идентификатору
+func (r *ReportRequestRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.ReportRequest, error) {
+	var request *model.ReportRequest
+	err := r.store.Tx(func(tx *memstore.Tx) error {
+		row := tx.Get(id)
+		if row == nil {
+			return model.ErrNotFound
+		}
+		request = toModel(row)
+		return nil
+	})
+	return request, err
+}
+
+// FindReusable возвращает последний завершенный запрос того же типа с теми же параметрами,
+// результат которого сформирован не раньше since, или nil, если такого нет
+func (r *ReportRequestRepository) FindReusable(ctx context.Context, reportType string, params model.JSON, since time.Time) (*model.ReportRequest, error) {
+	paramsHash, err := model.ParamsHash(reportType, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var request *model.ReportRequest
+	err = r.store.Tx(func(tx *memstore.Tx) error {
+		rows := tx.Select(func(row *memstore.Request) bool {
+			return row.Type == reportType && row.ParamsHash == paramsHash &&
+				row.Status == string(model.StatusCompleted) && row.ReportPath != nil &&
+				row.CompletedAt != nil && !row.CompletedAt.Before(since)
+		}, func(a, b *memstore.Request) bool {
+			return a.CompletedAt.After(*b.CompletedAt)
+		})
+		if len(rows) > 0 {
+			request = toModel(rows[0])
+		}
+		return nil
+	})
+	return request, err
+}
+
+// GetByIdempotencyKey получает запрос пользователя по ключу идемпотентности
+func (r *ReportRequestRepository) GetByIdempotencyKey(ctx context.Context, userID int, key string) (*model.ReportRequest, error) {
+	var request *model.ReportRequest
+	err := r.store.Tx(func(tx *memstore.Tx) error {
+		rows := tx.Select(func(row *memstore.Request) bool {
+			return row.UserID == userID && row.IdempotencyKey != nil && *row.IdempotencyKey == key
+		}, nil)
+		if len(rows) == 0 {
+			return model.ErrNotFound
+		}
+		request = toModel(rows[0])
+		return nil
+	})
+	return request, err
+}
+
+// ReleaseIdempotencyKey освобождает ключ идемпотентности запроса, срок хранения которого истек
+func (r *ReportRequestRepository) ReleaseIdempotencyKey(ctx context.Context, id uuid.UUID) error {
+	return r.store.Tx(func(tx *memstore.Tx) error {
+		if row := tx.Get(id); row != nil {
+			row.IdempotencyKey = nil
+		}
+		return nil
+	})
+}
+
+// matchFilter проверяет условия фильтра; курсор и сортировка не учитываются
+func matchFilter(filter model.ReportRequestFilter) func(*memstore.Request) bool {
+	return func(row *memstore.Request) bool {
+		if filter.UserID != nil && row.UserID != *filter.UserID {
+			return false
+		}
+		if len(filter.Statuses) > 0 {
+			found := false
+			for _, status := range filter.Statuses {
+				if row.Status == string(status) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		if filter.Type != "" && row.Type != filter.Type {
+			return false
+		}
+		if filter.CreatedFrom != nil && row.CreatedAt.Before(*filter.CreatedFrom) {
+			return false
+		}
+		if filter.CreatedTo != nil && !row.CreatedAt.Before(*filter.CreatedTo) {
+			return false
+		}
+		return true
+	}
+}
+
+// sortValue возвращает значение колонки сортировки
+func sortValue(row *memstore.Request, field model.SortField) time.Time {
+	if field == model.SortByUpdatedAt {
+		return row.UpdatedAt
+	}
+	return row.CreatedAt
+}
+
+// compareKey сравнивает строку с ключом (value, id) так же, как сравнение кортежей в Postgres
+func compareKey(row *memstore.Request, field model.SortField, value time.Time, id uuid.UUID) int {
+	rowValue := sortValue(row, field)
+	switch {
+	case rowValue.Before(value):
+		return -1
+	case rowValue.After(value):
+		return 1
+	}
+	return bytes.Compare(row.ID[:], id[:])
+}
+
+// List получает запросы на отчет по фильтру; возвращает не более filter.Limit+1 записей,
+// чтобы вызывающая сторона могла определить наличие следующей страницы
+func (r *ReportRequestRepository) List(ctx context.Context, filter model.ReportRequestFilter) ([]*model.ReportRequest, error) {
+	match := matchFilter(filter)
+	sign := 1
+	if filter.SortDesc {
+		sign = -1
+	}
+
+	requests := make([]*model.ReportRequest, 0, filter.Limit+1)
+	err := r.store.Tx(func(tx *memstore.Tx) error {
+		rows := tx.Select(func(row *memstore.Request) bool {
+			if !match(row) {
+				return false
+			}
+			if filter.Cursor != nil {
+				return sign*compareKey(row, filter.SortField, filter.Cursor.Value, filter.Cursor.ID) > 0
+			}
+			return true
+		}, func(a, b *memstore.Request) bool {
+			return sign*compareKey(a, filter.SortField, sortValue(b, filter.SortField), b.ID) < 0
+		})
+
+		for i := 0; i < len(rows) && i < filter.Limit+1; i++ {
+			requests = append(requests, toModel(rows[i]))
+		}
+		return nil
+	})
+	return requests, err
+}
+
+// pendingOrder упорядочивает ожидающие запросы как очередь генератора
+func pendingOrder(a, b *memstore.Request) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	return a.CreatedAt.Before(b.CreatedAt)
+}
+
+// GetPending получает список отложенных отчетов для обработки
+func (r *ReportRequestRepository) GetPending(ctx context.Context, limit int) ([]*model.ReportRequest, error) {
+	var requests []*model.ReportRequest
+	err := r.store.Tx(func(tx *memstore.Tx) error {
+		rows := tx.Select(func(row *memstore.Request) bool {
+			return row.Status == string(model.StatusPending)
+		}, pendingOrder)
+		for i := 0; i < len(rows) && i < limit; i++ {
+			requests = append(requests, toModel(rows[i]))
+		}
+		return nil
+	})
+	return requests, err
+}
+
+// UpdateStatus обновляет статус отчета
+func (r *ReportRequestRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status model.ReportStatus) error {
+	return r.store.Tx(func(tx *memstore.Tx) error {
+		if row := tx.Get(id); row != nil {
+			row.Status = string(status)
+			row.UpdatedAt = tx.Now()
+		}
+		return nil
+	})
+}
+
+// Cancel переводит запрос в статус CANCELLED, если он еще ожидает, выполняется
+// или ожидает повторной попытки
+func (r *ReportRequestRepository) Cancel(ctx context.Context, id uuid.UUID, reason, actor string) (*model.ReportRequest, error) {
+	var request *model.ReportRequest
+	err := r.store.Tx(func(tx *memstore.Tx) error {
+		row := tx.Get(id)
+		if row == nil {
+			return model.ErrNotFound
+		}
+		previous := model.ReportStatus(row.Status)
+		if previous != model.StatusPending && previous != model.StatusInProgress && previous != model.StatusFailed {
+			return model.ErrStatusConflict
+		}
+
+		row.Status = string(model.StatusCancelled)
+		row.Error = &reason
+		row.NotificationSent = false
+		row.UpdatedAt = tx.Now()
+		tx.Record(audit.Event{
+			RequestID:  id,
+			Type:       audit.EventStatusChanged,
+			FromStatus: string(previous),
+			ToStatus:   row.Status,
+			Error:      &reason,
+			Actor:      actor,
+			Source:     audit.SourceRegister,
+		})
+
+		request = toModel(row)
+		return nil
+	})
+	return request, err
+}
+
+// requeue возвращает строку в статус PENDING и записывает это в историю
+func requeue(tx *memstore.Tx, row *memstore.Request, resetRetryCount bool, requestedBy, reason string) {
+	previous := row.Status
+	now := tx.Now()
+
+	row.Status = string(model.StatusPending)
+	row.Error = nil
+	row.ReportPath = nil
+	row.NotificationSent = false
+	if resetRetryCount {
+		row.RetryCount = 0
+	}
+	row.RequeuedBy = &requestedBy
+	row.RequeueReason = &reason
+	row.RequeuedAt = &now
+	row.UpdatedAt = now
+
+	tx.Record(audit.Event{
+		RequestID:  row.ID,
+		Type:       audit.EventRequeued,
+		FromStatus: previous,
+		ToStatus:   row.Status,
+		Actor:      requestedBy,
+		Source:     audit.SourceRegister,
+		Details:    map[string]interface{}{"reason": reason, "reset_retry_count": resetRetryCount},
+	})
+}
+
+// Requeue возвращает неудачный или отмененный запрос в статус PENDING
+func (r *ReportRequestRepository) Requeue(ctx context.Context, id uuid.UUID, resetRetryCount bool, requestedBy, reason string) (*model.ReportRequest, error) {
+	var request *model.ReportRequest
+	err := r.store.Tx(func(tx *memstore.Tx) error {
+		row := tx.Get(id)
+		if row == nil {
+			return model.ErrNotFound
+		}
+		if row.Status != string(model.StatusFailed) && row.Status != string(model.StatusCancelled) {
+			return model.ErrStatusConflict
+		}
+
+		requeue(tx, row, resetRetryCount, requestedBy, reason)
+		request = toModel(row)
+		return nil
+	})
+	return request, err
+}
+
+// RequeueMatching возвращает в статус PENDING не более limit запросов, подходящих под фильтр.
+// Фильтр по статусам должен содержать только FAILED и/или CANCELLED.
+func (r *ReportRequestRepository) RequeueMatching(ctx context.Context, filter model.ReportRequestFilter, limit int, resetRetryCount bool, requestedBy, reason string) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.store.Tx(func(tx *memstore.Tx) error {
+		rows := tx.Select(matchFilter(filter), func(a, b *memstore.Request) bool {
+			return a.CreatedAt.Before(b.CreatedAt)
+		})
+		for i := 0; i < len(rows) && i < limit; i++ {
+			requeue(tx, rows[i], resetRetryCount, requestedBy, reason)
+			ids = append(ids, rows[i].ID)
+		}
+		return nil
+	})
+	return ids, err
+}
+
+// ListEvents возвращает историю запроса в порядке записи
+func (r *ReportRequestRepository) ListEvents(ctx context.Context, id uuid.UUID) ([]*model.ReportRequestEvent, error) {
+	events := []*model.ReportRequestEvent{}
+	err := r.store.Tx(func(tx *memstore.Tx) error {
+		for _, e := range tx.Events(id) {
+			event := &model.ReportRequestEvent{
+				ID:         e.ID,
+				RequestID:  e.RequestID,
+				EventType:  string(e.Type),
+				RetryCount: e.RetryCount,
+				Error:      e.Error,
+				Actor:      e.Actor,
+				Source:     e.Source,
+				Details:    model.JSON(e.DetailsJSON()),
+				CreatedAt:  e.CreatedAt,
+			}
+			if e.FromStatus != "" {
+				from := model.ReportStatus(e.FromStatus)
+				event.FromStatus = &from
+			}
+			if e.ToStatus != "" {
+				to := model.ReportStatus(e.ToStatus)
+				event.ToStatus = &to
+			}
+			events = append(events, event)
+		}
+		return nil
+	})
+	return events, err
+}
+
+// GetUserLoad возвращает нагрузку пользователя на очередь: сколько запросов он создал с момента since
+// и сколько его запросов ожидают генерации или генерируются
+func (r *ReportRequestRepository) GetUserLoad(ctx context.Context, userID int, since time.Time) (*model.UserLoad, error) {
+	load := &model.UserLoad{}
+	err := r.store.Tx(func(tx *memstore.Tx) error {
+		for _, row := range tx.Select(func(row *memstore.Request) bool { return row.UserID == userID }, nil) {
+			if !row.CreatedAt.Before(since) {
+				load.Recent++
+				if load.OldestRecent.IsZero() || row.CreatedAt.Before(load.OldestRecent) {
+					load.OldestRecent = row.CreatedAt
+				}
+			}
+			if row.Status == string(model.StatusPending) || row.Status == string(model.StatusInProgress) {
+				load.Active++
+			}
+		}
+		return nil
+	})
+	return load, err
+}
+
+// CountPending возвращает количество запросов, ожидающих генерации
+func (r *ReportRequestRepository) CountPending(ctx context.Context) (int, error) {
+	var count int
+	err := r.store.Tx(func(tx *memstore.Tx) error {
+		count = len(tx.Select(func(row *memstore.Request) bool {
+			return row.Status == string(model.StatusPending)
+		}, nil))
+		return nil
+	})
+	return count, err
+}
+
+// Stats собирает статистику очереди за окно [from, to) так же, как postgres.ReportRequestRepository.Stats
+func (r *ReportRequestRepository) Stats(ctx context.Context, from, to time.Time, topErrors int) (*model.QueueStats, error) {
+	stats := &model.QueueStats{
+		From:      from,
+		To:        to,
+		ByStatus:  map[model.ReportStatus]int{},
+		ByType:    []model.TypeStats{},
+		TopErrors: []model.ErrorStats{},
+	}
+	inWindow := func(t time.Time) bool { return !t.Before(from) && t.Before(to) }
+
+	err := r.store.Tx(func(tx *memstore.Tx) error {
+		byType := map[string]*model.TypeStats{}
+		errorCounts := map[string]int{}
+		var durations []float64
+		var oldestPending time.Time
+
+		for _, row := range tx.Select(nil, nil) {
+			status := model.ReportStatus(row.Status)
+
+			if inWindow(row.CreatedAt) {
+				stats.ByStatus[status]++
+				typeStats, ok := byType[row.Type]
+				if !ok {
+					typeStats = &model.TypeStats{Type: row.Type, ByStatus: map[model.ReportStatus]int{}}
+					byType[row.Type] = typeStats
+				}
+				typeStats.Total++
+				typeStats.ByStatus[status]++
+			}
+
+			switch status {
+			case model.StatusPending:
+				stats.Pending++
+				if oldestPending.IsZero() || row.CreatedAt.Before(oldestPending) {
+					oldestPending = row.CreatedAt
+				}
+			case model.StatusInProgress:
+				stats.InProgress++
+			case model.StatusCompleted:
+				if inWindow(row.UpdatedAt) {
+					stats.Completed++
+					if row.ReusedFrom == nil {
+						durations = append(durations, row.UpdatedAt.Sub(row.CreatedAt).Seconds())
+					}
+				}
+			case model.StatusFailed:
+				if inWindow(row.UpdatedAt) {
+					stats.Failed++
+					message := ""
+					if row.Error != nil {
+						message = *row.Error
+					}
+					errorCounts[message]++
+				}
+			}
+		}
+
+		for _, typeStats := range byType {
+			stats.ByType = append(stats.ByType, *typeStats)
+		}
+		sort.Slice(stats.ByType, func(i, j int) bool { return stats.ByType[i].Type < stats.ByType[j].Type })
+
+		if !oldestPending.IsZero() {
+			stats.OldestPendingAgeSeconds = to.Sub(oldestPending).Seconds()
+		}
+		if finished := stats.Completed + stats.Failed; finished > 0 {
+			stats.FailureRate = float64(stats.Failed) / float64(finished)
+		}
+		stats.Completion = completionStats(durations)
+
+		for message, count := range errorCounts {
+			stats.TopErrors = append(stats.TopErrors, model.ErrorStats{Error: message, Count: count})
+		}
+		sort.Slice(stats.TopErrors, func(i, j int) bool {
+			a, b := stats.TopErrors[i], stats.TopErrors[j]
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+			return a.Error < b.Error
+		})
+		if len(stats.TopErrors) > topErrors {
+			stats.TopErrors = stats.TopErrors[:topErrors]
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// completionStats считает среднее и перцентили с линейной интерполяцией, как percentile_cont
+func completionStats(durations []float64) model.CompletionStats {
+	c := model.CompletionStats{Count: len(durations)}
+	if len(durations) == 0 {
+		return c
+	}
+	sort.Float64s(durations)
+
+	var sum float64
+	for _, d := range durations {
+		sum += d
+	}
+	c.Avg = sum / float64(len(durations))
+	c.Max = durations[len(durations)-1]
+
+	percentile := func(p float64) float64 {
+		pos := p * float64(len(durations)-1)
+		lower := int(pos)
+		if lower+1 >= len(durations) {
+			return durations[lower]
+		}
+		return durations[lower] + (pos-float64(lower))*(durations[lower+1]-durations[lower])
+	}
+	c.P50 = percentile(0.5)
+	c.P90 = percentile(0.9)
+	c.P95 = percentile(0.95)
+	c.P99 = percentile(0.99)
+	return c
+}
+
+// QueueSnapshot считает очередь перед request так же, как postgres.ReportRequestRepository.QueueSnapshot
+func (r *ReportRequestRepository) QueueSnapshot(ctx context.Context, request *model.ReportRequest) (*model.QueueSnapshot, error) {
+	snapshot := &model.QueueSnapshot{Position: 1, Ahead: map[string]int{}}
+	err := r.store.Tx(func(tx *memstore.Tx) error {
+		for _, row := range tx.Select(nil, nil) {
+			switch model.ReportStatus(row.Status) {
+			case model.StatusPending:
+				if row.Priority > request.Priority ||
+					(row.Priority == request.Priority && row.CreatedAt.Before(request.CreatedAt)) {
+					snapshot.Ahead[row.Type]++
+					snapshot.Position++
+				}
+			case model.StatusInProgress:
+				snapshot.Running = append(snapshot.Running, model.RunningRequest{Type: row.Type, StartedAt: row.UpdatedAt})
+			}
+		}
+		return nil
+	})
+	return snapshot, err
+}
+
+// GenerationTimes считает среднюю длительность генерации по истории запросов
+// так же, как postgres.ReportRequestRepository.GenerationTimes
+func (r *ReportRequestRepository) GenerationTimes(ctx context.Context, since time.Time) ([]model.GenerationTime, error) {
+	byType := map[string]*model.GenerationTime{}
+	err := r.store.Tx(func(tx *memstore.Tx) error {
+		for _, row := range tx.Select(nil, nil) {
+			var started time.Time
+			for _, e := range tx.Events(row.ID) {
+				switch {
+				case e.ToStatus == string(model.StatusInProgress):
+					started = e.CreatedAt
+				case e.ToStatus == string(model.StatusCompleted) && e.FromStatus == string(model.StatusInProgress) &&
+					!started.IsZero() && !e.CreatedAt.Before(since):
+					t, ok := byType[row.Type]
+					if !ok {
+						t = &model.GenerationTime{Type: row.Type}
+						byType[row.Type] = t
+					}
+					// Накапливаем сумму и делим после обхода
+					t.AvgSeconds += e.CreatedAt.Sub(started).Seconds()
+					t.Count++
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	times := make([]model.GenerationTime, 0, len(byType))
+	for _, t := range byType {
+		t.AvgSeconds /= float64(t.Count)
+		times = append(times, *t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Type < times[j].Type })
+	return times, nil
+}
@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KostySCH/Reports_go/pkg/memstore"
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+	"github.com/google/uuid"
+)
+
+func TestCompletionStats(t *testing.T) {
+	tests := []struct {
+		name      string
+		durations []float64
+		want      model.CompletionStats
+	}{
+		{name: "empty", want: model.CompletionStats{}},
+		{
+			name:      "single",
+			durations: []float64{7},
+			want:      model.CompletionStats{Count: 1, Avg: 7, P50: 7, P90: 7, P95: 7, P99: 7, Max: 7},
+		},
+		{
+			// Перцентили интерполируются между соседними значениями, как percentile_cont
+			name:      "unsorted",
+			durations: []float64{4, 1, 3, 2},
+			want:      model.CompletionStats{Count: 4, Avg: 2.5, P50: 2.5, P90: 3.7, P95: 3.85, P99: 3.97, Max: 4},
+		},
+		{
+			name:      "odd count",
+			durations: []float64{30, 10, 20},
+			want:      model.CompletionStats{Count: 3, Avg: 20, P50: 20, P90: 28, P95: 29, P99: 29.8, Max: 30},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := completionStats(tt.durations)
+			if got.Count != tt.want.Count {
+				t.Fatalf("count = %d, want %d", got.Count, tt.want.Count)
+			}
+			values := map[string][2]float64{
+				"avg": {got.Avg, tt.want.Avg},
+				"p50": {got.P50, tt.want.P50},
+				"p90": {got.P90, tt.want.P90},
+				"p95": {got.P95, tt.want.P95},
+				"p99": {got.P99, tt.want.P99},
+				"max": {got.Max, tt.want.Max},
+			}
+			for name, v := range values {
+				if diff := v[0] - v[1]; diff > 1e-9 || diff < -1e-9 {
+					t.Errorf("%s = %v, want %v", name, v[0], v[1])
+				}
+			}
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	from := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+	text := func(s string) *string { return &s }
+	source := uuid.New()
+
+	store := memstore.New()
+	store.Tx(func(tx *memstore.Tx) error {
+		add := func(reportType string, status model.ReportStatus, created, updated time.Duration, row memstore.Request) {
+			row.ID = uuid.New()
+			row.UserID = 7
+			row.Type = reportType
+			row.Params = []byte(`{}`)
+			row.Status = string(status)
+			row.CreatedAt = from.Add(created)
+			row.UpdatedAt = from.Add(updated)
+			tx.Insert(&row)
+		}
+		add("a", model.StatusPending, 10*time.Minute, 10*time.Minute, memstore.Request{})
+		// Создан до окна: учитывается только в текущей глубине очереди
+		add("b", model.StatusInProgress, -time.Hour, -time.Hour, memstore.Request{})
+		add("a", model.StatusCompleted, 0, time.Minute, memstore.Request{})
+		// Готовый результат без генерации не влияет на длительности
+		add("a", model.StatusCompleted, 5*time.Minute, 5*time.Minute, memstore.Request{ReusedFrom: &source})
+		add("b", model.StatusFailed, time.Minute, 2*time.Minute, memstore.Request{Error: text("boom")})
+		add("b", model.StatusFailed, time.Minute, 3*time.Minute, memstore.Request{Error: text("boom")})
+		add("b", model.StatusFailed, 20*time.Minute, 21*time.Minute, memstore.Request{})
+		add("b", model.StatusFailed, -2*time.Hour, -90*time.Minute, memstore.Request{Error: text("old")})
+		return nil
+	})
+
+	stats, err := NewReportRequestRepository(store).Stats(context.Background(), from, to, 5)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+
+	wantByStatus := map[model.ReportStatus]int{model.StatusPending: 1, model.StatusCompleted: 2, model.StatusFailed: 3}
+	if !reflect.DeepEqual(stats.ByStatus, wantByStatus) {
+		t.Errorf("by status = %v, want %v", stats.ByStatus, wantByStatus)
+	}
+	wantByType := []model.TypeStats{
+		{Type: "a", Total: 3, ByStatus: map[model.ReportStatus]int{model.StatusPending: 1, model.StatusCompleted: 2}},
+		{Type: "b", Total: 3, ByStatus: map[model.ReportStatus]int{model.StatusFailed: 3}},
+	}
+	if !reflect.DeepEqual(stats.ByType, wantByType) {
+		t.Errorf("by type = %+v, want %+v", stats.ByType, wantByType)
+	}
+	if stats.Pending != 1 || stats.InProgress != 1 || stats.OldestPendingAgeSeconds != 3000 {
+		t.Errorf("queue = %d pending, %d in progress, oldest %vs; want 1, 1, 3000s",
+			stats.Pending, stats.InProgress, stats.OldestPendingAgeSeconds)
+	}
+	if stats.Completion.Count != 1 || stats.Completion.Avg != 60 || stats.Completion.Max != 60 {
+		t.Errorf("completion = %+v, want one generation of 60s", stats.Completion)
+	}
+	if stats.Completed != 2 || stats.Failed != 3 || stats.FailureRate != 0.6 {
+		t.Errorf("finished = %d completed, %d failed, rate %v; want 2, 3, 0.6", stats.Completed, stats.Failed, stats.FailureRate)
+	}
+	wantErrors := []model.ErrorStats{{Error: "boom", Count: 2}, {Error: "", Count: 1}}
+	if !reflect.DeepEqual(stats.TopErrors, wantErrors) {
+		t.Errorf("top errors = %v, want %v", stats.TopErrors, wantErrors)
+	}
+
+	stats, err = NewReportRequestRepository(store).Stats(context.Background(), from, to, 1)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if len(stats.TopErrors) != 1 || stats.TopErrors[0].Error != "boom" {
+		t.Errorf("top errors limited to 1 = %v, want only boom", stats.TopErrors)
+	}
+}
@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type ReportBatchRepository struct {
+	db *sql.DB
+}
+
+func NewReportBatchRepository(db *sql.DB) *ReportBatchRepository {
+	return &ReportBatchRepository{db: db}
+}
+
+// Create сохраняет пакет и все его запросы в одной транзакции; actor записывается в историю запросов
+func (r *ReportBatchRepository) Create(ctx context.Context, batch *model.ReportBatch, requests []*model.ReportRequest, actor string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO reporting.report_batches (id, user_id, total, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	if _, err := tx.ExecContext(ctx, query, batch.ID, batch.UserID, batch.Total, batch.CreatedAt); err != nil {
+		log.WithError(err).WithField("batch_id", batch.ID).Error("Failed to create report batch")
+		return err
+	}
+
+	for _, request := range requests {
+		_, err := insertReportRequest(ctx, tx, request, "")
+		if err == nil {
+			err = recordCreated(ctx, tx, request, actor)
+		}
+		if err != nil {
+			log.WithError(err).WithFields(logrus.Fields{
+				"batch_id":   batch.ID,
+				"request_id": request.ID,
+			}).Error("Failed to create batch report request")
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetByID получает пакет по идентификатору
+func (r *ReportBatchRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.ReportBatch, error) {
+	query := `
+		SELECT id, user_id, total, created_at
+		FROM reporting.report_batches
+		WHERE id = $1
+	`
+
+	batch := &model.ReportBatch{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&batch.ID, &batch.UserID, &batch.Total, &batch.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrBatchNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// GetItems получает статусы всех запросов пакета
+func (r *ReportBatchRepository) GetItems(ctx context.Context, batchID uuid.UUID) ([]model.BatchItemStatus, error) {
+	query := `
+		SELECT id, type, params, status, error
+		FROM reporting.report_requests
+		WHERE batch_id = $1
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]model.BatchItemStatus, 0)
+	for rows.Next() {
+		var item model.BatchItemStatus
+		if err := rows.Scan(&item.ID, &item.Type, &item.Params, &item.Status, &item.Error); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
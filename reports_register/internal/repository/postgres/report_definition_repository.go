@@ -0,0 +1,204 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type ReportDefinitionRepository struct {
+	db *sql.DB
+}
+
+func NewReportDefinitionRepository(db *sql.DB) *ReportDefinitionRepository {
+	return &ReportDefinitionRepository{db: db}
+}
+
+// reportDefinitionColumns перечисляет колонки, читаемые scanReportDefinition; список пользователей,
+// которым открыто определение, собирается из report_definition_shares
+const reportDefinitionColumns = `d.id, d.name, d.owner_id, d.type, d.params, d.priority, d.callback_url, d.callback_secret,
+	d.reuse, d.created_at, d.updated_at,
+	COALESCE((SELECT array_agg(s.user_id ORDER BY s.user_id)
+		FROM reporting.report_definition_shares s
+		WHERE s.definition_id = d.id), '{}')`
+
+func scanReportDefinition(row rowScanner) (*model.ReportDefinition, error) {
+	definition := &model.ReportDefinition{}
+	var sharedWith pq.Int64Array
+	err := row.Scan(
+		&definition.ID,
+		&definition.Name,
+		&definition.OwnerID,
+		&definition.Type,
+		&definition.Params,
+		&definition.Priority,
+		&definition.CallbackURL,
+		&definition.CallbackSecret,
+		&definition.Reuse,
+		&definition.CreatedAt,
+		&definition.UpdatedAt,
+		&sharedWith,
+	)
+	if err != nil {
+		return nil, err
+	}
+	definition.SharedWith = make([]int, len(sharedWith))
+	for i, userID := range sharedWith {
+		definition.SharedWith[i] = int(userID)
+	}
+	return definition, nil
+}
+
+// Create сохраняет новое определение; занятое владельцем имя возвращает model.ErrDefinitionNameTaken
+func (r *ReportDefinitionRepository) Create(ctx context.Context, definition *model.ReportDefinition) error {
+	query := `
+		INSERT INTO reporting.report_definitions (id, name, owner_id, type, params, priority, callback_url,
+			callback_secret, reuse, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		definition.ID,
+		definition.Name,
+		definition.OwnerID,
+		definition.Type,
+		definition.Params,
+		definition.Priority,
+		definition.CallbackURL,
+		definition.CallbackSecret,
+		definition.Reuse,
+		definition.CreatedAt,
+		definition.UpdatedAt,
+	)
+	if isUniqueViolation(err) {
+		return model.ErrDefinitionNameTaken
+	}
+	if err != nil {
+		log.WithError(err).WithField("definition_id", definition.ID).Error("Failed to create report definition")
+	}
+	return err
+}
+
+// GetByID получает определение по идентификатору
+func (r *ReportDefinitionRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.ReportDefinition, error) {
+	query := `SELECT ` + reportDefinitionColumns + `
+		FROM reporting.report_definitions d
+		WHERE d.id = $1
+	`
+
+	definition, err := scanReportDefinition(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrDefinitionNotFound
+	}
+	return definition, err
+}
+
+// List получает определения. Если ownerID не nil — только определения этого владельца;
+// если visibleTo не nil — только определения этого пользователя и открытые ему.
+func (r *ReportDefinitionRepository) List(ctx context.Context, ownerID, visibleTo *int) ([]*model.ReportDefinition, error) {
+	query := `SELECT ` + reportDefinitionColumns + `
+		FROM reporting.report_definitions d
+		WHERE ($1::int IS NULL OR d.owner_id = $1)
+			AND ($2::int IS NULL OR d.owner_id = $2 OR EXISTS (
+				SELECT 1 FROM reporting.report_definition_shares s
+				WHERE s.definition_id = d.id AND s.user_id = $2))
+		ORDER BY d.name ASC, d.created_at ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, ownerID, visibleTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	definitions := make([]*model.ReportDefinition, 0)
+	for rows.Next() {
+		definition, err := scanReportDefinition(rows)
+		if err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, definition)
+	}
+
+	return definitions, rows.Err()
+}
+
+// Update сохраняет изменения определения; владелец и список пользователей не меняются
+func (r *ReportDefinitionRepository) Update(ctx context.Context, definition *model.ReportDefinition) error {
+	query := `
+		UPDATE reporting.report_definitions
+		SET name = $1, type = $2, params = $3, priority = $4, callback_url = $5, callback_secret = $6,
+			reuse = $7, updated_at = $8
+		WHERE id = $9
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		definition.Name,
+		definition.Type,
+		definition.Params,
+		definition.Priority,
+		definition.CallbackURL,
+		definition.CallbackSecret,
+		definition.Reuse,
+		definition.UpdatedAt,
+		definition.ID,
+	)
+	if isUniqueViolation(err) {
+		return model.ErrDefinitionNameTaken
+	}
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, model.ErrDefinitionNotFound)
+}
+
+// Delete удаляет определение вместе с его списком пользователей; созданные по нему запросы сохраняются
+func (r *ReportDefinitionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM reporting.report_definitions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, model.ErrDefinitionNotFound)
+}
+
+// SetShares заменяет список пользователей, которым открыто определение
+func (r *ReportDefinitionRepository) SetShares(ctx context.Context, id uuid.UUID, userIDs []int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Блокируем определение, чтобы параллельные изменения списка не перемешались
+	result, err := tx.ExecContext(ctx, `
+		UPDATE reporting.report_definitions SET updated_at = now() WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(result, model.ErrDefinitionNotFound); err != nil {
+		return err
+	}
+
+	ids := make(pq.Int64Array, len(userIDs))
+	for i, userID := range userIDs {
+		ids[i] = int64(userID)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM reporting.report_definition_shares
+		WHERE definition_id = $1 AND NOT (user_id = ANY($2))`, id, ids); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO reporting.report_definition_shares (definition_id, user_id)
+		SELECT $1::uuid, unnest($2::bigint[])
+		ON CONFLICT (definition_id, user_id) DO NOTHING`, id, ids); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+)
+
+// QueueSnapshot считает ожидающие запросы перед request в порядке выборки генератором
+// (priority DESC, created_at ASC) и собирает генерирующиеся сейчас запросы.
+// Оба запроса выполняются в одной транзакции REPEATABLE READ.
+func (r *ReportRequestRepository) QueueSnapshot(ctx context.Context, request *model.ReportRequest) (*model.QueueSnapshot, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	snapshot := &model.QueueSnapshot{Position: 1, Ahead: map[string]int{}}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT type, count(*)
+		FROM reporting.report_requests
+		WHERE status = $1 AND (priority > $2 OR (priority = $2 AND created_at < $3))
+		GROUP BY type
+	`, model.StatusPending, request.Priority, request.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var (
+			reportType string
+			count      int
+		)
+		if err := rows.Scan(&reportType, &count); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		snapshot.Ahead[reportType] = count
+		snapshot.Position += count
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Генератор обновляет updated_at при захвате запроса, поэтому для IN_PROGRESS это время начала генерации
+	rows, err = tx.QueryContext(ctx, `
+		SELECT type, updated_at
+		FROM reporting.report_requests
+		WHERE status = $1
+	`, model.StatusInProgress)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var running model.RunningRequest
+		if err := rows.Scan(&running.Type, &running.StartedAt); err != nil {
+			return nil, err
+		}
+		snapshot.Running = append(snapshot.Running, running)
+	}
+	return snapshot, rows.Err()
+}
+
+// GenerationTimes возвращает среднюю длительность генерации по типам для отчетов, сгенерированных
+// начиная с since. Длительность считается по истории: от последнего перехода в IN_PROGRESS
+// до перехода в COMPLETED, поэтому время ожидания в очереди и повторно использованные отчеты не учитываются.
+func (r *ReportRequestRepository) GenerationTimes(ctx context.Context, since time.Time) ([]model.GenerationTime, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT rr.type, count(*), avg(EXTRACT(EPOCH FROM done.created_at - started.created_at))
+		FROM reporting.report_request_events done
+		JOIN reporting.report_requests rr ON rr.id = done.request_id
+		CROSS JOIN LATERAL (
+			SELECT e.created_at
+			FROM reporting.report_request_events e
+			WHERE e.request_id = done.request_id AND e.id < done.id AND e.to_status = $1
+			ORDER BY e.id DESC
+			LIMIT 1
+		) started
+		WHERE done.to_status = $2 AND done.from_status = $1 AND done.created_at >= $3
+		GROUP BY rr.type
+		ORDER BY rr.type
+	`, model.StatusInProgress, model.StatusCompleted, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	times := make([]model.GenerationTime, 0)
+	for rows.Next() {
+		var t model.GenerationTime
+		if err := rows.Scan(&t.Type, &t.Count, &t.AvgSeconds); err != nil {
+			return nil, err
+		}
+		times = append(times, t)
+	}
+	return times, rows.Err()
+}
@@ -3,11 +3,15 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/KostySCH/Reports_go/pkg/audit"
 	"github.com/KostySCH/Reports_go/reports_register/internal/model"
 	"github.com/google/uuid"
-	_ "github.com/lib/pq" // PostgreSQL драйвер
+	"github.com/lib/pq" // PostgreSQL драйвер
 	"github.com/sirupsen/logrus"
 )
 
@@ -26,20 +30,26 @@ func NewReportRequestRepository(db *sql.DB) *ReportRequestRepository {
 	return &ReportRequestRepository{db: db}
 }
 
-// Create создает новый запрос на отчет
-func (r *ReportRequestRepository) Create(ctx context.Context, request *model.ReportRequest) error {
-	query := `
-		INSERT INTO reporting.report_requests (id, user_id, type, params, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-	`
+// insertReportRequestQuery вставляет запрос на отчет; аргументы формирует insertReportRequest
+const insertReportRequestQuery = `
+		INSERT INTO reporting.report_requests (id, user_id, type, params, status, created_at, updated_at,
+			idempotency_key, schedule_id, scheduled_for, batch_id, priority, callback_url, callback_secret,
+			report_path, reused_from, completed_at, params_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`
 
-	log.WithFields(logrus.Fields{
-		"request_id": request.ID,
-		"user_id":    request.UserID,
-		"type":       request.Type,
-	}).Debug("Executing SQL query to create report request")
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// insertReportRequest вставляет запрос на отчет через db или транзакцию;
+// suffix дописывается к запросу, например ON CONFLICT
+func insertReportRequest(ctx context.Context, db execer, request *model.ReportRequest, suffix string) (sql.Result, error) {
+	paramsHash, err := model.ParamsHash(request.Type, request.Params)
+	if err != nil {
+		return nil, fmt.Errorf("params hash: %w", err)
+	}
 
-	_, err := r.db.ExecContext(ctx, query,
+	return db.ExecContext(ctx, insertReportRequestQuery+suffix,
 		request.ID,
 		request.UserID,
 		request.Type,
@@ -47,13 +57,72 @@ func (r *ReportRequestRepository) Create(ctx context.Context, request *model.Rep
 		request.Status,
 		request.CreatedAt,
 		request.UpdatedAt,
+		request.IdempotencyKey,
+		request.ScheduleID,
+		request.ScheduledFor,
+		request.BatchID,
+		request.Priority,
+		request.CallbackURL,
+		request.CallbackSecret,
+		request.ReportPath,
+		request.ReusedFrom,
+		request.CompletedAt,
+		paramsHash,
 	)
+}
+
+// recordCreated записывает в историю создание запроса
+func recordCreated(ctx context.Context, db execer, request *model.ReportRequest, actor string) error {
+	details := map[string]interface{}{"type": request.Type, "priority": request.Priority}
+	if request.ScheduleID != nil {
+		details["schedule_id"] = *request.ScheduleID
+	}
+	if request.BatchID != nil {
+		details["batch_id"] = *request.BatchID
+	}
+	if request.ReusedFrom != nil {
+		details["reused_from"] = *request.ReusedFrom
+	}
+
+	return audit.Record(ctx, db, audit.Event{
+		RequestID: request.ID,
+		Type:      audit.EventCreated,
+		ToStatus:  string(request.Status),
+		Actor:     actor,
+		Source:    audit.SourceRegister,
+		Details:   details,
+	})
+}
+
+// Create создает новый запрос на отчет; actor записывается в историю запроса
+func (r *ReportRequestRepository) Create(ctx context.Context, request *model.ReportRequest, actor string) error {
+	log.WithFields(logrus.Fields{
+		"request_id": request.ID,
+		"user_id":    request.UserID,
+		"type":       request.Type,
+	}).Debug("Executing SQL query to create report request")
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
+	_, err = insertReportRequest(ctx, tx, request, "")
+	if err == nil {
+		err = recordCreated(ctx, tx, request, actor)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+
+	if isUniqueViolation(err) && request.IdempotencyKey != nil {
+		return model.ErrDuplicateIdempotencyKey
+	}
 	if err != nil {
 		log.WithError(err).WithFields(logrus.Fields{
 			"request_id": request.ID,
 			"user_id":    request.UserID,
-			"query":      query,
 		}).Error("Failed to execute SQL query")
 		return err
 	}
@@ -66,13 +135,219 @@ func (r *ReportRequestRepository) Create(ctx context.Context, request *model.Rep
 	return nil
 }
 
+// reportRequestColumns перечисляет колонки, читаемые scanReportRequest
+const reportRequestColumns = `id, user_id, status, type, params, error, retry_count, report_path, created_at, updated_at,
+	idempotency_key, requeued_by, requeue_reason, requeued_at, schedule_id, scheduled_for, batch_id, priority,
+	callback_url, reused_from, completed_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanReportRequest(row rowScanner) (*model.ReportRequest, error) {
+	request := &model.ReportRequest{}
+	err := row.Scan(
+		&request.ID,
+		&request.UserID,
+		&request.Status,
+		&request.Type,
+		&request.Params,
+		&request.Error,
+		&request.RetryCount,
+		&request.ReportPath,
+		&request.CreatedAt,
+		&request.UpdatedAt,
+		&request.IdempotencyKey,
+		&request.RequeuedBy,
+		&request.RequeueReason,
+		&request.RequeuedAt,
+		&request.ScheduleID,
+		&request.ScheduledFor,
+		&request.BatchID,
+		&request.Priority,
+		&request.CallbackURL,
+		&request.ReusedFrom,
+		&request.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// isUniqueViolation проверяет, что ошибка вызвана нарушением уникального индекса
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// GetByID получает запрос на отчет по идентификатору
+func (r *ReportRequestRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.ReportRequest, error) {
+	query := `SELECT ` + reportRequestColumns + `
+		FROM reporting.report_requests
+		WHERE id = $1
+	`
+
+	request, err := scanReportRequest(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		log.WithError(err).WithField("request_id", id).Error("Failed to get report request")
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// FindReusable возвращает последний завершенный запрос того же типа с теми же параметрами,
+// результат которого сформирован не раньше since, или nil, если такого нет
+func (r *ReportRequestRepository) FindReusable(ctx context.Context, reportType string, params model.JSON, since time.Time) (*model.ReportRequest, error) {
+	paramsHash, err := model.ParamsHash(reportType, params)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + reportRequestColumns + `
+		FROM reporting.report_requests
+		WHERE type = $1 AND params_hash = $2 AND status = $3
+		AND report_path IS NOT NULL AND completed_at >= $4
+		ORDER BY completed_at DESC
+		LIMIT 1
+	`
+	request, err := scanReportRequest(r.db.QueryRowContext(ctx, query, reportType, paramsHash, model.StatusCompleted, since))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		log.WithError(err).WithField("type", reportType).Error("Failed to find reusable report request")
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// GetByIdempotencyKey получает запрос пользователя по ключу идемпотентности
+func (r *ReportRequestRepository) GetByIdempotencyKey(ctx context.Context, userID int, key string) (*model.ReportRequest, error) {
+	query := `SELECT ` + reportRequestColumns + `
+		FROM reporting.report_requests
+		WHERE user_id = $1 AND idempotency_key = $2
+	`
+
+	request, err := scanReportRequest(r.db.QueryRowContext(ctx, query, userID, key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// ReleaseIdempotencyKey освобождает ключ идемпотентности запроса, срок хранения которого истек
+func (r *ReportRequestRepository) ReleaseIdempotencyKey(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE reporting.report_requests
+		SET idempotency_key = NULL
+		WHERE id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// queryArgs накапливает аргументы SQL-запроса и выдает плейсхолдеры для них
+type queryArgs []interface{}
+
+func (a *queryArgs) add(value interface{}) string {
+	*a = append(*a, value)
+	return fmt.Sprintf("$%d", len(*a))
+}
+
+// filterConditions строит условия WHERE по фильтру; курсор и сортировка не учитываются
+func filterConditions(filter model.ReportRequestFilter, args *queryArgs) []string {
+	var conditions []string
+
+	if filter.UserID != nil {
+		conditions = append(conditions, "user_id = "+args.add(*filter.UserID))
+	}
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, len(filter.Statuses))
+		for i, status := range filter.Statuses {
+			statuses[i] = string(status)
+		}
+		conditions = append(conditions, "status = ANY("+args.add(pq.Array(statuses))+")")
+	}
+	if filter.Type != "" {
+		conditions = append(conditions, "type = "+args.add(filter.Type))
+	}
+	if filter.CreatedFrom != nil {
+		conditions = append(conditions, "created_at >= "+args.add(*filter.CreatedFrom))
+	}
+	if filter.CreatedTo != nil {
+		conditions = append(conditions, "created_at < "+args.add(*filter.CreatedTo))
+	}
+
+	return conditions
+}
+
+// List получает запросы на отчет по фильтру; возвращает не более filter.Limit+1 записей,
+// чтобы вызывающая сторона могла определить наличие следующей страницы
+func (r *ReportRequestRepository) List(ctx context.Context, filter model.ReportRequestFilter) ([]*model.ReportRequest, error) {
+	var args queryArgs
+	conditions := filterConditions(filter, &args)
+
+	sortColumn := string(filter.SortField)
+	direction, comparison := "ASC", ">"
+	if filter.SortDesc {
+		direction, comparison = "DESC", "<"
+	}
+	if filter.Cursor != nil {
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s (%s, %s)",
+			sortColumn, comparison, args.add(filter.Cursor.Value), args.add(filter.Cursor.ID)))
+	}
+
+	query := `SELECT ` + reportRequestColumns + `
+		FROM reporting.report_requests`
+	if len(conditions) > 0 {
+		query += `
+		WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(`
+		ORDER BY %s %s, id %s
+		LIMIT %s`, sortColumn, direction, direction, args.add(filter.Limit+1))
+
+	log.WithFields(logrus.Fields{
+		"query": query,
+		"args":  args,
+	}).Debug("Executing SQL query to list report requests")
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := make([]*model.ReportRequest, 0, filter.Limit+1)
+	for rows.Next() {
+		request, err := scanReportRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+
+	return requests, rows.Err()
+}
+
 // GetPending получает список отложенных отчетов для обработки
 func (r *ReportRequestRepository) GetPending(ctx context.Context, limit int) ([]*model.ReportRequest, error) {
 	query := `
-		SELECT id, user_id, status, type, params, created_at, updated_at
+		SELECT id, user_id, status, type, params, priority, created_at, updated_at
 		FROM reporting.report_requests
 		WHERE status = $1
-		ORDER BY created_at ASC
+		ORDER BY priority DESC, created_at ASC
 		LIMIT $2
 		FOR UPDATE SKIP LOCKED
 	`
@@ -92,6 +367,7 @@ func (r *ReportRequestRepository) GetPending(ctx context.Context, limit int) ([]
 			&request.Status,
 			&request.Type,
 			&request.Params,
+			&request.Priority,
 			&request.CreatedAt,
 			&request.UpdatedAt,
 		)
@@ -116,38 +392,287 @@ func (r *ReportRequestRepository) UpdateStatus(ctx context.Context, id uuid.UUID
 	return err
 }
 
-// GetStuck получает отчеты, которые зависли в статусе PENDING
-func (r *ReportRequestRepository) GetStuck(ctx context.Context, timeout time.Duration) ([]*model.ReportRequest, error) {
-	query := `
-		SELECT id, user_id, status, type, params, created_at, updated_at
+// lockStatus блокирует запрос до конца транзакции и возвращает его текущий статус
+func lockStatus(ctx context.Context, tx *sql.Tx, id uuid.UUID) (model.ReportStatus, error) {
+	var status model.ReportStatus
+	err := tx.QueryRowContext(ctx, `
+		SELECT status
 		FROM reporting.report_requests
-		WHERE status = $1 AND updated_at < $2
-		ORDER BY updated_at ASC
-	`
+		WHERE id = $1
+		FOR UPDATE
+	`, id).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", model.ErrNotFound
+	}
+	return status, err
+}
+
+// statusIn проверяет, что статус входит в allowed
+func statusIn(status model.ReportStatus, allowed ...model.ReportStatus) bool {
+	for _, s := range allowed {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Cancel переводит запрос в статус CANCELLED, если он еще ожидает, выполняется
+// или ожидает повторной попытки
+func (r *ReportRequestRepository) Cancel(ctx context.Context, id uuid.UUID, reason, actor string) (*model.ReportRequest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	previous, err := lockStatus(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Запрос уже находится в конечном статусе
+	if !statusIn(previous, model.StatusPending, model.StatusInProgress, model.StatusFailed) {
+		return nil, model.ErrStatusConflict
+	}
+
+	query := `
+		UPDATE reporting.report_requests
+		SET status = $1, error = $2, notification_sent = false, updated_at = $3
+		WHERE id = $4
+		RETURNING ` + reportRequestColumns
+
+	request, err := scanReportRequest(tx.QueryRowContext(ctx, query,
+		model.StatusCancelled,
+		reason,
+		time.Now(),
+		id,
+	))
+	if err == nil {
+		err = audit.Record(ctx, tx, audit.Event{
+			RequestID:  id,
+			Type:       audit.EventStatusChanged,
+			FromStatus: string(previous),
+			ToStatus:   string(model.StatusCancelled),
+			Error:      &reason,
+			Actor:      actor,
+			Source:     audit.SourceRegister,
+		})
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.WithError(err).WithField("request_id", id).Error("Failed to cancel report request")
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// requeueSet — общая часть UPDATE для возврата запроса в очередь;
+// ожидает аргументы $1 status, $2 reset retry_count, $3 requeued_by, $4 requeue_reason, $5 now
+const requeueSet = `
+		SET status = $1,
+			error = NULL,
+			report_path = NULL,
+			notification_sent = false,
+			retry_count = CASE WHEN $2 THEN 0 ELSE retry_count END,
+			requeued_by = $3,
+			requeue_reason = $4,
+			requeued_at = $5,
+			updated_at = $5`
+
+// recordRequeued записывает в историю возврат запроса в очередь
+func recordRequeued(ctx context.Context, db execer, id uuid.UUID, previous model.ReportStatus, resetRetryCount bool, requestedBy, reason string) error {
+	return audit.Record(ctx, db, audit.Event{
+		RequestID:  id,
+		Type:       audit.EventRequeued,
+		FromStatus: string(previous),
+		ToStatus:   string(model.StatusPending),
+		Actor:      requestedBy,
+		Source:     audit.SourceRegister,
+		Details:    map[string]interface{}{"reason": reason, "reset_retry_count": resetRetryCount},
+	})
+}
+
+// Requeue возвращает неудачный или отмененный запрос в статус PENDING
+func (r *ReportRequestRepository) Requeue(ctx context.Context, id uuid.UUID, resetRetryCount bool, requestedBy, reason string) (*model.ReportRequest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	previous, err := lockStatus(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !statusIn(previous, model.StatusFailed, model.StatusCancelled) {
+		return nil, model.ErrStatusConflict
+	}
+
+	query := `
+		UPDATE reporting.report_requests` + requeueSet + `
+		WHERE id = $6
+		RETURNING ` + reportRequestColumns
+
+	request, err := scanReportRequest(tx.QueryRowContext(ctx, query,
+		model.StatusPending,
+		resetRetryCount,
+		requestedBy,
+		reason,
+		time.Now(),
+		id,
+	))
+	if err == nil {
+		err = recordRequeued(ctx, tx, id, previous, resetRetryCount, requestedBy, reason)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.WithError(err).WithField("request_id", id).Error("Failed to requeue report request")
+		return nil, err
+	}
 
-	rows, err := r.db.QueryContext(ctx, query, model.StatusPending, time.Now().Add(-timeout))
+	return request, nil
+}
+
+// RequeueMatching возвращает в статус PENDING не более limit запросов, подходящих под фильтр.
+// Фильтр по статусам должен содержать только FAILED и/или CANCELLED.
+func (r *ReportRequestRepository) RequeueMatching(ctx context.Context, filter model.ReportRequestFilter, limit int, resetRetryCount bool, requestedBy, reason string) ([]uuid.UUID, error) {
+	args := queryArgs{model.StatusPending, resetRetryCount, requestedBy, reason, time.Now()}
+	conditions := filterConditions(filter, &args)
+
+	// Подзапрос блокирует строки и сохраняет статус до обновления для истории
+	query := `
+		UPDATE reporting.report_requests` + requeueSet + `
+		FROM (
+			SELECT id, status
+			FROM reporting.report_requests
+			WHERE ` + strings.Join(conditions, " AND ") + `
+			ORDER BY created_at ASC
+			LIMIT ` + args.add(limit) + `
+			FOR UPDATE SKIP LOCKED
+		) AS previous
+		WHERE report_requests.id = previous.id
+		RETURNING report_requests.id, previous.status`
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
+		log.WithError(err).Error("Failed to requeue report requests")
+		return nil, err
+	}
+
+	var (
+		ids      []uuid.UUID
+		previous []model.ReportStatus
+	)
+	for rows.Next() {
+		var (
+			id     uuid.UUID
+			status model.ReportStatus
+		)
+		if err := rows.Scan(&id, &status); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+		previous = append(previous, status)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, id := range ids {
+		if err := recordRequeued(ctx, tx, id, previous[i], resetRetryCount, requestedBy, reason); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// ListEvents возвращает историю запроса в порядке записи
+func (r *ReportRequestRepository) ListEvents(ctx context.Context, id uuid.UUID) ([]*model.ReportRequestEvent, error) {
+	query := `
+		SELECT id, request_id, event_type, from_status, to_status, retry_count, error, actor, source, details, created_at
+		FROM reporting.report_request_events
+		WHERE request_id = $1
+		ORDER BY id ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		log.WithError(err).WithField("request_id", id).Error("Failed to list report request events")
 		return nil, err
 	}
 	defer rows.Close()
 
-	var requests []*model.ReportRequest
+	events := []*model.ReportRequestEvent{}
 	for rows.Next() {
-		request := &model.ReportRequest{}
+		event := &model.ReportRequestEvent{}
 		err := rows.Scan(
-			&request.ID,
-			&request.UserID,
-			&request.Status,
-			&request.Type,
-			&request.Params,
-			&request.CreatedAt,
-			&request.UpdatedAt,
+			&event.ID,
+			&event.RequestID,
+			&event.EventType,
+			&event.FromStatus,
+			&event.ToStatus,
+			&event.RetryCount,
+			&event.Error,
+			&event.Actor,
+			&event.Source,
+			&event.Details,
+			&event.CreatedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
-		requests = append(requests, request)
+		events = append(events, event)
 	}
 
-	return requests, nil
+	return events, rows.Err()
+}
+
+// GetUserLoad возвращает нагрузку пользователя на очередь: сколько запросов он создал с момента since
+// и сколько его запросов ожидают генерации или генерируются
+func (r *ReportRequestRepository) GetUserLoad(ctx context.Context, userID int, since time.Time) (*model.UserLoad, error) {
+	query := `
+		SELECT
+			count(*) FILTER (WHERE created_at >= $2),
+			min(created_at) FILTER (WHERE created_at >= $2),
+			count(*) FILTER (WHERE status IN ($3, $4))
+		FROM reporting.report_requests
+		WHERE user_id = $1 AND (created_at >= $2 OR status IN ($3, $4))
+	`
+
+	load := &model.UserLoad{}
+	var oldest sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, userID, since, model.StatusPending, model.StatusInProgress).
+		Scan(&load.Recent, &oldest, &load.Active)
+	if err != nil {
+		return nil, err
+	}
+	if oldest.Valid {
+		load.OldestRecent = oldest.Time
+	}
+	return load, nil
+}
+
+// CountPending возвращает количество запросов, ожидающих генерации
+func (r *ReportRequestRepository) CountPending(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT count(*) FROM reporting.report_requests WHERE status = $1
+	`, model.StatusPending).Scan(&count)
+	return count, err
 }
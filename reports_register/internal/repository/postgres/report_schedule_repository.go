@@ -0,0 +1,269 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type ReportScheduleRepository struct {
+	db *sql.DB
+}
+
+func NewReportScheduleRepository(db *sql.DB) *ReportScheduleRepository {
+	return &ReportScheduleRepository{db: db}
+}
+
+// reportScheduleColumns перечисляет колонки, читаемые scanReportSchedule
+const reportScheduleColumns = `id, name, owner_id, cron_expr, timezone, type, params_template, enabled, priority,
+	next_run_at, last_run_at, last_error, created_at, updated_at`
+
+func scanReportSchedule(row rowScanner) (*model.ReportSchedule, error) {
+	schedule := &model.ReportSchedule{}
+	err := row.Scan(
+		&schedule.ID,
+		&schedule.Name,
+		&schedule.OwnerID,
+		&schedule.CronExpr,
+		&schedule.Timezone,
+		&schedule.Type,
+		&schedule.ParamsTemplate,
+		&schedule.Enabled,
+		&schedule.Priority,
+		&schedule.NextRunAt,
+		&schedule.LastRunAt,
+		&schedule.LastError,
+		&schedule.CreatedAt,
+		&schedule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// Create сохраняет новое расписание
+func (r *ReportScheduleRepository) Create(ctx context.Context, schedule *model.ReportSchedule) error {
+	query := `
+		INSERT INTO reporting.report_schedules (id, name, owner_id, cron_expr, timezone, type, params_template, enabled,
+			priority, next_run_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		schedule.ID,
+		schedule.Name,
+		schedule.OwnerID,
+		schedule.CronExpr,
+		schedule.Timezone,
+		schedule.Type,
+		schedule.ParamsTemplate,
+		schedule.Enabled,
+		schedule.Priority,
+		schedule.NextRunAt,
+		schedule.CreatedAt,
+		schedule.UpdatedAt,
+	)
+	if err != nil {
+		log.WithError(err).WithField("schedule_id", schedule.ID).Error("Failed to create report schedule")
+	}
+	return err
+}
+
+// GetByID получает расписание по идентификатору
+func (r *ReportScheduleRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.ReportSchedule, error) {
+	query := `SELECT ` + reportScheduleColumns + `
+		FROM reporting.report_schedules
+		WHERE id = $1
+	`
+
+	schedule, err := scanReportSchedule(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrScheduleNotFound
+	}
+	return schedule, err
+}
+
+// List получает расписания; если ownerID не nil — только расписания этого пользователя
+func (r *ReportScheduleRepository) List(ctx context.Context, ownerID *int) ([]*model.ReportSchedule, error) {
+	query := `SELECT ` + reportScheduleColumns + `
+		FROM reporting.report_schedules
+		WHERE $1::int IS NULL OR owner_id = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := make([]*model.ReportSchedule, 0)
+	for rows.Next() {
+		schedule, err := scanReportSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, rows.Err()
+}
+
+// Update сохраняет изменения расписания и сбрасывает последнюю ошибку
+func (r *ReportScheduleRepository) Update(ctx context.Context, schedule *model.ReportSchedule) error {
+	query := `
+		UPDATE reporting.report_schedules
+		SET name = $1, cron_expr = $2, timezone = $3, type = $4, params_template = $5, enabled = $6,
+			priority = $7, next_run_at = $8, last_error = NULL, updated_at = $9
+		WHERE id = $10
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		schedule.Name,
+		schedule.CronExpr,
+		schedule.Timezone,
+		schedule.Type,
+		schedule.ParamsTemplate,
+		schedule.Enabled,
+		schedule.Priority,
+		schedule.NextRunAt,
+		schedule.UpdatedAt,
+		schedule.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, model.ErrScheduleNotFound)
+}
+
+// Delete удаляет расписание; уже созданные по нему запросы сохраняются
+func (r *ReportScheduleRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM reporting.report_schedules WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, model.ErrScheduleNotFound)
+}
+
+// ScheduleRun — результат планирования одного запуска расписания
+type ScheduleRun struct {
+	// Request — запрос на отчет для создания; nil, если запуск пропускается
+	Request *model.ReportRequest
+	// NextRunAt — следующее время запуска расписания
+	NextRunAt time.Time
+	// Err — ошибка подготовки запуска, сохраняется в last_error расписания
+	Err error
+}
+
+// RunDue обрабатывает наступившие расписания. Каждое расписание блокируется через
+// FOR UPDATE SKIP LOCKED, поэтому при нескольких репликах его обрабатывает только одна;
+// создание запроса и перенос next_run_at выполняются в одной транзакции, а уникальный
+// индекс (schedule_id, scheduled_for) дополнительно защищает от повторного создания.
+func (r *ReportScheduleRepository) RunDue(ctx context.Context, now time.Time, limit int, plan func(*model.ReportSchedule) ScheduleRun) (int, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + reportScheduleColumns + `
+		FROM reporting.report_schedules
+		WHERE enabled AND next_run_at <= $1
+		ORDER BY next_run_at ASC
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+	rows, err := tx.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	var schedules []*model.ReportSchedule
+	for rows.Next() {
+		schedule, err := scanReportSchedule(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, schedule := range schedules {
+		run := plan(schedule)
+
+		var lastError *string
+		if run.Err != nil {
+			message := run.Err.Error()
+			lastError = &message
+		}
+
+		if run.Request != nil {
+			inserted, err := insertScheduledRequest(ctx, tx, run.Request)
+			if err != nil {
+				return 0, err
+			}
+			if inserted {
+				created++
+			}
+		}
+
+		updateQuery := `
+			UPDATE reporting.report_schedules
+			SET next_run_at = $1, last_run_at = $2, last_error = $3
+			WHERE id = $4
+		`
+		if _, err := tx.ExecContext(ctx, updateQuery, run.NextRunAt, now, lastError, schedule.ID); err != nil {
+			return 0, err
+		}
+
+		log.WithFields(logrus.Fields{
+			"schedule_id": schedule.ID,
+			"next_run_at": run.NextRunAt,
+			"error":       lastError,
+		}).Debug("Processed due report schedule")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return created, nil
+}
+
+// insertScheduledRequest создает запрос по расписанию; повторная вставка для того же
+// запуска расписания игнорируется
+func insertScheduledRequest(ctx context.Context, tx *sql.Tx, request *model.ReportRequest) (bool, error) {
+	result, err := insertReportRequest(ctx, tx, request, `
+		ON CONFLICT (schedule_id, scheduled_for) DO NOTHING`)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+	return true, recordCreated(ctx, tx, request, "schedule:"+request.ScheduleID.String())
+}
+
+// requireAffected возвращает notFound, если запрос не затронул ни одной строки
+func requireAffected(result sql.Result, notFound error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return notFound
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+)
+
+// Stats собирает статистику очереди за окно [from, to); topErrors ограничивает число самых частых ошибок.
+// Все запросы выполняются в одной транзакции REPEATABLE READ, поэтому цифры согласованы между собой.
+func (r *ReportRequestRepository) Stats(ctx context.Context, from, to time.Time, topErrors int) (*model.QueueStats, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stats := &model.QueueStats{
+		From:      from,
+		To:        to,
+		ByStatus:  map[model.ReportStatus]int{},
+		ByType:    []model.TypeStats{},
+		TopErrors: []model.ErrorStats{},
+	}
+
+	if err := statsByType(ctx, tx, stats); err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT
+			count(*) FILTER (WHERE status = $1),
+			count(*) FILTER (WHERE status = $2),
+			COALESCE(EXTRACT(EPOCH FROM $3 - min(created_at) FILTER (WHERE status = $1)), 0)
+		FROM reporting.report_requests
+		WHERE status IN ($1, $2)
+	`, model.StatusPending, model.StatusInProgress, to).Scan(&stats.Pending, &stats.InProgress, &stats.OldestPendingAgeSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	// Время генерации считается до updated_at, которое для COMPLETED совпадает с моментом завершения
+	c := &stats.Completion
+	err = tx.QueryRowContext(ctx, `
+		WITH durations AS (
+			SELECT EXTRACT(EPOCH FROM updated_at - created_at) AS seconds
+			FROM reporting.report_requests
+			WHERE status = $1 AND reused_from IS NULL
+			AND updated_at >= $2 AND updated_at < $3
+		)
+		SELECT
+			count(*),
+			COALESCE(avg(seconds), 0),
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY seconds), 0),
+			COALESCE(percentile_cont(0.9) WITHIN GROUP (ORDER BY seconds), 0),
+			COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY seconds), 0),
+			COALESCE(percentile_cont(0.99) WITHIN GROUP (ORDER BY seconds), 0),
+			COALESCE(max(seconds), 0)
+		FROM durations
+	`, model.StatusCompleted, from, to).Scan(&c.Count, &c.Avg, &c.P50, &c.P90, &c.P95, &c.P99, &c.Max)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT
+			count(*) FILTER (WHERE status = $1),
+			count(*) FILTER (WHERE status = $2)
+		FROM reporting.report_requests
+		WHERE status IN ($1, $2) AND updated_at >= $3 AND updated_at < $4
+	`, model.StatusCompleted, model.StatusFailed, from, to).Scan(&stats.Completed, &stats.Failed)
+	if err != nil {
+		return nil, err
+	}
+	if finished := stats.Completed + stats.Failed; finished > 0 {
+		stats.FailureRate = float64(stats.Failed) / float64(finished)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT COALESCE(error, ''), count(*)
+		FROM reporting.report_requests
+		WHERE status = $1 AND updated_at >= $2 AND updated_at < $3
+		GROUP BY 1
+		ORDER BY 2 DESC, 1
+		LIMIT $4
+	`, model.StatusFailed, from, to, topErrors)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e model.ErrorStats
+		if err := rows.Scan(&e.Error, &e.Count); err != nil {
+			return nil, err
+		}
+		stats.TopErrors = append(stats.TopErrors, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// statsByType заполняет счетчики по статусам и типам для запросов, созданных в окне
+func statsByType(ctx context.Context, tx *sql.Tx, stats *model.QueueStats) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT type, status, count(*)
+		FROM reporting.report_requests
+		WHERE created_at >= $1 AND created_at < $2
+		GROUP BY type, status
+		ORDER BY type, status
+	`, stats.From, stats.To)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			reportType string
+			status     model.ReportStatus
+			count      int
+		)
+		if err := rows.Scan(&reportType, &status, &count); err != nil {
+			return err
+		}
+
+		stats.ByStatus[status] += count
+		if n := len(stats.ByType); n == 0 || stats.ByType[n-1].Type != reportType {
+			stats.ByType = append(stats.ByType, model.TypeStats{Type: reportType, ByStatus: map[model.ReportStatus]int{}})
+		}
+		typeStats := &stats.ByType[len(stats.ByType)-1]
+		typeStats.Total += count
+		typeStats.ByStatus[status] = count
+	}
+
+	return rows.Err()
+}
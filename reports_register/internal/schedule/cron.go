@@ -0,0 +1,67 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Spec — разобранное cron-выражение расписания в заданном часовом поясе
+type Spec struct {
+	schedule cron.Schedule
+	location *time.Location
+}
+
+// Parse разбирает стандартное cron-выражение из пяти полей (или дескриптор вроде @monthly)
+// и часовой пояс IANA; пустой часовой пояс означает UTC
+func Parse(expr, timezone string) (*Spec, error) {
+	location := time.UTC
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("unknown timezone %q", timezone)
+		}
+		location = loc
+	}
+
+	schedule, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %v", expr, err)
+	}
+
+	return &Spec{schedule: schedule, location: location}, nil
+}
+
+// Next возвращает ближайшее время запуска строго после after.
+// Расписание считается по местным часам: запуск, попавший в час, пропущенный при переходе
+// на летнее время, сдвигается вперед на величину перехода, а повторяющийся при переходе
+// на зимнее время час не дает второго запуска. Если подходящего времени нет, возвращается нулевое время.
+func (s *Spec) Next(after time.Time) time.Time {
+	// Местные часы переносятся в UTC, где у каждого показания часов ровно один момент
+	wall := asWallClock(after.In(s.location), time.UTC)
+	for {
+		wall = s.schedule.Next(wall)
+		if wall.IsZero() {
+			return time.Time{}
+		}
+		// Повторяющееся местное время time.Date относит к первому из двух моментов.
+		// Несуществующее он может отнести к моменту до перехода, поэтому время догоняется
+		// до показаний часов расписания.
+		next := asWallClock(wall, s.location)
+		next = next.Add(wall.Sub(asWallClock(next, time.UTC)))
+		if next.After(after) {
+			return next
+		}
+	}
+}
+
+// asWallClock возвращает время с теми же показаниями часов, что и t, в часовом поясе location
+func asWallClock(t time.Time, location *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), location)
+}
+
+// Location возвращает часовой пояс расписания
+func (s *Spec) Location() *time.Location {
+	return s.location
+}
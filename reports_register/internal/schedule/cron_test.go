@@ -0,0 +1,130 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, expr, timezone string) *Spec {
+	t.Helper()
+	spec, err := Parse(expr, timezone)
+	if err != nil {
+		t.Fatalf("Parse(%q, %q): %v", expr, timezone, err)
+	}
+	return spec
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr     string
+		timezone string
+		wantErr  bool
+		wantLoc  string
+	}{
+		{expr: "0 9 * * *", wantLoc: "UTC"},
+		{expr: "0 9 * * 1-5", timezone: "Europe/Moscow", wantLoc: "Europe/Moscow"},
+		{expr: "@monthly", timezone: "America/New_York", wantLoc: "America/New_York"},
+		{expr: "0 9 * *", wantErr: true},
+		{expr: "0 0 9 * * *", wantErr: true},
+		{expr: "61 9 * * *", wantErr: true},
+		{expr: "0 9 * * *", timezone: "Mars/Olympus", wantErr: true},
+	}
+	for _, tt := range tests {
+		spec, err := Parse(tt.expr, tt.timezone)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q, %q) succeeded, want an error", tt.expr, tt.timezone)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q, %q): %v", tt.expr, tt.timezone, err)
+			continue
+		}
+		if got := spec.Location().String(); got != tt.wantLoc {
+			t.Errorf("Parse(%q, %q) location = %s, want %s", tt.expr, tt.timezone, got, tt.wantLoc)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	utc := func(value string) time.Time {
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return at
+	}
+	tests := []struct {
+		name     string
+		expr     string
+		timezone string
+		after    string
+		want     []string
+	}{
+		{
+			name: "utc by default", expr: "0 9 * * *",
+			after: "2024-05-01T09:00:00Z",
+			want:  []string{"2024-05-02T09:00:00Z", "2024-05-03T09:00:00Z"},
+		},
+		{
+			name: "local time of the schedule", expr: "0 9 * * *", timezone: "Europe/Moscow",
+			after: "2024-05-01T05:00:00Z",
+			want:  []string{"2024-05-01T06:00:00Z", "2024-05-02T06:00:00Z"},
+		},
+		{
+			name: "weekdays across a weekend", expr: "0 9 * * 1-5", timezone: "Europe/Moscow",
+			after: "2024-05-03T07:00:00Z",
+			want:  []string{"2024-05-06T06:00:00Z"},
+		},
+		{
+			name: "monthly at local midnight", expr: "@monthly", timezone: "Asia/Tokyo",
+			after: "2024-05-15T00:00:00Z",
+			want:  []string{"2024-05-31T15:00:00Z", "2024-06-30T15:00:00Z"},
+		},
+		{
+			name: "same local hour across spring forward", expr: "0 9 * * *", timezone: "America/New_York",
+			after: "2024-03-09T15:00:00Z",
+			want:  []string{"2024-03-10T13:00:00Z", "2024-03-11T13:00:00Z"},
+		},
+		{
+			name: "run in the skipped hour is shifted, not dropped", expr: "30 2 * * *", timezone: "America/New_York",
+			after: "2024-03-09T08:00:00Z",
+			// 2024-03-10 02:30 не существует и становится 03:30 EDT
+			want: []string{"2024-03-10T07:30:00Z", "2024-03-11T06:30:00Z"},
+		},
+		{
+			name: "repeated hour runs once on fall back", expr: "30 1 * * *", timezone: "America/New_York",
+			after: "2024-11-02T08:00:00Z",
+			// 2024-11-03 01:30 бывает дважды: в 05:30Z (EDT) и в 06:30Z (EST)
+			want: []string{"2024-11-03T05:30:00Z", "2024-11-04T06:30:00Z"},
+		},
+		{
+			name: "hourly across fall back", expr: "0 * * * *", timezone: "America/New_York",
+			after: "2024-11-03T04:30:00Z",
+			want:  []string{"2024-11-03T05:00:00Z", "2024-11-03T07:00:00Z", "2024-11-03T08:00:00Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := mustParse(t, tt.expr, tt.timezone)
+			at := utc(tt.after)
+			for i, want := range tt.want {
+				at = spec.Next(at)
+				if !at.Equal(utc(want)) {
+					t.Fatalf("run %d = %s, want %s", i+1, at.UTC().Format(time.RFC3339), want)
+				}
+				if at.Location() != spec.Location() {
+					t.Errorf("run %d is in %s, want %s", i+1, at.Location(), spec.Location())
+				}
+			}
+		})
+	}
+}
+
+func TestNextWithoutMatchingTime(t *testing.T) {
+	spec := mustParse(t, "0 0 30 2 *", "")
+	if next := spec.Next(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); !next.IsZero() {
+		t.Errorf("Next = %s, want zero time for February 30", next)
+	}
+}
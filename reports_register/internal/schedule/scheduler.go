@@ -0,0 +1,131 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/KostySCH/Reports_go/pkg/reporttype"
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+	"github.com/KostySCH/Reports_go/reports_register/internal/repository/postgres"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+var log = logrus.New()
+
+func init() {
+	log.SetFormatter(&logrus.JSONFormatter{})
+	log.SetLevel(logrus.DebugLevel)
+}
+
+// retryAfterError — через сколько повторить запуск расписания, cron-выражение которого не разбирается
+const retryAfterError = time.Hour
+
+// Scheduler периодически создает запросы на отчет по наступившим расписаниям.
+// Может работать одновременно в нескольких репликах reports_register.
+type Scheduler struct {
+	repo       *postgres.ReportScheduleRepository
+	types      *reporttype.Registry
+	pollPeriod time.Duration
+	batchSize  int
+}
+
+func NewScheduler(repo *postgres.ReportScheduleRepository, types *reporttype.Registry, pollPeriod time.Duration, batchSize int) *Scheduler {
+	return &Scheduler{
+		repo:       repo,
+		types:      types,
+		pollPeriod: pollPeriod,
+		batchSize:  batchSize,
+	}
+}
+
+// Start запускает цикл планировщика в отдельной горутине до отмены ctx
+func (s *Scheduler) Start(ctx context.Context) {
+	log.WithField("poll_period", s.pollPeriod.String()).Info("Starting report scheduler")
+
+	go func() {
+		ticker := time.NewTicker(s.pollPeriod)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info("Report scheduler stopped")
+				return
+			case <-ticker.C:
+				s.runDue(ctx)
+			}
+		}
+	}()
+}
+
+// runDue обрабатывает наступившие расписания пачками, пока они не закончатся
+func (s *Scheduler) runDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		now := time.Now()
+		processed := 0
+		created, err := s.repo.RunDue(ctx, now, s.batchSize, func(schedule *model.ReportSchedule) postgres.ScheduleRun {
+			processed++
+			return s.plan(schedule, now)
+		})
+		if err != nil {
+			log.WithError(err).Error("Failed to run due report schedules")
+			return
+		}
+		if created > 0 {
+			log.WithField("created", created).Info("Created scheduled report requests")
+		}
+		if processed < s.batchSize {
+			return
+		}
+	}
+}
+
+// plan готовит запрос на отчет для наступившего запуска расписания. Если сервис был недоступен
+// и пропустил несколько запусков, создается один запрос за последний пропущенный запуск.
+func (s *Scheduler) plan(schedule *model.ReportSchedule, now time.Time) postgres.ScheduleRun {
+	spec, err := Parse(schedule.CronExpr, schedule.Timezone)
+	if err != nil {
+		return postgres.ScheduleRun{NextRunAt: now.Add(retryAfterError), Err: err}
+	}
+
+	scheduledFor := schedule.NextRunAt
+	for next := spec.Next(scheduledFor); !next.After(now); next = spec.Next(next) {
+		scheduledFor = next
+	}
+	run := postgres.ScheduleRun{NextRunAt: spec.Next(now)}
+
+	var template map[string]interface{}
+	if err := json.Unmarshal(schedule.ParamsTemplate, &template); err != nil {
+		run.Err = fmt.Errorf("invalid params template: %v", err)
+		return run
+	}
+
+	params, err := s.types.Validate(schedule.Type, spec.RenderParams(template, scheduledFor))
+	if err != nil {
+		run.Err = err
+		return run
+	}
+
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		run.Err = err
+		return run
+	}
+
+	run.Request = &model.ReportRequest{
+		ID:           uuid.New(),
+		UserID:       schedule.OwnerID,
+		Type:         schedule.Type,
+		Params:       model.JSON(paramsJSON),
+		Status:       model.StatusPending,
+		Priority:     schedule.Priority,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		ScheduleID:   &schedule.ID,
+		ScheduledFor: &scheduledFor,
+	}
+	return run
+}
@@ -0,0 +1,43 @@
+package schedule
+
+import (
+	"strings"
+	"time"
+)
+
+// Плейсхолдеры относительных периодов, которые можно использовать в строковых значениях шаблона параметров.
+// Значения вычисляются относительно времени запуска расписания в его часовом поясе.
+var placeholders = map[string]func(at time.Time) string{
+	"{{today}}":          func(at time.Time) string { return at.Format("2006-01-02") },
+	"{{yesterday}}":      func(at time.Time) string { return at.AddDate(0, 0, -1).Format("2006-01-02") },
+	"{{current_month}}":  func(at time.Time) string { return at.Format("2006-01") },
+	"{{previous_month}}": func(at time.Time) string { return firstOfMonth(at).AddDate(0, -1, 0).Format("2006-01") },
+	"{{current_year}}":   func(at time.Time) string { return at.Format("2006") },
+	"{{previous_year}}":  func(at time.Time) string { return at.AddDate(-1, 0, 0).Format("2006") },
+}
+
+func firstOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// RenderParams подставляет относительные периоды в строковые значения шаблона параметров,
+// например {"month": "{{previous_month}}"} при запуске 2025-06-01 дает {"month": "2025-05"}
+func (s *Spec) RenderParams(template map[string]interface{}, at time.Time) map[string]interface{} {
+	at = at.In(s.location)
+
+	params := make(map[string]interface{}, len(template))
+	for name, value := range template {
+		str, ok := value.(string)
+		if !ok || !strings.Contains(str, "{{") {
+			params[name] = value
+			continue
+		}
+		for placeholder, render := range placeholders {
+			if strings.Contains(str, placeholder) {
+				str = strings.ReplaceAll(str, placeholder, render(at))
+			}
+		}
+		params[name] = str
+	}
+	return params
+}
@@ -0,0 +1,79 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRenderParams(t *testing.T) {
+	template := map[string]interface{}{
+		"branch_id": float64(3),
+		"draft":     true,
+		"format":    "pdf",
+		"today":     "{{today}}",
+		"yesterday": "{{yesterday}}",
+		"month":     "{{current_month}}",
+		"previous":  "{{previous_month}}",
+		"year":      "{{current_year}}",
+		"last_year": "{{previous_year}}",
+		"period":    "{{previous_month}}..{{current_month}}",
+		"unknown":   "{{next_month}}",
+	}
+	tests := []struct {
+		name     string
+		timezone string
+		at       time.Time
+		want     map[string]interface{}
+	}{
+		{
+			name: "first day of the year",
+			at:   time.Date(2025, 1, 1, 6, 0, 0, 0, time.UTC),
+			want: map[string]interface{}{
+				"today": "2025-01-01", "yesterday": "2024-12-31", "month": "2025-01", "previous": "2024-12",
+				"year": "2025", "last_year": "2024", "period": "2024-12..2025-01",
+			},
+		},
+		{
+			// AddDate(0, -1, 0) от 31 марта дал бы 3 марта, а не февраль
+			name: "end of a long month",
+			at:   time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC),
+			want: map[string]interface{}{
+				"today": "2025-03-31", "yesterday": "2025-03-30", "month": "2025-03", "previous": "2025-02",
+				"year": "2025", "last_year": "2024", "period": "2025-02..2025-03",
+			},
+		},
+		{
+			// В UTC еще 31 мая, а в Москве уже 1 июня
+			name:     "schedule timezone",
+			timezone: "Europe/Moscow",
+			at:       time.Date(2025, 5, 31, 22, 0, 0, 0, time.UTC),
+			want: map[string]interface{}{
+				"today": "2025-06-01", "yesterday": "2025-05-31", "month": "2025-06", "previous": "2025-05",
+				"year": "2025", "last_year": "2024", "period": "2025-05..2025-06",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mustParse(t, "@daily", tt.timezone).RenderParams(template, tt.at)
+
+			want := map[string]interface{}{
+				"branch_id": float64(3),
+				"draft":     true,
+				"format":    "pdf",
+				"unknown":   "{{next_month}}",
+			}
+			for name, value := range tt.want {
+				want[name] = value
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("params = %v, want %v", got, want)
+			}
+		})
+	}
+
+	if template["month"] != "{{current_month}}" {
+		t.Errorf("template was modified: %v", template)
+	}
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// rateWindow — окно, в котором считается частота создания запросов
+	rateWindow = time.Minute
+	// activeRetryAfter — рекомендуемая пауза, когда у пользователя слишком много незавершенных запросов
+	activeRetryAfter = 30 * time.Second
+	// backpressureRetryAfter — рекомендуемая пауза, когда очередь переполнена
+	backpressureRetryAfter = time.Minute
+)
+
+// Limits задает ограничения на создание запросов. Нулевое значение отключает ограничение.
+type Limits struct {
+	// RequestsPerMinute — сколько запросов пользователь может создать за минуту
+	RequestsPerMinute int
+	// MaxActivePerUser — сколько запросов пользователя могут одновременно быть в PENDING и IN_PROGRESS
+	MaxActivePerUser int
+	// MaxQueueDepth — при таком количестве запросов в PENDING новые запросы не принимаются ни от кого
+	MaxQueueDepth int
+}
+
+// LimitError возвращается, если запрос отклонен из-за ограничений
+type LimitError struct {
+	// Global — true, если переполнена общая очередь, а не исчерпана квота пользователя
+	Global     bool
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("limit exceeded: %s", e.Reason)
+}
+
+// Limiter проверяет квоты пользователей и общую глубину очереди по данным в базе,
+// поэтому ограничения действуют одинаково для всех экземпляров reports_register.
+// Параллельные запросы одного пользователя могут превысить квоту на несколько штук.
+type Limiter struct {
+	repo   LoadCounter
+	limits Limits
+}
+
+func NewLimiter(repo LoadCounter, limits Limits) *Limiter {
+	return &Limiter{repo: repo, limits: limits}
+}
+
+// CheckUser проверяет, что пользователь может создать еще n запросов: с ними не будет превышена
+// ни частота запросов, ни количество незавершенных запросов
+func (l *Limiter) CheckUser(ctx context.Context, userID, n int) error {
+	if l.limits.RequestsPerMinute <= 0 && l.limits.MaxActivePerUser <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	load, err := l.repo.GetUserLoad(ctx, userID, now.Add(-rateWindow))
+	if err != nil {
+		log.WithError(err).WithField("user_id", userID).Error("Failed to get user queue load")
+		return err
+	}
+
+	if l.limits.RequestsPerMinute > 0 && load.Recent+n > l.limits.RequestsPerMinute {
+		// Место в окне начнет освобождаться, когда из него выйдет самый ранний запрос
+		retryAfter := rateWindow
+		if load.Recent > 0 {
+			retryAfter = load.OldestRecent.Add(rateWindow).Sub(now)
+		}
+		if retryAfter < time.Second {
+			retryAfter = time.Second
+		}
+		return l.reject(userID, &LimitError{
+			Reason:     fmt.Sprintf("at most %d report requests per minute are allowed", l.limits.RequestsPerMinute),
+			RetryAfter: retryAfter,
+		})
+	}
+
+	if l.limits.MaxActivePerUser > 0 && load.Active+n > l.limits.MaxActivePerUser {
+		return l.reject(userID, &LimitError{
+			Reason:     fmt.Sprintf("at most %d pending or in-progress report requests are allowed", l.limits.MaxActivePerUser),
+			RetryAfter: activeRetryAfter,
+		})
+	}
+
+	return nil
+}
+
+// CheckQueue проверяет, что после добавления n запросов очередь не превысит допустимую глубину
+func (l *Limiter) CheckQueue(ctx context.Context, userID, n int) error {
+	if l.limits.MaxQueueDepth <= 0 {
+		return nil
+	}
+
+	depth, err := l.repo.CountPending(ctx)
+	if err != nil {
+		log.WithError(err).Error("Failed to get report queue depth")
+		return err
+	}
+
+	if depth+n > l.limits.MaxQueueDepth {
+		return l.reject(userID, &LimitError{
+			Global:     true,
+			Reason:     "report queue is full, try again later",
+			RetryAfter: backpressureRetryAfter,
+		})
+	}
+	return nil
+}
+
+func (l *Limiter) reject(userID int, err *LimitError) error {
+	log.WithFields(logrus.Fields{
+		"user_id":     userID,
+		"global":      err.Global,
+		"retry_after": err.RetryAfter,
+	}).Warn(err.Error())
+	return err
+}
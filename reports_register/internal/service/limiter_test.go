@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+)
+
+type stubLoad struct {
+	load    model.UserLoad
+	pending int
+	calls   int
+}
+
+func (s *stubLoad) GetUserLoad(ctx context.Context, userID int, since time.Time) (*model.UserLoad, error) {
+	s.calls++
+	load := s.load
+	return &load, nil
+}
+
+func (s *stubLoad) CountPending(ctx context.Context) (int, error) {
+	s.calls++
+	return s.pending, nil
+}
+
+func TestLimiterCheckUser(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		limits Limits
+		load   model.UserLoad
+		n      int
+		// wantRetryAfter — ожидаемая пауза с точностью до секунды; 0 — запрос должен пройти
+		wantRetryAfter time.Duration
+	}{
+		{name: "no limits", load: model.UserLoad{Recent: 100, Active: 100}, n: 1},
+		{name: "below rate", limits: Limits{RequestsPerMinute: 3}, load: model.UserLoad{Recent: 2, OldestRecent: now}, n: 1},
+		{name: "rate reached", limits: Limits{RequestsPerMinute: 3},
+			load: model.UserLoad{Recent: 3, OldestRecent: now.Add(-45 * time.Second)}, n: 1, wantRetryAfter: 15 * time.Second},
+		{name: "rate exceeded by batch", limits: Limits{RequestsPerMinute: 3},
+			load: model.UserLoad{Recent: 1, OldestRecent: now.Add(-20 * time.Second)}, n: 3, wantRetryAfter: 40 * time.Second},
+		{name: "batch larger than rate", limits: Limits{RequestsPerMinute: 3}, n: 4, wantRetryAfter: time.Minute},
+		{name: "retry after at least a second", limits: Limits{RequestsPerMinute: 1},
+			load: model.UserLoad{Recent: 1, OldestRecent: now.Add(-time.Minute)}, n: 1, wantRetryAfter: time.Second},
+		{name: "below active", limits: Limits{MaxActivePerUser: 3}, load: model.UserLoad{Active: 1}, n: 2},
+		{name: "active exceeded", limits: Limits{MaxActivePerUser: 3}, load: model.UserLoad{Active: 2}, n: 2, wantRetryAfter: activeRetryAfter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &stubLoad{load: tt.load}
+			err := NewLimiter(repo, tt.limits).CheckUser(context.Background(), 1, tt.n)
+
+			if tt.wantRetryAfter == 0 {
+				if err != nil {
+					t.Fatalf("CheckUser: %v", err)
+				}
+				return
+			}
+			var lerr *LimitError
+			if !errors.As(err, &lerr) {
+				t.Fatalf("CheckUser error = %v, want *LimitError", err)
+			}
+			if lerr.Global {
+				t.Error("user quota error is marked as global")
+			}
+			if diff := lerr.RetryAfter - tt.wantRetryAfter; diff > time.Second || diff < -time.Second {
+				t.Errorf("RetryAfter = %v, want %v", lerr.RetryAfter, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestLimiterCheckUserWithoutLimitsSkipsRepository(t *testing.T) {
+	repo := &stubLoad{}
+	if err := NewLimiter(repo, Limits{MaxQueueDepth: 1}).CheckUser(context.Background(), 1, 1); err != nil {
+		t.Fatalf("CheckUser: %v", err)
+	}
+	if repo.calls != 0 {
+		t.Errorf("repository called %d times, want 0", repo.calls)
+	}
+}
+
+func TestLimiterCheckQueue(t *testing.T) {
+	tests := []struct {
+		name     string
+		depth    int
+		pending  int
+		n        int
+		wantFull bool
+	}{
+		{name: "disabled", pending: 1000, n: 1},
+		{name: "room left", depth: 10, pending: 9, n: 1},
+		{name: "full", depth: 10, pending: 10, n: 1, wantFull: true},
+		{name: "batch overflows", depth: 10, pending: 8, n: 3, wantFull: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &stubLoad{pending: tt.pending}
+			err := NewLimiter(repo, Limits{MaxQueueDepth: tt.depth}).CheckQueue(context.Background(), 1, tt.n)
+
+			if !tt.wantFull {
+				if err != nil {
+					t.Fatalf("CheckQueue: %v", err)
+				}
+				return
+			}
+			var lerr *LimitError
+			if !errors.As(err, &lerr) {
+				t.Fatalf("CheckQueue error = %v, want *LimitError", err)
+			}
+			if !lerr.Global {
+				t.Error("queue error is not marked as global")
+			}
+			if lerr.RetryAfter != backpressureRetryAfter {
+				t.Errorf("RetryAfter = %v, want %v", lerr.RetryAfter, backpressureRetryAfter)
+			}
+		})
+	}
+}
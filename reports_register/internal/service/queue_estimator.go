@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+)
+
+// EstimatorOptions задает оценку времени готовности
+type EstimatorOptions struct {
+	// Window — за какой период берутся длительности генерации
+	Window time.Duration
+	// Workers — сколько отчетов генератор формирует одновременно
+	Workers int
+	// CacheTTL — как долго используются однажды посчитанные длительности
+	CacheTTL time.Duration
+}
+
+// QueueEstimator оценивает место запроса в очереди и время его готовности. Длительность генерации
+// берется средней по типу отчета за Window, а для типов без истории — средней по всем типам.
+type QueueEstimator struct {
+	repo QueueSource
+	opts EstimatorOptions
+	// now подменяется в тестах
+	now func() time.Time
+
+	mu       sync.Mutex
+	loadedAt time.Time
+	byType   map[string]float64
+	overall  float64
+}
+
+func NewQueueEstimator(repo QueueSource, opts EstimatorOptions) *QueueEstimator {
+	return &QueueEstimator{repo: repo, opts: opts, now: time.Now}
+}
+
+// Estimate дополняет info местом в очереди для PENDING и ожидаемым временем готовности
+// для PENDING и IN_PROGRESS. Для остальных статусов ничего не делает.
+func (e *QueueEstimator) Estimate(ctx context.Context, request *model.ReportRequest, info *model.ReportStatusInfo) error {
+	if request.Status != model.StatusPending && request.Status != model.StatusInProgress {
+		return nil
+	}
+
+	durations, overall, err := e.durations(ctx)
+	if err != nil {
+		return err
+	}
+	duration := func(reportType string) (float64, bool) {
+		if d, ok := durations[reportType]; ok {
+			return d, true
+		}
+		return overall, overall > 0
+	}
+
+	now := e.now()
+	own, known := duration(request.Type)
+
+	if request.Status == model.StatusInProgress {
+		// Генератор обновляет updated_at при захвате запроса
+		if known {
+			eta := laterOf(request.UpdatedAt.Add(seconds(own)), now)
+			info.EstimatedCompletionAt = &eta
+		}
+		return nil
+	}
+
+	snapshot, err := e.repo.QueueSnapshot(ctx, request)
+	if err != nil {
+		return err
+	}
+	info.QueuePosition = &snapshot.Position
+	if !known {
+		return nil
+	}
+
+	// Работа, которую генератор должен выполнить до начала этого запроса: ожидающие запросы
+	// перед ним и остаток генерирующихся сейчас
+	var work float64
+	for reportType, count := range snapshot.Ahead {
+		d, ok := duration(reportType)
+		if !ok {
+			return nil
+		}
+		work += float64(count) * d
+	}
+	for _, running := range snapshot.Running {
+		d, ok := duration(running.Type)
+		if !ok {
+			return nil
+		}
+		work += math.Max(d-now.Sub(running.StartedAt).Seconds(), 0)
+	}
+
+	workers := e.opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	eta := now.Add(seconds(work/float64(workers) + own))
+	info.EstimatedCompletionAt = &eta
+	return nil
+}
+
+// durations возвращает средние длительности генерации по типам и по всем типам, обновляя их раз в CacheTTL
+func (e *QueueEstimator) durations(ctx context.Context) (map[string]float64, float64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.byType != nil && e.now().Sub(e.loadedAt) < e.opts.CacheTTL {
+		return e.byType, e.overall, nil
+	}
+
+	times, err := e.repo.GenerationTimes(ctx, e.now().Add(-e.opts.Window))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	byType := make(map[string]float64, len(times))
+	var sum float64
+	var count int
+	for _, t := range times {
+		byType[t.Type] = t.AvgSeconds
+		sum += t.AvgSeconds * float64(t.Count)
+		count += t.Count
+	}
+	e.byType = byType
+	e.overall = 0
+	if count > 0 {
+		e.overall = sum / float64(count)
+	}
+	e.loadedAt = e.now()
+	return e.byType, e.overall, nil
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+func laterOf(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+)
+
+type stubQueue struct {
+	snapshot model.QueueSnapshot
+	times    []model.GenerationTime
+	loads    int
+	since    time.Time
+}
+
+func (s *stubQueue) QueueSnapshot(ctx context.Context, request *model.ReportRequest) (*model.QueueSnapshot, error) {
+	snapshot := s.snapshot
+	return &snapshot, nil
+}
+
+func (s *stubQueue) GenerationTimes(ctx context.Context, since time.Time) ([]model.GenerationTime, error) {
+	s.loads++
+	s.since = since
+	return s.times, nil
+}
+
+func TestQueueEstimatorEstimate(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	// Средняя по всем типам взвешена количеством: (3*60 + 1*120) / 4 = 75 секунд
+	history := []model.GenerationTime{
+		{Type: "a", Count: 3, AvgSeconds: 60},
+		{Type: "b", Count: 1, AvgSeconds: 120},
+	}
+	tests := []struct {
+		name     string
+		request  model.ReportRequest
+		snapshot model.QueueSnapshot
+		times    []model.GenerationTime
+		workers  int
+		// wantPosition равен 0, если место в очереди не сообщается; wantETA равен -1, если нет оценки
+		wantPosition int
+		wantETA      time.Duration
+	}{
+		{
+			name:    "completed request is not estimated",
+			request: model.ReportRequest{Type: "a", Status: model.StatusCompleted},
+			times:   history,
+			wantETA: -1,
+		},
+		{
+			name:    "in progress finishes one average after start",
+			request: model.ReportRequest{Type: "a", Status: model.StatusInProgress, UpdatedAt: now.Add(-20 * time.Second)},
+			times:   history,
+			wantETA: 40 * time.Second,
+		},
+		{
+			name:    "overrunning request is expected now",
+			request: model.ReportRequest{Type: "a", Status: model.StatusInProgress, UpdatedAt: now.Add(-100 * time.Second)},
+			times:   history,
+			wantETA: 0,
+		},
+		{
+			name:    "in progress without history",
+			request: model.ReportRequest{Type: "a", Status: model.StatusInProgress, UpdatedAt: now},
+			wantETA: -1,
+		},
+		{
+			// Впереди 2*60 + 120 секунд ожидающих и 30 секунд остатка генерации на двух воркерах,
+			// затем 60 секунд собственной генерации: 270/2 + 60
+			name:    "pending behind queue and running work",
+			request: model.ReportRequest{Type: "a", Status: model.StatusPending},
+			snapshot: model.QueueSnapshot{
+				Position: 4,
+				Ahead:    map[string]int{"a": 2, "b": 1},
+				Running:  []model.RunningRequest{{Type: "a", StartedAt: now.Add(-30 * time.Second)}},
+			},
+			times:        history,
+			workers:      2,
+			wantPosition: 4,
+			wantETA:      195 * time.Second,
+		},
+		{
+			name:    "overrunning running request adds no work",
+			request: model.ReportRequest{Type: "b", Status: model.StatusPending},
+			snapshot: model.QueueSnapshot{
+				Position: 1,
+				Running:  []model.RunningRequest{{Type: "a", StartedAt: now.Add(-100 * time.Second)}},
+			},
+			times:        history,
+			workers:      1,
+			wantPosition: 1,
+			wantETA:      120 * time.Second,
+		},
+		{
+			name:         "type without history uses the overall average",
+			request:      model.ReportRequest{Type: "c", Status: model.StatusPending},
+			snapshot:     model.QueueSnapshot{Position: 2, Ahead: map[string]int{"c": 1}},
+			times:        history,
+			workers:      0,
+			wantPosition: 2,
+			wantETA:      150 * time.Second,
+		},
+		{
+			name:         "pending without history reports only the position",
+			request:      model.ReportRequest{Type: "a", Status: model.StatusPending},
+			snapshot:     model.QueueSnapshot{Position: 3, Ahead: map[string]int{"a": 2}},
+			workers:      1,
+			wantPosition: 3,
+			wantETA:      -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &stubQueue{snapshot: tt.snapshot, times: tt.times}
+			estimator := NewQueueEstimator(repo, EstimatorOptions{Window: time.Hour, Workers: tt.workers, CacheTTL: time.Minute})
+			estimator.now = func() time.Time { return now }
+
+			var info model.ReportStatusInfo
+			if err := estimator.Estimate(context.Background(), &tt.request, &info); err != nil {
+				t.Fatalf("Estimate: %v", err)
+			}
+
+			switch {
+			case tt.wantPosition == 0 && info.QueuePosition != nil:
+				t.Errorf("queue position = %d, want none", *info.QueuePosition)
+			case tt.wantPosition != 0 && (info.QueuePosition == nil || *info.QueuePosition != tt.wantPosition):
+				t.Errorf("queue position = %v, want %d", info.QueuePosition, tt.wantPosition)
+			}
+			switch {
+			case tt.wantETA < 0 && info.EstimatedCompletionAt != nil:
+				t.Errorf("eta = %s, want none", info.EstimatedCompletionAt)
+			case tt.wantETA >= 0 && (info.EstimatedCompletionAt == nil || !info.EstimatedCompletionAt.Equal(now.Add(tt.wantETA))):
+				t.Errorf("eta = %v, want %s", info.EstimatedCompletionAt, now.Add(tt.wantETA))
+			}
+		})
+	}
+}
+
+func TestQueueEstimatorCachesDurations(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	repo := &stubQueue{
+		snapshot: model.QueueSnapshot{Position: 1},
+		times:    []model.GenerationTime{{Type: "a", Count: 1, AvgSeconds: 60}},
+	}
+	estimator := NewQueueEstimator(repo, EstimatorOptions{Window: 24 * time.Hour, Workers: 1, CacheTTL: time.Minute})
+	estimator.now = func() time.Time { return now }
+
+	estimate := func() *time.Time {
+		t.Helper()
+		var info model.ReportStatusInfo
+		request := &model.ReportRequest{Type: "a", Status: model.StatusPending}
+		if err := estimator.Estimate(context.Background(), request, &info); err != nil {
+			t.Fatalf("Estimate: %v", err)
+		}
+		return info.EstimatedCompletionAt
+	}
+
+	estimate()
+	if repo.loads != 1 || !repo.since.Equal(now.Add(-24*time.Hour)) {
+		t.Fatalf("loaded %d times since %s, want once since %s", repo.loads, repo.since, now.Add(-24*time.Hour))
+	}
+
+	repo.times = []model.GenerationTime{{Type: "a", Count: 1, AvgSeconds: 90}}
+	now = now.Add(30 * time.Second)
+	if eta := estimate(); repo.loads != 1 || !eta.Equal(now.Add(time.Minute)) {
+		t.Errorf("within ttl: loaded %d times, eta %s; want cached 60s average", repo.loads, eta)
+	}
+
+	now = now.Add(time.Minute)
+	if eta := estimate(); repo.loads != 2 || !eta.Equal(now.Add(90*time.Second)) {
+		t.Errorf("after ttl: loaded %d times, eta %s; want reloaded 90s average", repo.loads, eta)
+	}
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/KostySCH/Reports_go/pkg/auth"
+	"github.com/KostySCH/Reports_go/pkg/reporttype"
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+	"github.com/KostySCH/Reports_go/reports_register/internal/repository/postgres"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// MaxBatchSize ограничивает количество запросов в одном пакете
+const MaxBatchSize = 1000
+
+type ReportBatchService struct {
+	repo    *postgres.ReportBatchRepository
+	types   *reporttype.Registry
+	limiter *Limiter
+}
+
+func NewReportBatchService(repo *postgres.ReportBatchRepository, types *reporttype.Registry, limiter *Limiter) *ReportBatchService {
+	return &ReportBatchService{repo: repo, types: types, limiter: limiter}
+}
+
+// BatchItem описывает один отчет в пакете
+type BatchItem struct {
+	Type   string
+	Params map[string]interface{}
+	// Priority — приоритет в очереди генерации; nil означает model.DefaultPriority
+	Priority *int
+}
+
+// Create проверяет все отчеты пакета и создает их в одной транзакции.
+// Если хотя бы один отчет не прошел проверку, не создается ни один.
+// Каждый отчет пакета расходует квоту пользователя так же, как отдельный запрос, и пакет
+// не принимается, если переполнит общую очередь.
+func (s *ReportBatchService) Create(ctx context.Context, caller *auth.Identity, items []BatchItem) (*model.ReportBatch, []uuid.UUID, error) {
+	userID := caller.UserID
+
+	verr := &reporttype.ValidationError{}
+	if len(items) == 0 {
+		verr.Fields = append(verr.Fields, reporttype.FieldError{Field: "reports", Message: "must not be empty"})
+	}
+	if len(items) > MaxBatchSize {
+		verr.Fields = append(verr.Fields, reporttype.FieldError{
+			Field:   "reports",
+			Message: fmt.Sprintf("must contain at most %d reports", MaxBatchSize),
+		})
+	}
+	if len(verr.Fields) > 0 {
+		return nil, nil, verr
+	}
+
+	now := time.Now()
+	batch := &model.ReportBatch{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Total:     len(items),
+		CreatedAt: now,
+	}
+
+	requests := make([]*model.ReportRequest, 0, len(items))
+	ids := make([]uuid.UUID, 0, len(items))
+	for i, item := range items {
+		params, err := s.types.Validate(item.Type, item.Params)
+		priority, perr := resolvePriority(item.Priority, "priority")
+		if perr != nil {
+			err = withFieldError(err, *perr)
+		}
+		if itemErr, ok := err.(*reporttype.ValidationError); ok {
+			for _, field := range itemErr.Fields {
+				field.Field = fmt.Sprintf("reports[%d].%s", i, field.Field)
+				verr.Fields = append(verr.Fields, field)
+			}
+			continue
+		}
+
+		paramsJSON, err := json.Marshal(params)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		request := &model.ReportRequest{
+			ID:        uuid.New(),
+			UserID:    userID,
+			Type:      item.Type,
+			Params:    model.JSON(paramsJSON),
+			Status:    model.StatusPending,
+			Priority:  priority,
+			CreatedAt: now,
+			UpdatedAt: now,
+			BatchID:   &batch.ID,
+		}
+		requests = append(requests, request)
+		ids = append(ids, request.ID)
+	}
+	if len(verr.Fields) > 0 {
+		log.WithError(verr).WithField("user_id", userID).Warn("Report batch validation failed")
+		return nil, nil, verr
+	}
+
+	if err := s.limiter.CheckUser(ctx, userID, len(requests)); err != nil {
+		return nil, nil, err
+	}
+	if err := s.limiter.CheckQueue(ctx, userID, len(requests)); err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.repo.Create(ctx, batch, requests, caller.String()); err != nil {
+		return nil, nil, err
+	}
+
+	log.WithFields(logrus.Fields{
+		"batch_id": batch.ID,
+		"user_id":  userID,
+		"total":    batch.Total,
+	}).Info("Report batch created")
+
+	return batch, ids, nil
+}
+
+// GetStatus возвращает сводный статус пакета и статусы всех его запросов
+func (s *ReportBatchService) GetStatus(ctx context.Context, id uuid.UUID, caller *auth.Identity) (*model.ReportBatchStatus, error) {
+	batch, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !caller.CanAccess(batch.UserID) {
+		return nil, model.ErrForbidden
+	}
+
+	items, err := s.repo.GetItems(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return model.NewReportBatchStatus(batch, items), nil
+}
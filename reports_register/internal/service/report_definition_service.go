@@ -0,0 +1,336 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KostySCH/Reports_go/pkg/auth"
+	"github.com/KostySCH/Reports_go/pkg/reporttype"
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+	"github.com/KostySCH/Reports_go/reports_register/internal/repository/postgres"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxDefinitionNameLength ограничивает длину имени определения
+	maxDefinitionNameLength = 200
+	// maxDefinitionShares ограничивает число пользователей, которым открыто одно определение
+	maxDefinitionShares = 100
+)
+
+type ReportDefinitionService struct {
+	repo     *postgres.ReportDefinitionRepository
+	types    *reporttype.Registry
+	requests *ReportRequestService
+}
+
+func NewReportDefinitionService(repo *postgres.ReportDefinitionRepository, types *reporttype.Registry, requests *ReportRequestService) *ReportDefinitionService {
+	return &ReportDefinitionService{repo: repo, types: types, requests: requests}
+}
+
+// DefinitionInput описывает создаваемое или изменяемое определение отчета
+type DefinitionInput struct {
+	Name    string
+	OwnerID int
+	Type    string
+	Params  map[string]interface{}
+	// Priority — приоритет создаваемых запросов; nil означает model.DefaultPriority
+	Priority       *int
+	CallbackURL    string
+	CallbackSecret string
+	Reuse          bool
+}
+
+// RunInput описывает запуск определения
+type RunInput struct {
+	// Params переопределяют сохраненные параметры, например месяц отчета
+	Params map[string]interface{}
+	// Priority переопределяет сохраненный приоритет
+	Priority       *int
+	IdempotencyKey string
+}
+
+// Create сохраняет определение отчета, проверив тип и параметры так же, как при создании запроса
+func (s *ReportDefinitionService) Create(ctx context.Context, input DefinitionInput) (*model.ReportDefinition, error) {
+	params, priority, err := s.validate(input)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	definition := &model.ReportDefinition{
+		ID:         uuid.New(),
+		Name:       strings.TrimSpace(input.Name),
+		OwnerID:    input.OwnerID,
+		Type:       input.Type,
+		Params:     params,
+		Priority:   priority,
+		Reuse:      input.Reuse,
+		SharedWith: []int{},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if input.CallbackURL != "" {
+		definition.CallbackURL = &input.CallbackURL
+	}
+	if input.CallbackSecret != "" {
+		definition.CallbackSecret = &input.CallbackSecret
+	}
+
+	if err := s.repo.Create(ctx, definition); err != nil {
+		return nil, err
+	}
+
+	log.WithFields(logrus.Fields{
+		"definition_id": definition.ID,
+		"owner_id":      definition.OwnerID,
+		"type":          definition.Type,
+	}).Info("Report definition created")
+
+	return definition, nil
+}
+
+// Get возвращает определение, если caller его владелец, администратор или определение ему открыто
+func (s *ReportDefinitionService) Get(ctx context.Context, id uuid.UUID, caller *auth.Identity) (*model.ReportDefinition, error) {
+	definition, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !definition.CanRun(caller.UserID) && !caller.IsAdmin() {
+		return nil, model.ErrForbidden
+	}
+	return definition, nil
+}
+
+// manage возвращает определение, если caller может его изменять: владелец или администратор
+func (s *ReportDefinitionService) manage(ctx context.Context, id uuid.UUID, caller *auth.Identity) (*model.ReportDefinition, error) {
+	definition, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !caller.CanAccess(definition.OwnerID) {
+		return nil, model.ErrForbidden
+	}
+	return definition, nil
+}
+
+// List возвращает определения caller и открытые ему. Администратор видит все определения
+// или, если задан ownerID, определения этого владельца.
+func (s *ReportDefinitionService) List(ctx context.Context, caller *auth.Identity, ownerID *int) ([]*model.ReportDefinition, error) {
+	if caller.IsAdmin() {
+		return s.repo.List(ctx, ownerID, nil)
+	}
+	if ownerID != nil && *ownerID != caller.UserID {
+		return nil, model.ErrForbidden
+	}
+	return s.repo.List(ctx, ownerID, &caller.UserID)
+}
+
+// Update заменяет настройки определения. Владелец и список пользователей не меняются.
+// Если callback_secret не передан, а callback_url не изменился, сохраняется прежний ключ подписи.
+func (s *ReportDefinitionService) Update(ctx context.Context, id uuid.UUID, caller *auth.Identity, input DefinitionInput) (*model.ReportDefinition, error) {
+	definition, err := s.manage(ctx, id, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.CallbackSecret == "" && definition.CallbackSecret != nil &&
+		definition.CallbackURL != nil && *definition.CallbackURL == input.CallbackURL {
+		input.CallbackSecret = *definition.CallbackSecret
+	}
+
+	input.OwnerID = definition.OwnerID
+	params, priority, err := s.validate(input)
+	if err != nil {
+		return nil, err
+	}
+
+	definition.Name = strings.TrimSpace(input.Name)
+	definition.Type = input.Type
+	definition.Params = params
+	definition.Priority = priority
+	definition.CallbackURL = nil
+	if input.CallbackURL != "" {
+		definition.CallbackURL = &input.CallbackURL
+	}
+	definition.CallbackSecret = nil
+	if input.CallbackSecret != "" {
+		definition.CallbackSecret = &input.CallbackSecret
+	}
+	definition.Reuse = input.Reuse
+	definition.UpdatedAt = time.Now()
+
+	if err := s.repo.Update(ctx, definition); err != nil {
+		return nil, err
+	}
+
+	log.WithField("definition_id", definition.ID).Info("Report definition updated")
+	return definition, nil
+}
+
+// Delete удаляет определение; уже созданные по нему запросы сохраняются
+func (s *ReportDefinitionService) Delete(ctx context.Context, id uuid.UUID, caller *auth.Identity) error {
+	if _, err := s.manage(ctx, id, caller); err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	log.WithField("definition_id", id).Info("Report definition deleted")
+	return nil
+}
+
+// Share заменяет список пользователей, которые могут просматривать и запускать определение.
+// Изменять определение и его список может только владелец или администратор.
+func (s *ReportDefinitionService) Share(ctx context.Context, id uuid.UUID, caller *auth.Identity, userIDs []int) (*model.ReportDefinition, error) {
+	definition, err := s.manage(ctx, id, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	shared, ferr := normalizeShares(definition.OwnerID, userIDs)
+	if ferr != nil {
+		return nil, &reporttype.ValidationError{Fields: []reporttype.FieldError{*ferr}}
+	}
+
+	if err := s.repo.SetShares(ctx, id, shared); err != nil {
+		return nil, err
+	}
+
+	log.WithFields(logrus.Fields{
+		"definition_id": id,
+		"shared_with":   shared,
+	}).Info("Report definition shares updated")
+
+	definition.SharedWith = shared
+	return definition, nil
+}
+
+// Run создает запрос на отчет по определению от имени caller: квоты и права на результат
+// относятся к запустившему пользователю. Параметры запуска переопределяют сохраненные.
+func (s *ReportDefinitionService) Run(ctx context.Context, id uuid.UUID, caller *auth.Identity, input RunInput) (*model.ReportRequest, bool, error) {
+	definition, err := s.Get(ctx, id, caller)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var params map[string]interface{}
+	if err := json.Unmarshal(definition.Params, &params); err != nil {
+		return nil, false, err
+	}
+	if params == nil {
+		params = make(map[string]interface{}, len(input.Params))
+	}
+	for name, value := range input.Params {
+		params[name] = value
+	}
+
+	priority := input.Priority
+	if priority == nil {
+		priority = &definition.Priority
+	}
+
+	create := CreateInput{
+		UserID:         caller.UserID,
+		Actor:          caller.String(),
+		Type:           definition.Type,
+		Params:         params,
+		IdempotencyKey: input.IdempotencyKey,
+		Priority:       priority,
+		SkipReuse:      !definition.Reuse,
+	}
+	if definition.CallbackURL != nil {
+		create.CallbackURL = *definition.CallbackURL
+	}
+	if definition.CallbackSecret != nil {
+		create.CallbackSecret = *definition.CallbackSecret
+	}
+
+	request, replayed, err := s.requests.Create(ctx, create)
+	if err != nil {
+		return nil, false, err
+	}
+
+	log.WithFields(logrus.Fields{
+		"definition_id": definition.ID,
+		"request_id":    request.ID,
+		"user_id":       caller.UserID,
+	}).Info("Report definition run")
+
+	return request, replayed, nil
+}
+
+// validate проверяет имя, тип и параметры по реестру, приоритет и адрес вебхука
+func (s *ReportDefinitionService) validate(input DefinitionInput) (model.JSON, int, error) {
+	verr := &reporttype.ValidationError{}
+
+	name := strings.TrimSpace(input.Name)
+	switch {
+	case name == "":
+		verr.Fields = append(verr.Fields, reporttype.FieldError{Field: "name", Message: "is required"})
+	case len(name) > maxDefinitionNameLength:
+		verr.Fields = append(verr.Fields, reporttype.FieldError{
+			Field:   "name",
+			Message: fmt.Sprintf("must be at most %d characters", maxDefinitionNameLength),
+		})
+	}
+
+	if _, err := s.types.Validate(input.Type, input.Params); err != nil {
+		typeErr, ok := err.(*reporttype.ValidationError)
+		if !ok {
+			return nil, 0, err
+		}
+		verr.Fields = append(verr.Fields, typeErr.Fields...)
+	}
+
+	priority, perr := resolvePriority(input.Priority, "priority")
+	if perr != nil {
+		verr.Fields = append(verr.Fields, *perr)
+	}
+	if ferr := validateCallback(input.CallbackURL, input.CallbackSecret); ferr != nil {
+		verr.Fields = append(verr.Fields, *ferr)
+	}
+
+	if len(verr.Fields) > 0 {
+		return nil, 0, verr
+	}
+
+	// Сохраняем параметры в том виде, в каком их передал пользователь: значения по умолчанию
+	// подставляются при каждом запуске
+	params, err := json.Marshal(input.Params)
+	if err != nil {
+		return nil, 0, err
+	}
+	return model.JSON(params), priority, nil
+}
+
+// normalizeShares проверяет список пользователей, убирает повторы и сортирует его
+func normalizeShares(ownerID int, userIDs []int) ([]int, *reporttype.FieldError) {
+	seen := make(map[int]bool, len(userIDs))
+	shared := make([]int, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if userID <= 0 {
+			return nil, &reporttype.FieldError{Field: "user_ids", Message: fmt.Sprintf("invalid user id %d", userID)}
+		}
+		if userID == ownerID || seen[userID] {
+			continue
+		}
+		seen[userID] = true
+		shared = append(shared, userID)
+	}
+	if len(shared) > maxDefinitionShares {
+		return nil, &reporttype.FieldError{
+			Field:   "user_ids",
+			Message: fmt.Sprintf("must contain at most %d users", maxDefinitionShares),
+		}
+	}
+	sort.Ints(shared)
+	return shared, nil
+}
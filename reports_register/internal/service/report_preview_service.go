@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+
+	"github.com/KostySCH/Reports_go/pkg/reporttype"
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+)
+
+// Previewer собирает данные отчета без формирования документа, например generator.Client
+type Previewer interface {
+	Preview(ctx context.Context, reportType string, params map[string]interface{}) (*model.ReportPreview, error)
+}
+
+type ReportPreviewService struct {
+	types     *reporttype.Registry
+	previewer Previewer
+}
+
+func NewReportPreviewService(types *reporttype.Registry, previewer Previewer) *ReportPreviewService {
+	return &ReportPreviewService{types: types, previewer: previewer}
+}
+
+// Preview проверяет параметры так же, как при создании запроса, и синхронно получает данные отчета.
+// Ничего не сохраняется и не попадает в очередь генерации. Возвращает *reporttype.ValidationError,
+// model.ErrPreviewNoData, model.ErrPreviewTimeout или model.ErrPreviewUnavailable.
+func (s *ReportPreviewService) Preview(ctx context.Context, reportType string, params map[string]interface{}) (*model.ReportPreview, error) {
+	normalized, err := s.types.Validate(reportType, params)
+	if err != nil {
+		return nil, err
+	}
+	return s.previewer.Preview(ctx, reportType, normalized)
+}
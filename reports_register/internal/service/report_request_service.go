@@ -1,12 +1,18 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/KostySCH/Reports_go/pkg/auth"
+	"github.com/KostySCH/Reports_go/pkg/reporttype"
 	"github.com/KostySCH/Reports_go/reports_register/internal/model"
-	"github.com/KostySCH/Reports_go/reports_register/internal/repository/postgres"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
@@ -18,47 +24,182 @@ func init() {
 	log.SetLevel(logrus.DebugLevel)
 }
 
+// MaxBulkRetry ограничивает количество запросов, возвращаемых в очередь одним вызовом
+const MaxBulkRetry = 1000
+
+const (
+	// DefaultStatsWindow — окно статистики очереди по умолчанию
+	DefaultStatsWindow = 24 * time.Hour
+	// MaxStatsWindow ограничивает окно статистики, чтобы запрос не сканировал всю таблицу
+	MaxStatsWindow = 30 * 24 * time.Hour
+	// statsTopErrors — сколько самых частых ошибок возвращает статистика
+	statsTopErrors = 10
+)
+
+// Options задает настройки ReportRequestService
+type Options struct {
+	// PublisherURL — базовый адрес reports_publisher для ссылок на скачивание
+	PublisherURL string
+	// IdempotencyRetention — срок, в течение которого повторный запрос с тем же ключом возвращает исходный
+	IdempotencyRetention time.Duration
+	// ReuseWindow — срок, в течение которого завершенный отчет с тем же типом и параметрами
+	// отдается новым запросам без повторной генерации; 0 отключает повторное использование
+	ReuseWindow time.Duration
+	// Estimator добавляет в статус место в очереди и ожидаемое время готовности; nil отключает оценку
+	Estimator *QueueEstimator
+}
+
 type ReportRequestService struct {
-	repo *postgres.ReportRequestRepository
+	repo    ReportRequestRepository
+	types   *reporttype.Registry
+	limiter *Limiter
+	opts    Options
 }
 
-func NewReportRequestService(repo *postgres.ReportRequestRepository) *ReportRequestService {
-	return &ReportRequestService{repo: repo}
+func NewReportRequestService(repo ReportRequestRepository, types *reporttype.Registry, limiter *Limiter, opts Options) *ReportRequestService {
+	opts.PublisherURL = strings.TrimRight(opts.PublisherURL, "/")
+	return &ReportRequestService{
+		repo:    repo,
+		types:   types,
+		limiter: limiter,
+		opts:    opts,
+	}
 }
 
-// Create создает новый запрос на отчет
-func (s *ReportRequestService) Create(ctx context.Context, userID int, reportType string, params map[string]interface{}) (*model.ReportRequest, error) {
+// CreateInput описывает запрос клиента на создание отчета
+type CreateInput struct {
+	UserID         int
+	Type           string
+	Params         map[string]interface{}
+	IdempotencyKey string
+	// Actor — кто создает запрос (auth.Identity.String()); записывается в историю запроса
+	Actor string
+	// Priority — приоритет в очереди генерации; nil означает model.DefaultPriority
+	Priority *int
+	// CallbackURL — адрес, на который reports_publisher отправит вебхук о завершении отчета
+	CallbackURL string
+	// CallbackSecret — ключ подписи HMAC-SHA256 вебхука
+	CallbackSecret string
+	// SkipReuse требует сгенерировать отчет заново, даже если есть свежий готовый результат
+	SkipReuse bool
+}
+
+// Types возвращает типы отчетов, доступные для заказа
+func (s *ReportRequestService) Types() []reporttype.Definition {
+	return s.types.List()
+}
+
+// Create создает новый запрос на отчет. Если указан ключ идемпотентности и запрос с ним
+// уже создавался в пределах срока хранения, возвращается исходный запрос и replayed = true.
+// Повтор по ключу идемпотентности не расходует квоту; новый запрос проверяется ограничителем
+// и при превышении квоты возвращается *LimitError. Если включено повторное использование и
+// такой же отчет завершился в пределах ReuseWindow, запрос сразу создается в статусе COMPLETED
+// со ссылкой на готовый файл и не попадает в очередь генерации.
+func (s *ReportRequestService) Create(ctx context.Context, input CreateInput) (request *model.ReportRequest, replayed bool, err error) {
 	log.WithFields(logrus.Fields{
-		"user_id": userID,
-		"type":    reportType,
-		"params":  params,
+		"user_id":         input.UserID,
+		"type":            input.Type,
+		"params":          input.Params,
+		"idempotency_key": input.IdempotencyKey,
 	}).Debug("Creating new report request")
 
+	// Проверяем тип отчета и параметры по реестру
+	params, err := s.types.Validate(input.Type, input.Params)
+	priority, perr := resolvePriority(input.Priority, "priority")
+	if perr != nil {
+		err = withFieldError(err, *perr)
+	}
+	if ferr := validateCallback(input.CallbackURL, input.CallbackSecret); ferr != nil {
+		err = withFieldError(err, *ferr)
+	}
+	if err != nil {
+		log.WithError(err).WithField("type", input.Type).Warn("Report request validation failed")
+		return nil, false, err
+	}
+
 	// Преобразуем параметры в JSON
 	paramsJSON, err := json.Marshal(params)
 	if err != nil {
 		log.WithError(err).Error("Failed to marshal report parameters")
-		return nil, err
+		return nil, false, err
+	}
+
+	if input.IdempotencyKey != "" {
+		existing, err := s.findIdempotent(ctx, input.UserID, input.IdempotencyKey)
+		if err != nil {
+			return nil, false, err
+		}
+		if existing != nil {
+			return s.replay(existing, input.Type, paramsJSON)
+		}
+	}
+
+	if err := s.limiter.CheckUser(ctx, input.UserID, 1); err != nil {
+		return nil, false, err
+	}
+
+	var source *model.ReportRequest
+	if s.opts.ReuseWindow > 0 && !input.SkipReuse {
+		source, err = s.repo.FindReusable(ctx, input.Type, model.JSON(paramsJSON), time.Now().Add(-s.opts.ReuseWindow))
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	if source == nil {
+		if err := s.limiter.CheckQueue(ctx, input.UserID, 1); err != nil {
+			return nil, false, err
+		}
 	}
 
 	// Создаем новый запрос на отчет
-	request := &model.ReportRequest{
+	request = &model.ReportRequest{
 		ID:        uuid.New(),
-		UserID:    userID,
-		Type:      reportType,
-		Params:    paramsJSON,
+		UserID:    input.UserID,
+		Type:      input.Type,
+		Params:    model.JSON(paramsJSON),
 		Status:    model.StatusPending,
+		Priority:  priority,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
+	if input.IdempotencyKey != "" {
+		request.IdempotencyKey = &input.IdempotencyKey
+	}
+	if input.CallbackURL != "" {
+		request.CallbackURL = &input.CallbackURL
+	}
+	if input.CallbackSecret != "" {
+		request.CallbackSecret = &input.CallbackSecret
+	}
+	if source != nil {
+		request.Status = model.StatusCompleted
+		request.ReportPath = source.ReportPath
+		request.ReusedFrom = &source.ID
+		// Копия не продлевает срок повторного использования результата
+		request.CompletedAt = source.CompletedAt
+
+		log.WithFields(logrus.Fields{
+			"request_id":  request.ID,
+			"reused_from": source.ID,
+		}).Info("Reusing completed report instead of generating it again")
+	}
 
 	// Сохраняем запрос в базу данных
-	if err := s.repo.Create(ctx, request); err != nil {
+	err = s.repo.Create(ctx, request, input.Actor)
+	if errors.Is(err, model.ErrDuplicateIdempotencyKey) {
+		// Параллельный запрос с тем же ключом успел создать запись раньше нас
+		existing, err := s.repo.GetByIdempotencyKey(ctx, input.UserID, input.IdempotencyKey)
+		if err != nil {
+			return nil, false, err
+		}
+		return s.replay(existing, input.Type, paramsJSON)
+	}
+	if err != nil {
 		log.WithError(err).WithFields(logrus.Fields{
-			"user_id": userID,
-			"type":    reportType,
+			"user_id": input.UserID,
+			"type":    input.Type,
 		}).Error("Failed to save report request to database")
-		return nil, err
+		return nil, false, err
 	}
 
 	log.WithFields(logrus.Fields{
@@ -66,9 +207,347 @@ func (s *ReportRequestService) Create(ctx context.Context, userID int, reportTyp
 		"status":     request.Status,
 	}).Info("Successfully created report request")
 
+	return request, false, nil
+}
+
+// resolvePriority проверяет приоритет и подставляет значение по умолчанию
+func resolvePriority(priority *int, field string) (int, *reporttype.FieldError) {
+	if priority == nil {
+		return model.DefaultPriority, nil
+	}
+	if *priority < model.MinPriority || *priority > model.MaxPriority {
+		return 0, &reporttype.FieldError{
+			Field:   field,
+			Message: fmt.Sprintf("must be between %d and %d", model.MinPriority, model.MaxPriority),
+		}
+	}
+	return *priority, nil
+}
+
+// maxCallbackURLLength ограничивает длину адреса вебхука
+const maxCallbackURLLength = 2048
+
+// validateCallback проверяет адрес вебхука: абсолютный URL со схемой http или https
+func validateCallback(callbackURL, secret string) *reporttype.FieldError {
+	if callbackURL == "" {
+		if secret != "" {
+			return &reporttype.FieldError{Field: "callback_secret", Message: "requires callback_url"}
+		}
+		return nil
+	}
+	if len(callbackURL) > maxCallbackURLLength {
+		return &reporttype.FieldError{
+			Field:   "callback_url",
+			Message: fmt.Sprintf("must be at most %d characters", maxCallbackURLLength),
+		}
+	}
+	parsed, err := url.Parse(callbackURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return &reporttype.FieldError{Field: "callback_url", Message: "must be an absolute http or https URL"}
+	}
+	return nil
+}
+
+// withFieldError добавляет ошибку поля к ошибке проверки параметров
+func withFieldError(err error, field reporttype.FieldError) error {
+	verr, ok := err.(*reporttype.ValidationError)
+	if !ok {
+		verr = &reporttype.ValidationError{}
+	}
+	verr.Fields = append(verr.Fields, field)
+	return verr
+}
+
+// findIdempotent ищет запрос с ключом идемпотентности в пределах срока хранения.
+// Ключ запроса с истекшим сроком хранения освобождается для повторного использования.
+func (s *ReportRequestService) findIdempotent(ctx context.Context, userID int, key string) (*model.ReportRequest, error) {
+	existing, err := s.repo.GetByIdempotencyKey(ctx, userID, key)
+	if errors.Is(err, model.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		log.WithError(err).WithField("idempotency_key", key).Error("Failed to look up idempotency key")
+		return nil, err
+	}
+
+	if time.Since(existing.CreatedAt) <= s.opts.IdempotencyRetention {
+		return existing, nil
+	}
+
+	if err := s.repo.ReleaseIdempotencyKey(ctx, existing.ID); err != nil {
+		log.WithError(err).WithField("request_id", existing.ID).Error("Failed to release expired idempotency key")
+		return nil, err
+	}
+	return nil, nil
+}
+
+// replay возвращает ранее созданный запрос, если он совпадает с повторным по типу и параметрам
+func (s *ReportRequestService) replay(existing *model.ReportRequest, reportType string, paramsJSON []byte) (*model.ReportRequest, bool, error) {
+	same, err := sameParams(existing.Params, paramsJSON)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing.Type != reportType || !same {
+		log.WithFields(logrus.Fields{
+			"request_id":      existing.ID,
+			"idempotency_key": *existing.IdempotencyKey,
+		}).Warn("Idempotency key reused with different parameters")
+		return nil, false, model.ErrIdempotencyConflict
+	}
+
+	log.WithFields(logrus.Fields{
+		"request_id": existing.ID,
+		"status":     existing.Status,
+	}).Info("Returning existing report request for idempotency key")
+
+	return existing, true, nil
+}
+
+// sameParams сравнивает параметры независимо от порядка ключей и форматирования JSONB
+func sameParams(a, b []byte) (bool, error) {
+	canonicalA, err := canonicalJSON(a)
+	if err != nil {
+		return false, err
+	}
+	canonicalB, err := canonicalJSON(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(canonicalA, canonicalB), nil
+}
+
+// canonicalJSON приводит JSON к виду с отсортированными ключами и без пробелов
+func canonicalJSON(data []byte) ([]byte, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// get возвращает запрос на отчет, если он доступен пользователю caller
+func (s *ReportRequestService) get(ctx context.Context, id uuid.UUID, caller *auth.Identity) (*model.ReportRequest, error) {
+	request, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !caller.CanAccess(request.UserID) {
+		return nil, model.ErrForbidden
+	}
 	return request, nil
 }
 
+// GetStatus возвращает текущее состояние запроса на отчет
+func (s *ReportRequestService) GetStatus(ctx context.Context, id uuid.UUID, caller *auth.Identity) (*model.ReportStatusInfo, error) {
+	request, err := s.get(ctx, id, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &model.ReportStatusInfo{
+		ID:         request.ID,
+		Type:       request.Type,
+		Status:     request.Status,
+		Error:      request.Error,
+		RetryCount: request.RetryCount,
+		Priority:   request.Priority,
+		ReportPath: request.ReportPath,
+		CreatedAt:  request.CreatedAt,
+		UpdatedAt:  request.UpdatedAt,
+
+		RequeuedBy:    request.RequeuedBy,
+		RequeueReason: request.RequeueReason,
+		RequeuedAt:    request.RequeuedAt,
+		ReusedFrom:    request.ReusedFrom,
+		CompletedAt:   request.CompletedAt,
+	}
+	if request.Status == model.StatusCompleted && request.ReportPath != nil {
+		info.DownloadURL = s.downloadURL(request.ID)
+	}
+	if s.opts.Estimator != nil {
+		// Без оценки статус остается полезным, поэтому ошибка только записывается в журнал
+		if err := s.opts.Estimator.Estimate(ctx, request, info); err != nil {
+			log.WithError(err).WithField("request_id", request.ID).Warn("Failed to estimate queue position")
+		}
+	}
+
+	return info, nil
+}
+
+// downloadURL формирует ссылку на скачивание отчета через reports_publisher
+func (s *ReportRequestService) downloadURL(id uuid.UUID) string {
+	return fmt.Sprintf("%s/api/v1/reports/%s/download", s.opts.PublisherURL, id)
+}
+
+// List возвращает страницу запросов на отчет по фильтру
+func (s *ReportRequestService) List(ctx context.Context, filter model.ReportRequestFilter) (*model.ReportRequestPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = model.DefaultListLimit
+	}
+	if filter.Limit > model.MaxListLimit {
+		filter.Limit = model.MaxListLimit
+	}
+	if filter.SortField == "" {
+		filter.SortField = model.SortByCreatedAt
+	}
+
+	requests, err := s.repo.List(ctx, filter)
+	if err != nil {
+		log.WithError(err).Error("Failed to list report requests")
+		return nil, err
+	}
+
+	page := &model.ReportRequestPage{Items: requests}
+	if len(requests) > filter.Limit {
+		page.Items = requests[:filter.Limit]
+		last := page.Items[len(page.Items)-1]
+		page.NextCursor = model.NewListCursor(last, filter.SortField, filter.SortDesc).Encode()
+	}
+
+	return page, nil
+}
+
+// History возвращает историю запроса: переходы статусов, повторные попытки и уведомления
+func (s *ReportRequestService) History(ctx context.Context, id uuid.UUID, caller *auth.Identity) ([]*model.ReportRequestEvent, error) {
+	if _, err := s.get(ctx, id, caller); err != nil {
+		return nil, err
+	}
+	return s.repo.ListEvents(ctx, id)
+}
+
+// Cancel отменяет запрос на отчет; генератор прерывает генерацию, если она уже началась
+func (s *ReportRequestService) Cancel(ctx context.Context, id uuid.UUID, caller *auth.Identity, reason string) (*model.ReportRequest, error) {
+	if _, err := s.get(ctx, id, caller); err != nil {
+		return nil, err
+	}
+
+	message := "cancelled by user"
+	if reason != "" {
+		message += ": " + reason
+	}
+
+	request, err := s.repo.Cancel(ctx, id, message, caller.String())
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(logrus.Fields{
+		"request_id": request.ID,
+		"reason":     reason,
+		"by":         caller.String(),
+	}).Info("Report request cancelled")
+
+	return request, nil
+}
+
+// RetryInput описывает ручной возврат запроса в очередь
+type RetryInput struct {
+	ResetRetryCount bool
+	RequestedBy     string
+	Reason          string
+}
+
+// Retry возвращает неудачный или отмененный запрос в очередь на генерацию.
+// Возвращенный запрос снова занимает место в очереди, поэтому проверяются квота владельца и глубина очереди.
+func (s *ReportRequestService) Retry(ctx context.Context, id uuid.UUID, caller *auth.Identity, input RetryInput) (*model.ReportRequest, error) {
+	current, err := s.get(ctx, id, caller)
+	if err != nil {
+		return nil, err
+	}
+	if !retryable(current.Status) {
+		return nil, model.ErrStatusConflict
+	}
+	if err := s.checkRequeue(ctx, caller, []*model.ReportRequest{current}); err != nil {
+		return nil, err
+	}
+
+	request, err := s.repo.Requeue(ctx, id, input.ResetRetryCount, input.RequestedBy, input.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(logrus.Fields{
+		"request_id":        request.ID,
+		"requested_by":      input.RequestedBy,
+		"reason":            input.Reason,
+		"reset_retry_count": input.ResetRetryCount,
+	}).Info("Report request requeued")
+
+	return request, nil
+}
+
+// RetryMatching возвращает в очередь до limit запросов, подходящих под фильтр.
+// Без фильтра по статусу возвращаются только запросы в статусе FAILED.
+// Каждый возвращаемый запрос расходует квоту своего владельца так же, как новый.
+func (s *ReportRequestService) RetryMatching(ctx context.Context, caller *auth.Identity, filter model.ReportRequestFilter, limit int, input RetryInput) ([]uuid.UUID, error) {
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = []model.ReportStatus{model.StatusFailed}
+	}
+	for _, status := range filter.Statuses {
+		if !retryable(status) {
+			return nil, fmt.Errorf("%w: only FAILED and CANCELLED requests can be retried", model.ErrStatusConflict)
+		}
+	}
+	if limit <= 0 || limit > MaxBulkRetry {
+		limit = MaxBulkRetry
+	}
+
+	// Выбираем те же строки, что вернет RequeueMatching, чтобы проверить квоты до изменения
+	matching := filter
+	matching.SortField, matching.SortDesc, matching.Limit, matching.Cursor = model.SortByCreatedAt, false, limit, nil
+	requests, err := s.repo.List(ctx, matching)
+	if err != nil {
+		return nil, err
+	}
+	if len(requests) > limit {
+		requests = requests[:limit]
+	}
+	if len(requests) == 0 {
+		return nil, nil
+	}
+	if err := s.checkRequeue(ctx, caller, requests); err != nil {
+		return nil, err
+	}
+
+	ids, err := s.repo.RequeueMatching(ctx, filter, limit, input.ResetRetryCount, input.RequestedBy, input.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(logrus.Fields{
+		"count":             len(ids),
+		"requested_by":      input.RequestedBy,
+		"reason":            input.Reason,
+		"reset_retry_count": input.ResetRetryCount,
+	}).Info("Report requests requeued in bulk")
+
+	return ids, nil
+}
+
+func retryable(status model.ReportStatus) bool {
+	return status == model.StatusFailed || status == model.StatusCancelled
+}
+
+// checkRequeue проверяет, что возврат requests в очередь не превысит квоты их владельцев
+// и допустимую глубину очереди
+func (s *ReportRequestService) checkRequeue(ctx context.Context, caller *auth.Identity, requests []*model.ReportRequest) error {
+	perUser := make(map[int]int)
+	for _, request := range requests {
+		perUser[request.UserID]++
+	}
+	for _, request := range requests {
+		n, ok := perUser[request.UserID]
+		if !ok {
+			continue
+		}
+		delete(perUser, request.UserID)
+		if err := s.limiter.CheckUser(ctx, request.UserID, n); err != nil {
+			return err
+		}
+	}
+	return s.limiter.CheckQueue(ctx, caller.UserID, len(requests))
+}
+
 // GetPending получает список отложенных отчетов для обработки
 func (s *ReportRequestService) GetPending(ctx context.Context, limit int) ([]*model.ReportRequest, error) {
 	return s.repo.GetPending(ctx, limit)
@@ -79,7 +558,12 @@ func (s *ReportRequestService) UpdateStatus(ctx context.Context, id uuid.UUID, s
 	return s.repo.UpdateStatus(ctx, id, status)
 }
 
-// GetStuck получает отчеты, которые зависли в статусе PENDING
-func (s *ReportRequestService) GetStuck(ctx context.Context, timeout time.Duration) ([]*model.ReportRequest, error) {
-	return s.repo.GetStuck(ctx, timeout)
+// Stats возвращает статистику очереди и генерации за последние window; доступна только администраторам
+func (s *ReportRequestService) Stats(ctx context.Context, caller *auth.Identity, window time.Duration) (*model.QueueStats, error) {
+	if !caller.IsAdmin() {
+		return nil, model.ErrForbidden
+	}
+
+	to := time.Now()
+	return s.repo.Stats(ctx, to.Add(-window), to, statsTopErrors)
 }
@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KostySCH/Reports_go/pkg/auth"
+	"github.com/KostySCH/Reports_go/pkg/memstore"
+	"github.com/KostySCH/Reports_go/pkg/reporttype"
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+	"github.com/KostySCH/Reports_go/reports_register/internal/repository/memory"
+	"github.com/google/uuid"
+)
+
+var testStart = time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+// insertRequests добавляет n запросов пользователя в статусе status, созданных до окна частоты запросов
+func insertRequests(store *memstore.Store, userID int, status model.ReportStatus, n int) []uuid.UUID {
+	var ids []uuid.UUID
+	store.Tx(func(tx *memstore.Tx) error {
+		for i := 0; i < n; i++ {
+			created := testStart.Add(time.Duration(len(ids)) * time.Second)
+			row := &memstore.Request{
+				ID:        uuid.New(),
+				UserID:    userID,
+				Type:      "branch_performance_report",
+				Params:    []byte(`{}`),
+				Status:    string(status),
+				CreatedAt: created,
+				UpdatedAt: created,
+			}
+			tx.Insert(row)
+			ids = append(ids, row.ID)
+		}
+		return nil
+	})
+	return ids
+}
+
+func newRetryService(store *memstore.Store, limits Limits) *ReportRequestService {
+	repo := memory.NewReportRequestRepository(store)
+	return NewReportRequestService(repo, reporttype.Default(), NewLimiter(repo, limits), Options{})
+}
+
+func statusOf(t *testing.T, store *memstore.Store, id uuid.UUID) model.ReportStatus {
+	t.Helper()
+	var status model.ReportStatus
+	store.Tx(func(tx *memstore.Tx) error {
+		status = model.ReportStatus(tx.Get(id).Status)
+		return nil
+	})
+	return status
+}
+
+func TestRetryChecksLimits(t *testing.T) {
+	owner := &auth.Identity{UserID: 1}
+	tests := []struct {
+		name       string
+		limits     Limits
+		active     int
+		queued     int
+		wantLimit  bool
+		wantGlobal bool
+	}{
+		{name: "within limits", limits: Limits{MaxActivePerUser: 2, MaxQueueDepth: 2}, active: 1},
+		{name: "user active limit", limits: Limits{MaxActivePerUser: 1}, active: 1, wantLimit: true},
+		{name: "queue depth", limits: Limits{MaxQueueDepth: 1}, queued: 1, wantLimit: true, wantGlobal: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memstore.New()
+			svc := newRetryService(store, tt.limits)
+			insertRequests(store, owner.UserID, model.StatusPending, tt.active)
+			insertRequests(store, 2, model.StatusPending, tt.queued)
+			id := insertRequests(store, owner.UserID, model.StatusFailed, 1)[0]
+
+			_, err := svc.Retry(context.Background(), id, owner, RetryInput{RequestedBy: owner.String()})
+			if !tt.wantLimit {
+				if err != nil {
+					t.Fatalf("Retry: %v", err)
+				}
+				if got := statusOf(t, store, id); got != model.StatusPending {
+					t.Errorf("status = %s, want %s", got, model.StatusPending)
+				}
+				return
+			}
+
+			var lerr *LimitError
+			if !errors.As(err, &lerr) {
+				t.Fatalf("Retry error = %v, want *LimitError", err)
+			}
+			if lerr.Global != tt.wantGlobal {
+				t.Errorf("Global = %v, want %v", lerr.Global, tt.wantGlobal)
+			}
+			if got := statusOf(t, store, id); got != model.StatusFailed {
+				t.Errorf("status = %s, want the request to stay %s", got, model.StatusFailed)
+			}
+		})
+	}
+}
+
+func TestRetryRejectsActiveRequestBeforeLimits(t *testing.T) {
+	store := memstore.New()
+	svc := newRetryService(store, Limits{MaxActivePerUser: 1})
+	id := insertRequests(store, 1, model.StatusPending, 1)[0]
+
+	_, err := svc.Retry(context.Background(), id, &auth.Identity{UserID: 1}, RetryInput{})
+	if !errors.Is(err, model.ErrStatusConflict) {
+		t.Fatalf("Retry error = %v, want %v", err, model.ErrStatusConflict)
+	}
+}
+
+func TestRetryMatchingCountsRequeuedRows(t *testing.T) {
+	admin := &auth.Identity{UserID: 100, Roles: []string{auth.RoleAdmin}}
+	tests := []struct {
+		name      string
+		limits    Limits
+		limit     int
+		wantLimit bool
+	}{
+		{name: "all rows fit the user quota", limits: Limits{MaxActivePerUser: 3}},
+		{name: "rows exceed the user quota", limits: Limits{MaxActivePerUser: 2}, wantLimit: true},
+		{name: "limit caps the counted rows", limits: Limits{MaxActivePerUser: 2}, limit: 2},
+		{name: "rows exceed the queue depth", limits: Limits{MaxQueueDepth: 4}, wantLimit: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memstore.New()
+			svc := newRetryService(store, tt.limits)
+			ids := insertRequests(store, 1, model.StatusFailed, 3)
+			others := insertRequests(store, 2, model.StatusFailed, 2)
+
+			requeued, err := svc.RetryMatching(context.Background(), admin, model.ReportRequestFilter{}, tt.limit, RetryInput{})
+			if tt.wantLimit {
+				var lerr *LimitError
+				if !errors.As(err, &lerr) {
+					t.Fatalf("RetryMatching error = %v, want *LimitError", err)
+				}
+				for _, id := range append(ids, others...) {
+					if got := statusOf(t, store, id); got != model.StatusFailed {
+						t.Fatalf("status = %s, want every request to stay %s", got, model.StatusFailed)
+					}
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("RetryMatching: %v", err)
+			}
+			want := len(ids) + len(others)
+			if tt.limit > 0 {
+				want = tt.limit
+			}
+			if len(requeued) != want {
+				t.Errorf("requeued %d requests, want %d", len(requeued), want)
+			}
+		})
+	}
+}
+
+func TestCreateQueuesRequestForGenerator(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	repo := memory.NewReportRequestRepository(store)
+	svc := NewReportRequestService(repo, reporttype.Default(), NewLimiter(repo, Limits{}),
+		Options{PublisherURL: "http://reports-publisher.test/"})
+	owner := &auth.Identity{UserID: 7, Subject: "user:7"}
+
+	request, _, err := svc.Create(ctx, CreateInput{
+		UserID: owner.UserID,
+		Type:   "branch_performance_report",
+		// Параметры приходят в сервис из JSON, поэтому числа — float64
+		Params: map[string]interface{}{"branch_id": float64(3), "month": "2024-05", "format": "pdf"},
+		Actor:  owner.String(),
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if got := statusOf(t, store, request.ID); got != model.StatusPending {
+		t.Fatalf("status after create = %s, want %s", got, model.StatusPending)
+	}
+
+	// Генератор завершает запрос в той же базе
+	path := "minio://reports-pdf/reports/2024/05/01/branch_performance_report_1.pdf"
+	store.Tx(func(tx *memstore.Tx) error {
+		row := tx.Get(request.ID)
+		row.Status = string(model.StatusCompleted)
+		row.ReportPath = &path
+		return nil
+	})
+
+	info, err := svc.GetStatus(ctx, request.ID, owner)
+	if err != nil {
+		t.Fatalf("GetStatus: %v", err)
+	}
+	if info.Status != model.StatusCompleted || info.ReportPath == nil || *info.ReportPath != path {
+		t.Errorf("status = %s, path %v; want COMPLETED at %s", info.Status, info.ReportPath, path)
+	}
+	if want := "http://reports-publisher.test/api/v1/reports/" + request.ID.String() + "/download"; info.DownloadURL != want {
+		t.Errorf("download url = %q, want %q", info.DownloadURL, want)
+	}
+
+	history, err := svc.History(ctx, request.ID, owner)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(history) != 1 || history[0].EventType != "CREATED" || history[0].Actor != owner.String() {
+		t.Errorf("history = %+v, want one CREATED event by %s", history, owner)
+	}
+}
+
+func createBranchReport(t *testing.T, svc *ReportRequestService) *model.ReportRequest {
+	t.Helper()
+	request, _, err := svc.Create(context.Background(), CreateInput{
+		UserID: 7,
+		Type:   "branch_performance_report",
+		Params: map[string]interface{}{"branch_id": float64(3), "month": "2024-05", "format": "pdf"},
+		Actor:  "user:7",
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return request
+}
+
+// completeAt завершает запрос так, как это делает генератор, а затем «трогает» строку сейчас
+func completeAt(store *memstore.Store, id uuid.UUID, completedAt time.Time) {
+	path := "minio://reports-pdf/reports/" + id.String() + ".pdf"
+	store.Tx(func(tx *memstore.Tx) error {
+		row := tx.Get(id)
+		row.Status = string(model.StatusCompleted)
+		row.ReportPath = &path
+		row.CompletedAt = &completedAt
+		row.UpdatedAt = time.Now()
+		return nil
+	})
+}
+
+func TestCreateReusesResultsCompletedWithinWindow(t *testing.T) {
+	tests := []struct {
+		name         string
+		completedAgo time.Duration
+		wantReuse    bool
+	}{
+		{name: "fresh result", completedAgo: 10 * time.Minute, wantReuse: true},
+		{name: "stale result updated recently", completedAgo: 2 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memstore.New()
+			repo := memory.NewReportRequestRepository(store)
+			svc := NewReportRequestService(repo, reporttype.Default(), NewLimiter(repo, Limits{}), Options{ReuseWindow: time.Hour})
+
+			source := createBranchReport(t, svc)
+			completedAt := time.Now().Add(-tt.completedAgo)
+			completeAt(store, source.ID, completedAt)
+
+			request := createBranchReport(t, svc)
+			if !tt.wantReuse {
+				if request.Status != model.StatusPending || request.ReusedFrom != nil {
+					t.Fatalf("request = %s reused from %v, want a new PENDING request", request.Status, request.ReusedFrom)
+				}
+				return
+			}
+			if request.Status != model.StatusCompleted || request.ReusedFrom == nil || *request.ReusedFrom != source.ID {
+				t.Fatalf("request = %s reused from %v, want COMPLETED reused from %s", request.Status, request.ReusedFrom, source.ID)
+			}
+			if request.CompletedAt == nil || !request.CompletedAt.Equal(completedAt) {
+				t.Errorf("completed at = %v, want the source's %v", request.CompletedAt, completedAt)
+			}
+		})
+	}
+}
+
+func TestReusedCopyDoesNotExtendReuseWindow(t *testing.T) {
+	store := memstore.New()
+	repo := memory.NewReportRequestRepository(store)
+	limiter := NewLimiter(repo, Limits{})
+	svc := NewReportRequestService(repo, reporttype.Default(), limiter, Options{ReuseWindow: time.Hour})
+
+	source := createBranchReport(t, svc)
+	completeAt(store, source.ID, time.Now().Add(-10*time.Minute))
+	if copied := createBranchReport(t, svc); copied.ReusedFrom == nil {
+		t.Fatal("result within the window was not reused")
+	}
+
+	// Копия создана только что, но ее результату столько же лет, сколько результату источника
+	strict := NewReportRequestService(repo, reporttype.Default(), limiter, Options{ReuseWindow: 5 * time.Minute})
+	if request := createBranchReport(t, strict); request.ReusedFrom != nil {
+		t.Errorf("request reused %s, whose result is older than the window", *request.ReusedFrom)
+	}
+}
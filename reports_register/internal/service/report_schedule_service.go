@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/KostySCH/Reports_go/pkg/auth"
+	"github.com/KostySCH/Reports_go/pkg/reporttype"
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+	"github.com/KostySCH/Reports_go/reports_register/internal/repository/postgres"
+	"github.com/KostySCH/Reports_go/reports_register/internal/schedule"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type ReportScheduleService struct {
+	repo  *postgres.ReportScheduleRepository
+	types *reporttype.Registry
+}
+
+func NewReportScheduleService(repo *postgres.ReportScheduleRepository, types *reporttype.Registry) *ReportScheduleService {
+	return &ReportScheduleService{repo: repo, types: types}
+}
+
+// ScheduleInput описывает создаваемое или изменяемое расписание
+type ScheduleInput struct {
+	Name           string
+	OwnerID        int
+	Cron           string
+	Timezone       string
+	Type           string
+	ParamsTemplate map[string]interface{}
+	Enabled        bool
+	// Priority — приоритет создаваемых запросов; nil означает model.DefaultPriority
+	Priority *int
+}
+
+// Create создает расписание и вычисляет время первого запуска
+func (s *ReportScheduleService) Create(ctx context.Context, input ScheduleInput) (*model.ReportSchedule, error) {
+	spec, template, priority, err := s.validate(input)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sched := &model.ReportSchedule{
+		ID:             uuid.New(),
+		Name:           input.Name,
+		OwnerID:        input.OwnerID,
+		CronExpr:       input.Cron,
+		Timezone:       input.Timezone,
+		Type:           input.Type,
+		ParamsTemplate: template,
+		Enabled:        input.Enabled,
+		Priority:       priority,
+		NextRunAt:      spec.Next(now),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	if err := s.repo.Create(ctx, sched); err != nil {
+		return nil, err
+	}
+
+	log.WithFields(logrus.Fields{
+		"schedule_id": sched.ID,
+		"owner_id":    sched.OwnerID,
+		"cron":        sched.CronExpr,
+		"next_run_at": sched.NextRunAt,
+	}).Info("Report schedule created")
+
+	return sched, nil
+}
+
+// Get возвращает расписание по идентификатору, если оно доступно пользователю caller
+func (s *ReportScheduleService) Get(ctx context.Context, id uuid.UUID, caller *auth.Identity) (*model.ReportSchedule, error) {
+	sched, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !caller.CanAccess(sched.OwnerID) {
+		return nil, model.ErrForbidden
+	}
+	return sched, nil
+}
+
+// List возвращает расписания пользователя или все расписания, если ownerID равен nil
+func (s *ReportScheduleService) List(ctx context.Context, ownerID *int) ([]*model.ReportSchedule, error) {
+	return s.repo.List(ctx, ownerID)
+}
+
+// Update заменяет настройки расписания и пересчитывает время следующего запуска.
+// Владелец расписания не меняется.
+func (s *ReportScheduleService) Update(ctx context.Context, id uuid.UUID, caller *auth.Identity, input ScheduleInput) (*model.ReportSchedule, error) {
+	sched, err := s.Get(ctx, id, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	input.OwnerID = sched.OwnerID
+	spec, template, priority, err := s.validate(input)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sched.Name = input.Name
+	sched.CronExpr = input.Cron
+	sched.Timezone = input.Timezone
+	sched.Type = input.Type
+	sched.ParamsTemplate = template
+	sched.Enabled = input.Enabled
+	sched.Priority = priority
+	sched.NextRunAt = spec.Next(now)
+	sched.LastError = nil
+	sched.UpdatedAt = now
+
+	if err := s.repo.Update(ctx, sched); err != nil {
+		return nil, err
+	}
+
+	log.WithFields(logrus.Fields{
+		"schedule_id": sched.ID,
+		"next_run_at": sched.NextRunAt,
+	}).Info("Report schedule updated")
+
+	return sched, nil
+}
+
+// Delete удаляет расписание
+func (s *ReportScheduleService) Delete(ctx context.Context, id uuid.UUID, caller *auth.Identity) error {
+	if _, err := s.Get(ctx, id, caller); err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	log.WithField("schedule_id", id).Info("Report schedule deleted")
+	return nil
+}
+
+// validate проверяет расписание: cron-выражение, часовой пояс, приоритет и шаблон параметров,
+// подставляя в него текущую дату и проверяя результат по реестру типов отчетов
+func (s *ReportScheduleService) validate(input ScheduleInput) (*schedule.Spec, model.JSON, int, error) {
+	verr := &reporttype.ValidationError{}
+
+	if strings.TrimSpace(input.Name) == "" {
+		verr.Fields = append(verr.Fields, reporttype.FieldError{Field: "name", Message: "is required"})
+	}
+
+	if _, err := time.LoadLocation(input.Timezone); err != nil {
+		verr.Fields = append(verr.Fields, reporttype.FieldError{Field: "timezone", Message: "unknown timezone"})
+		input.Timezone = ""
+	}
+
+	spec, err := schedule.Parse(input.Cron, input.Timezone)
+	if err != nil {
+		verr.Fields = append(verr.Fields, reporttype.FieldError{Field: "cron", Message: err.Error()})
+	}
+
+	if spec != nil {
+		_, err := s.types.Validate(input.Type, spec.RenderParams(input.ParamsTemplate, time.Now()))
+		if typeErr, ok := err.(*reporttype.ValidationError); ok {
+			for _, field := range typeErr.Fields {
+				field.Field = strings.Replace(field.Field, "params.", "params_template.", 1)
+				verr.Fields = append(verr.Fields, field)
+			}
+		}
+	}
+
+	priority, perr := resolvePriority(input.Priority, "priority")
+	if perr != nil {
+		verr.Fields = append(verr.Fields, *perr)
+	}
+
+	if len(verr.Fields) > 0 {
+		return nil, nil, 0, verr
+	}
+
+	template, err := json.Marshal(input.ParamsTemplate)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return spec, model.JSON(template), priority, nil
+}
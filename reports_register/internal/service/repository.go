@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/KostySCH/Reports_go/reports_register/internal/model"
+	"github.com/KostySCH/Reports_go/reports_register/internal/repository/postgres"
+	"github.com/google/uuid"
+)
+
+// ReportRequestRepository хранит запросы на отчет и их историю.
+// Реализации: postgres.ReportRequestRepository и memory.ReportRequestRepository.
+type ReportRequestRepository interface {
+	LoadCounter
+	QueueSource
+
+	// Create сохраняет новый запрос; при занятом ключе идемпотентности возвращает model.ErrDuplicateIdempotencyKey
+	Create(ctx context.Context, request *model.ReportRequest, actor string) error
+	// GetByID возвращает model.ErrNotFound, если запроса нет
+	GetByID(ctx context.Context, id uuid.UUID) (*model.ReportRequest, error)
+	GetByIdempotencyKey(ctx context.Context, userID int, key string) (*model.ReportRequest, error)
+	ReleaseIdempotencyKey(ctx context.Context, id uuid.UUID) error
+	// FindReusable возвращает nil, если подходящего завершенного запроса нет
+	FindReusable(ctx context.Context, reportType string, params model.JSON, since time.Time) (*model.ReportRequest, error)
+	// List возвращает не более filter.Limit+1 записей
+	List(ctx context.Context, filter model.ReportRequestFilter) ([]*model.ReportRequest, error)
+	GetPending(ctx context.Context, limit int) ([]*model.ReportRequest, error)
+	UpdateStatus(ctx context.Context, id uuid.UUID, status model.ReportStatus) error
+	// Cancel и Requeue возвращают model.ErrStatusConflict, если действие недопустимо в текущем статусе
+	Cancel(ctx context.Context, id uuid.UUID, reason, actor string) (*model.ReportRequest, error)
+	Requeue(ctx context.Context, id uuid.UUID, resetRetryCount bool, requestedBy, reason string) (*model.ReportRequest, error)
+	RequeueMatching(ctx context.Context, filter model.ReportRequestFilter, limit int, resetRetryCount bool, requestedBy, reason string) ([]uuid.UUID, error)
+	ListEvents(ctx context.Context, id uuid.UUID) ([]*model.ReportRequestEvent, error)
+	Stats(ctx context.Context, from, to time.Time, topErrors int) (*model.QueueStats, error)
+}
+
+// LoadCounter считает нагрузку на очередь для Limiter
+type LoadCounter interface {
+	GetUserLoad(ctx context.Context, userID int, since time.Time) (*model.UserLoad, error)
+	CountPending(ctx context.Context) (int, error)
+}
+
+// QueueSource дает QueueEstimator положение запроса в очереди и недавние длительности генерации
+type QueueSource interface {
+	QueueSnapshot(ctx context.Context, request *model.ReportRequest) (*model.QueueSnapshot, error)
+	GenerationTimes(ctx context.Context, since time.Time) ([]model.GenerationTime, error)
+}
+
+var _ ReportRequestRepository = (*postgres.ReportRequestRepository)(nil)